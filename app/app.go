@@ -6,11 +6,15 @@ import (
 	_ "github.com/lib/pq"
 	"github.com/life-unlimited/podcastination-server/config"
 	"github.com/life-unlimited/podcastination-server/feedgen"
+	"github.com/life-unlimited/podcastination-server/integrity"
 	"github.com/life-unlimited/podcastination-server/stores"
 	"github.com/life-unlimited/podcastination-server/tasks"
+	"github.com/life-unlimited/podcastination-server/transfer"
 	"github.com/life-unlimited/podcastination-server/web_server"
+	"github.com/life-unlimited/podcastination-server/websub"
 	"github.com/pkg/errors"
 	"log"
+	"path/filepath"
 	"time"
 )
 
@@ -18,6 +22,7 @@ type App struct {
 	config    config.PodcastinationConfig
 	db        *sql.DB
 	scheduler *tasks.Scheduler
+	watcher   *tasks.ImportWatcher
 	webServer *web_server.WebServer
 	Stores    stores.Stores
 }
@@ -29,8 +34,9 @@ func NewApp(config config.PodcastinationConfig) *App {
 	}
 }
 
-// Boot boots the App.
-func (a *App) Boot() error {
+// Connect connects to the database, performs migrations if needed and sets up the stores. This is also done by
+// Boot, so it only needs to be called if the App is used without booting, for example for CLI commands.
+func (a *App) Connect() error {
 	// Connect to database.
 	db, err := connectDB(a.config.PostgresDatasource, defaultMaxDBConnections)
 	if err != nil {
@@ -45,6 +51,14 @@ func (a *App) Boot() error {
 	if err != nil {
 		return errors.Wrap(err, "perform database migrations")
 	}
+	err = resolveLegacyEpisodeGUIDs(db, a.config.StaticContentURL)
+	if err != nil {
+		return errors.Wrap(err, "resolve legacy episode guids")
+	}
+	err = generateEpisodeGUIDs(db)
+	if err != nil {
+		return errors.Wrap(err, "generate episode guids")
+	}
 	a.db = db
 	// Setup stores.Stores.
 	a.Stores = stores.Stores{
@@ -52,6 +66,8 @@ func (a *App) Boot() error {
 		Owners:   stores.OwnerStore{DB: a.db},
 		Seasons:  stores.SeasonStore{DB: a.db},
 		Episodes: stores.EpisodeStore{DB: a.db},
+		Chapters: stores.ChapterStore{DB: a.db},
+		APIKeys:  stores.APIKeyStore{DB: a.db},
 	}
 	// Check database connection.
 	_, err = a.Stores.Podcasts.All()
@@ -59,6 +75,15 @@ func (a *App) Boot() error {
 		return fmt.Errorf("could not connect to db: %v", err)
 	}
 	log.Println("connection to database established.")
+	return nil
+}
+
+// Boot boots the App.
+func (a *App) Boot() error {
+	err := a.Connect()
+	if err != nil {
+		return errors.Wrap(err, "connect")
+	}
 	// Create scheduler.
 	a.scheduler = tasks.NewScheduler(tasks.SchedulingConfig{
 		PullDir:        a.config.PullDir,
@@ -66,33 +91,109 @@ func (a *App) Boot() error {
 		ImportInterval: time.Duration(a.config.ImportInterval) * time.Minute,
 	}, a.db)
 	// Perform integrity check.
-	// TODO: Perform integrity check.
-	// Refresh all podcast.xml files.
+	log.Println("performing integrity check")
+	report, err := a.CheckIntegrity(a.config.IntegrityCheckLengths, a.config.IntegrityRepair)
+	if err != nil {
+		log.Printf("%+v", errors.Wrap(err, "check integrity"))
+	} else {
+		logIntegrityReport(report)
+	}
+	// Fill file sizes of episodes imported before they were stored.
+	backfilled, err := integrity.BackfillFileSizes(a.config.PodcastDir, a.Stores.Episodes)
+	if err != nil {
+		log.Printf("%+v", errors.Wrap(err, "backfill file sizes"))
+	} else if backfilled > 0 {
+		log.Printf("backfilled file sizes of %d episodes", backfilled)
+	}
+	// Refresh all podcast.xml files. Embargoes lifting afterwards are handled by the publishing job.
+	publishedSince := time.Now()
+	webSub := a.webSubPublisher()
 	log.Println("refreshing all podcast xml files")
-	err = feedgen.RefreshFeedForPodcasts(a.Stores, a.config.StaticContentURL, a.config.PodcastDir, tasks.PodcastXMLDetailsFileName)
+	changedFeeds, err := feedgen.RefreshFeedForPodcasts(a.Stores, a.config.StaticContentURL, a.config.PodcastDir,
+		tasks.PodcastXMLDetailsFileName, webSub.HubURLs())
 	if err != nil {
 		log.Printf("%+v", errors.Wrap(err, "refresh feed for podcasts"))
 	} else {
 		log.Println("done.")
 	}
+	// Mirror the podcast dir to the storage.
+	mirror, err := a.storageMirror()
+	if err != nil {
+		return errors.Wrap(err, "create storage mirror")
+	}
+	if mirror != nil {
+		log.Println("syncing podcast dir to storage")
+		if err = mirror.SyncAll(); err != nil {
+			log.Printf("%+v", errors.Wrap(err, "sync podcast dir to storage"))
+		} else {
+			log.Println("done.")
+		}
+	}
+	// Notify hubs after syncing so that they fetch the new feeds.
+	if err = webSub.Publish(changedFeeds...); err != nil {
+		log.Printf("%+v", errors.Wrap(err, "notify websub hubs"))
+	}
 	// Let's go.
-	a.scheduler.ScheduleJob(&tasks.ImportJob{
+	importJob := &tasks.ImportJob{
 		StaticContentURL: a.config.StaticContentURL,
 		PullDir:          a.config.PullDir,
 		PodcastDir:       a.config.PodcastDir,
 		ImportInterval:   time.Duration(a.config.ImportInterval) * time.Minute,
+		FailedDir:        a.failedDir(),
+		MaxAttempts:      a.config.MaxImportAttempts,
+		RetryBackoff:     time.Duration(a.config.ImportRetryBackoff) * time.Minute,
+		Mirror:           mirror,
+		WebSub:           webSub,
 		Store: tasks.ImportJobStores{
 			Podcasts: a.Stores.Podcasts,
 			Owners:   a.Stores.Owners,
 			Seasons:  a.Stores.Seasons,
 			Episodes: a.Stores.Episodes,
+			Chapters: a.Stores.Chapters,
 		},
-	}, true)
+	}
+	if importJob.MaxAttempts == 0 {
+		importJob.MaxAttempts = config.DefaultMaxImportAttempts
+	}
+	if importJob.RetryBackoff == 0 {
+		importJob.RetryBackoff = importJob.ImportInterval
+	}
+	publishingJob := tasks.NewPublishingJob(a.Stores.Episodes, func(podcastId int) error {
+		changed, err := feedgen.RefreshFeedForPodcast(a.Stores, a.config.StaticContentURL, a.config.PodcastDir,
+			tasks.PodcastXMLDetailsFileName, webSub.HubURLs(), podcastId)
+		if err != nil {
+			return err
+		}
+		if err = mirror.SyncPodcast(podcastId); err != nil {
+			return err
+		}
+		if err = webSub.Publish(changed...); err != nil {
+			log.Printf("could not notify websub hubs about podcast %d: %v", podcastId, err)
+		}
+		return nil
+	}, publishedSince)
+	importJob.Publishing = publishingJob
+	a.scheduler.ScheduleJob(importJob, true)
+	a.scheduler.ScheduleJob(publishingJob, false)
+	if a.config.WatchPullDir {
+		a.watcher = tasks.NewImportWatcher(importJob, time.Duration(a.config.WatchStabilityDelay)*time.Second)
+		if err = a.watcher.Start(); err != nil {
+			log.Printf("%+v", errors.Wrap(err, "start import watcher, falling back to polling only"))
+			a.watcher = nil
+		}
+	}
 	// Start web web_server.
 	a.webServer = web_server.NewServer(web_server.Config{
-		StaticDir: a.config.PodcastDir,
-		Addr:      a.config.ServerAddr,
-	}, &a.Stores)
+		StaticDir:        a.config.PodcastDir,
+		Addr:             a.config.ServerAddr,
+		StaticContentURL: a.config.StaticContentURL,
+		APIURL:           a.config.APIURL,
+		AllowedOrigins:   a.config.CORSAllowedOrigins,
+		PullDir:          a.config.PullDir,
+		MaxUploadSize:    int64(a.config.MaxUploadSize) << 20,
+		Mirror:           mirror,
+		WebSub:           webSub,
+	}, &a.Stores, importJob, publishingJob)
 	err = a.webServer.Start()
 	if err != nil {
 		log.Fatalf("could not start web server: %v", err)
@@ -100,11 +201,135 @@ func (a *App) Boot() error {
 	return nil
 }
 
+// CheckIntegrity checks the integrity of the podcast dir and the stores and repairs found issues if wanted.
+func (a *App) CheckIntegrity(checkLengths, repair bool) (integrity.Report, error) {
+	checker := integrity.Checker{
+		PodcastDir:   a.config.PodcastDir,
+		FeedFileName: tasks.PodcastXMLDetailsFileName,
+		Stores: integrity.Stores{
+			Podcasts: &a.Stores.Podcasts,
+			Seasons:  &a.Stores.Seasons,
+			Episodes: &a.Stores.Episodes,
+			Chapters: &a.Stores.Chapters,
+		},
+		CheckLengths: checkLengths,
+		Repair:       repair,
+		OrphanDir:    a.config.OrphanDir,
+		PullDir:      a.config.PullDir,
+	}
+	return checker.Check()
+}
+
+// storageMirror creates the transfer.Mirror for the configured storage. If none is configured, nil is returned.
+func (a *App) storageMirror() (*transfer.Mirror, error) {
+	storageConfig := a.config.Storage
+	switch storageConfig.Type {
+	case "":
+		return nil, nil
+	case config.StorageTypeS3:
+		return &transfer.Mirror{
+			Local: &transfer.LocalStorage{Dir: a.config.PodcastDir, BaseURL: a.config.StaticContentURL},
+			Remote: &transfer.S3Storage{
+				Endpoint:  storageConfig.Endpoint,
+				Region:    storageConfig.Region,
+				Bucket:    storageConfig.Bucket,
+				AccessKey: storageConfig.AccessKey,
+				SecretKey: storageConfig.SecretKey,
+				Prefix:    storageConfig.Prefix,
+				BaseURL:   a.config.StaticContentURL,
+			},
+			Delete: storageConfig.Delete,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown storage type %q", storageConfig.Type)
+	}
+}
+
+// webSubPublisher creates the websub.Publisher for the configured hubs. If none are configured, nil is returned.
+func (a *App) webSubPublisher() *websub.Publisher {
+	webSubConfig := a.config.WebSub
+	if len(webSubConfig.Hubs) == 0 && webSubConfig.BuiltInHubURL == "" {
+		return nil
+	}
+	publisher := &websub.Publisher{Hubs: webSubConfig.Hubs}
+	if webSubConfig.BuiltInHubURL != "" {
+		publisher.Hub = websub.NewHub(webSubConfig.BuiltInHubURL, a.config.StaticContentURL)
+		publisher.Hub.AllowPrivateCallbacks = webSubConfig.AllowPrivateCallbacks
+		// The podcast xml announces the feed link as topic which might be on another host.
+		publisher.Hub.AllowTopic = a.isFeedLink
+	}
+	return publisher
+}
+
+// isFeedLink checks if the given url is the feed link of a podcast.
+func (a *App) isFeedLink(url string) bool {
+	pcs, err := a.Stores.Podcasts.All()
+	if err != nil {
+		log.Printf("%+v", errors.Wrap(err, "retrieve podcasts for checking feed link"))
+		return false
+	}
+	for _, podcast := range pcs {
+		if podcast.FeedLink == url {
+			return true
+		}
+	}
+	return false
+}
+
+// failedDir returns the directory where failed import tasks are moved to.
+func (a *App) failedDir() string {
+	if a.config.FailedDir != "" {
+		return a.config.FailedDir
+	}
+	return filepath.Join(a.config.PullDir, ".failed")
+}
+
+// FailedImportTasks retrieves all import tasks that were moved to the failed dir.
+func (a *App) FailedImportTasks() ([]tasks.FailedImportTask, error) {
+	return tasks.FailedImportTasks(a.failedDir())
+}
+
+// ImportWarnings retrieves the recorded warnings of the latest scheduled imports.
+func (a *App) ImportWarnings() ([]tasks.ImportWarnings, error) {
+	return tasks.RecordedImportWarnings(a.failedDir())
+}
+
+// RequeueFailedImportTask moves the failed import task with the given name back to the pull dir.
+func (a *App) RequeueFailedImportTask(name string) error {
+	return tasks.RequeueFailedImportTask(a.failedDir(), a.config.PullDir, name)
+}
+
+// logIntegrityReport logs the issues and repairs of the given integrity.Report.
+func logIntegrityReport(report integrity.Report) {
+	if report.IsClean() {
+		log.Println("integrity check found no issues.")
+		return
+	}
+	issues := report.Issues()
+	log.Printf("integrity check found %d issues:", len(issues))
+	for _, issue := range issues {
+		log.Printf("  %s", issue)
+	}
+	for _, repair := range report.Repairs {
+		log.Printf("  repaired: %s", repair)
+	}
+	for _, repairErr := range report.RepairErrors {
+		log.Printf("  repair failed: %s", repairErr)
+	}
+}
+
 // Shutdown shuts down the app.
 func (a *App) Shutdown() error {
-	a.scheduler.Stop()
-	if err := a.webServer.Stop(); err != nil {
-		return fmt.Errorf("stop web server: %v", err)
+	if a.watcher != nil {
+		a.watcher.Stop()
+	}
+	if a.scheduler != nil {
+		a.scheduler.Stop()
+	}
+	if a.webServer != nil {
+		if err := a.webServer.Stop(); err != nil {
+			return fmt.Errorf("stop web server: %v", err)
+		}
 	}
 	if a.db != nil {
 		return closeDB(a.db)
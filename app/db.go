@@ -5,6 +5,7 @@ import (
 	nativeerrors "errors"
 	"fmt"
 	"github.com/doug-martin/goqu/v9"
+	"github.com/google/uuid"
 	"github.com/jackc/pgconn"
 	_ "github.com/jackc/pgx/v4/stdlib"
 	"github.com/life-unlimited/podcastination-server/embedded"
@@ -39,8 +40,61 @@ var dbMigrations = []dbMigration{
 		version: "1.0",
 		up:      embedded.DBMigration1x0,
 	},
+	{
+		version: "1.1",
+		up:      embedded.DBMigration1x1,
+	},
+	{
+		version: "1.2",
+		up:      embedded.DBMigration1x2,
+	},
+	{
+		version: "1.3",
+		up:      embedded.DBMigration1x3,
+	},
+	{
+		version: "1.4",
+		up:      embedded.DBMigration1x4,
+	},
+	{
+		version: "1.5",
+		up:      embedded.DBMigration1x5,
+	},
+	{
+		version: "1.6",
+		up:      embedded.DBMigration1x6,
+	},
+	{
+		version: "1.7",
+		up:      embedded.DBMigration1x7,
+	},
+	{
+		version: "1.8",
+		up:      embedded.DBMigration1x8,
+	},
+	{
+		version: "1.9",
+		up:      embedded.DBMigration1x9,
+	},
+	{
+		version: "1.10",
+		up:      embedded.DBMigration1x10,
+	},
+	{
+		version: "1.11",
+		up:      embedded.DBMigration1x11,
+	},
 }
 
+// legacyGUIDsUnresolvedKey is set by the migration for episode guids. Before, the static url of the mp3 file was used
+// as guid. The migration keeps the mp3 location as legacy guid which needs to be prefixed with the static content url
+// afterwards, as this is not known to the database.
+const legacyGUIDsUnresolvedKey = "legacy-guids-unresolved"
+
+// episodeGUIDsUngeneratedKey is set by the migration for episode guids. Episodes that existed before need random guids
+// which are generated afterwards, as postgres only generates them with extensions or starting with version 13.
+const episodeGUIDsUngeneratedKey = "episode-guids-ungenerated"
+
 // connectDB connects to the database with the given connection string and returns the connection pool.
 func connectDB(connectionStr string, maxDBConnections int) (*sql.DB, error) {
 	dbPool, err := sql.Open("pgx", connectionStr)
@@ -135,6 +189,94 @@ func performDBMigrations(db *sql.DB) error {
 	return nil
 }
 
+// resolveLegacyEpisodeGUIDs prefixes the legacy guids of episodes with the given static content url if not done yet,
+// so that they match the guids used before the migration for episode guids.
+func resolveLegacyEpisodeGUIDs(db *sql.DB, staticContentURL string) error {
+	_, unresolved, err := retrieveKeyValFromDB(db, legacyGUIDsUnresolvedKey)
+	if err != nil {
+		return errors.Wrap(err, "retrieve key val from database")
+	}
+	if !unresolved {
+		return nil
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "begin tx")
+	}
+	_, err = tx.Exec("UPDATE episodes SET legacy_guid = $1 || '/' || legacy_guid WHERE legacy_guid IS NOT NULL",
+		staticContentURL)
+	if err != nil {
+		rollbackTx(tx, "resolving legacy guids failed")
+		return errors.Wrap(err, "resolve legacy guids")
+	}
+	_, err = tx.Exec("DELETE FROM podcastination WHERE key = $1", legacyGUIDsUnresolvedKey)
+	if err != nil {
+		rollbackTx(tx, "deleting legacy guids key failed")
+		return errors.Wrap(err, "delete legacy guids key")
+	}
+	if err = tx.Commit(); err != nil {
+		return errors.Wrap(err, "commit tx")
+	}
+	return nil
+}
+
+// generateEpisodeGUIDs generates random guids for episodes that existed before the migration for episode guids if not
+// done yet. As these episodes use their legacy guid in feeds, this does not affect subscribers.
+func generateEpisodeGUIDs(db *sql.DB) error {
+	_, ungenerated, err := retrieveKeyValFromDB(db, episodeGUIDsUngeneratedKey)
+	if err != nil {
+		return errors.Wrap(err, "retrieve key val from database")
+	}
+	if !ungenerated {
+		return nil
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "begin tx")
+	}
+	rows, err := tx.Query("SELECT id FROM episodes WHERE guid IS NULL")
+	if err != nil {
+		rollbackTx(tx, "retrieving episodes without guid failed")
+		return errors.Wrap(err, "retrieve episodes without guid")
+	}
+	episodeIds := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			_ = rows.Close()
+			rollbackTx(tx, "scanning episode id failed")
+			return errors.Wrap(err, "scan episode id")
+		}
+		episodeIds = append(episodeIds, id)
+	}
+	if err = rows.Close(); err != nil {
+		rollbackTx(tx, "closing episode rows failed")
+		return errors.Wrap(err, "close episode rows")
+	}
+	for _, id := range episodeIds {
+		_, err = tx.Exec("UPDATE episodes SET guid = $1 WHERE id = $2", uuid.New().String(), id)
+		if err != nil {
+			rollbackTx(tx, "generating episode guid failed")
+			return errors.Wrap(err, fmt.Sprintf("generate guid for episode %d", id))
+		}
+	}
+	_, err = tx.Exec("ALTER TABLE episodes ALTER COLUMN guid SET NOT NULL")
+	if err != nil {
+		rollbackTx(tx, "requiring episode guids failed")
+		return errors.Wrap(err, "require episode guids")
+	}
+	_, err = tx.Exec("DELETE FROM podcastination WHERE key = $1", episodeGUIDsUngeneratedKey)
+	if err != nil {
+		rollbackTx(tx, "deleting episode guids key failed")
+		return errors.Wrap(err, "delete episode guids key")
+	}
+	if err = tx.Commit(); err != nil {
+		return errors.Wrap(err, "commit tx")
+	}
+	log.Printf("generated guids for %d episodes", len(episodeIds))
+	return nil
+}
+
 // getDBMigrationsToDo retrieves all database migrations that need to be performed. If the version is dbVersionZero, it
 // will return all migrations. If the version is unknown, an error will be returned.
 func getDBMigrationsToDo(currentVersion dbVersion) ([]dbMigration, error) {
@@ -157,8 +299,10 @@ func getDBMigrationsToDo(currentVersion dbVersion) ([]dbMigration, error) {
 			// Continue with next one as we already performed everything for this database version.
 			continue
 		}
-		// Append migration to todos.
-		migrationsToDo = append(migrationsToDo, migration)
+		// Append migration to todos if it comes after the current version.
+		if found {
+			migrationsToDo = append(migrationsToDo, migration)
+		}
 	}
 	// Check if found.
 	if !found {
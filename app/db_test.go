@@ -2,10 +2,12 @@ package app
 
 import (
 	"database/sql"
+	"database/sql/driver"
 	nativeerrors "errors"
 	"fmt"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/doug-martin/goqu/v9"
+	"github.com/google/uuid"
 	"github.com/hashicorp/go-version"
 	"github.com/stretchr/testify/suite"
 	"testing"
@@ -125,6 +127,12 @@ func (suite *GetDBMigrationsToDoTestSuite) TestLatest() {
 	suite.Assert().Len(migrations, 0, "should return no migrations to do because current version is latest")
 }
 
+func (suite *GetDBMigrationsToDoTestSuite) TestFirst() {
+	migrations, err := getDBMigrationsToDo(dbMigrations[0].version)
+	suite.Require().Nilf(err, "retrieval should not fail but got %s", err)
+	suite.Assert().Equal(dbMigrations[1:], migrations, "should return only migrations after the first one")
+}
+
 func (suite *GetDBMigrationsToDoTestSuite) TestUnknownVersion() {
 	_, err := getDBMigrationsToDo(dbVersion(fmt.Sprintf("%s-unknown-version-lol", dbMigrations[len(dbMigrations)-1])))
 	suite.Assert().NotNil(err, "retrieval should fail because of unknown version")
@@ -215,3 +223,72 @@ func (suite *PerformDBMigrationsTestSuite) TestLatest() {
 func Test_performDBMigrations(t *testing.T) {
 	suite.Run(t, new(PerformDBMigrationsTestSuite))
 }
+
+type GenerateEpisodeGUIDsTestSuite struct {
+	dbSuite
+	keyVal RetrieveKeyValFromDBTestSuite
+}
+
+func (suite *GenerateEpisodeGUIDsTestSuite) SetupTest() {
+	suite.prepareDB()
+	suite.keyVal.dbSuite = suite.dbSuite
+	suite.keyVal.prepareQuery(episodeGUIDsUngeneratedKey)
+}
+
+func (suite *GenerateEpisodeGUIDsTestSuite) TearDownTest() {
+	suite.Assert().Nil(suite.mock.ExpectationsWereMet(), "all mock expectations should be met")
+}
+
+func (suite *GenerateEpisodeGUIDsTestSuite) TestAlreadyGenerated() {
+	suite.mock.ExpectQuery(suite.keyVal.retrieveQuery).WillReturnRows(sqlmock.NewRows([]string{"value"}))
+
+	err := generateEpisodeGUIDs(suite.db)
+	suite.Assert().Nilf(err, "should not fail but got %s", err)
+}
+
+func (suite *GenerateEpisodeGUIDsTestSuite) TestGenerate() {
+	suite.mock.ExpectQuery(suite.keyVal.retrieveQuery).WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("true"))
+	suite.mock.ExpectBegin()
+	suite.mock.ExpectQuery("SELECT id FROM episodes WHERE guid IS NULL").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	for _, id := range []int{1, 2} {
+		suite.mock.ExpectExec("UPDATE episodes SET guid = $1 WHERE id = $2").WithArgs(uuidV4Arg{}, id).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	suite.mock.ExpectExec("ALTER TABLE episodes ALTER COLUMN guid SET NOT NULL").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	suite.mock.ExpectExec("DELETE FROM podcastination WHERE key = $1").WithArgs(episodeGUIDsUngeneratedKey).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	suite.mock.ExpectCommit()
+
+	err := generateEpisodeGUIDs(suite.db)
+	suite.Assert().Nilf(err, "should not fail but got %s", err)
+}
+
+func (suite *GenerateEpisodeGUIDsTestSuite) TestUpdateFail() {
+	suite.mock.ExpectQuery(suite.keyVal.retrieveQuery).WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("true"))
+	suite.mock.ExpectBegin()
+	suite.mock.ExpectQuery("SELECT id FROM episodes WHERE guid IS NULL").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	suite.mock.ExpectExec("UPDATE episodes SET guid = $1 WHERE id = $2").WillReturnError(nativeerrors.New("ERROR"))
+	suite.mock.ExpectRollback()
+
+	err := generateEpisodeGUIDs(suite.db)
+	suite.Assert().NotNil(err, "should fail")
+}
+
+func Test_generateEpisodeGUIDs(t *testing.T) {
+	suite.Run(t, new(GenerateEpisodeGUIDsTestSuite))
+}
+
+// uuidV4Arg matches arguments that are random uuids as defined by RFC 4122.
+type uuidV4Arg struct{}
+
+func (uuidV4Arg) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	if !ok {
+		return false
+	}
+	id, err := uuid.Parse(s)
+	return err == nil && id.Version() == 4 && id.Variant() == uuid.RFC4122
+}
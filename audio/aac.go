@@ -0,0 +1,60 @@
+package audio
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// adtsSampleRates are the sample rates referenced by the sampling frequency index of ADTS headers.
+var adtsSampleRates = []uint64{96000, 88200, 64000, 48000, 44100, 32000, 24000, 22050, 16000, 12000, 11025, 8000,
+	7350}
+
+// adtsSamplesPerBlock is the number of samples in each raw data block of an ADTS frame.
+const adtsSamplesPerBlock = 1024
+
+// isADTSHeader checks if the given bytes start with an ADTS frame header.
+func isADTSHeader(b []byte) bool {
+	return len(b) >= 2 && b[0] == 0xFF && b[1]&0xF6 == 0xF0
+}
+
+// adtsDuration sums up the samples of all frames in the given ADTS stream.
+func adtsDuration(r io.ReadSeeker) (time.Duration, error) {
+	var samples, sampleRate uint64
+	header := make([]byte, 7)
+	frames := 0
+	for {
+		_, err := io.ReadFull(r, header)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// Trailing garbage after the last frame is ignored.
+			if frames > 0 {
+				break
+			}
+			return 0, fmt.Errorf("could not read frame header: %v", err)
+		}
+		if !isADTSHeader(header) {
+			if frames > 0 {
+				break
+			}
+			return 0, fmt.Errorf("invalid frame header")
+		}
+		sampleRateIndex := int(header[2]>>2) & 0x0F
+		if sampleRateIndex >= len(adtsSampleRates) {
+			return 0, fmt.Errorf("invalid sample rate index %d", sampleRateIndex)
+		}
+		sampleRate = adtsSampleRates[sampleRateIndex]
+		frameLength := int64(header[3]&0x03)<<11 | int64(header[4])<<3 | int64(header[5]>>5)
+		if frameLength < int64(len(header)) {
+			return 0, fmt.Errorf("invalid frame length %d", frameLength)
+		}
+		samples += uint64(header[6]&0x03+1) * adtsSamplesPerBlock
+		frames++
+		if _, err = r.Seek(frameLength-int64(len(header)), io.SeekCurrent); err != nil {
+			return 0, fmt.Errorf("could not seek: %v", err)
+		}
+	}
+	return samplesDuration(samples, sampleRate), nil
+}
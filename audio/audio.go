@@ -0,0 +1,213 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Format is a supported audio format.
+type Format string
+
+const (
+	FormatMP3    Format = "mp3"
+	FormatM4A    Format = "m4a"
+	FormatAAC    Format = "aac"
+	FormatVorbis Format = "vorbis"
+	FormatOpus   Format = "opus"
+	FormatFLAC   Format = "flac"
+)
+
+// mimeTypes holds the MIME type for each Format.
+var mimeTypes = map[Format]string{
+	FormatMP3:    "audio/mpeg",
+	FormatM4A:    "audio/mp4",
+	FormatAAC:    "audio/aac",
+	FormatVorbis: "audio/ogg",
+	FormatOpus:   "audio/ogg",
+	FormatFLAC:   "audio/flac",
+}
+
+// extensions holds the allowed file extensions for each Format. The first one is the default.
+var extensions = map[Format][]string{
+	FormatMP3:    {".mp3"},
+	FormatM4A:    {".m4a", ".mp4", ".m4b"},
+	FormatAAC:    {".aac"},
+	FormatVorbis: {".ogg", ".oga"},
+	FormatOpus:   {".opus", ".ogg", ".oga"},
+	FormatFLAC:   {".flac"},
+}
+
+// MIMEType returns the MIME type of the Format.
+func (f Format) MIMEType() string {
+	return mimeTypes[f]
+}
+
+// Info holds the details of an audio file.
+type Info struct {
+	Format   Format
+	MIMEType string
+	Duration time.Duration
+}
+
+// Seconds returns the duration rounded to whole seconds.
+func (info Info) Seconds() int {
+	return int(info.Duration.Round(time.Second) / time.Second)
+}
+
+// IsSupportedFileName checks if the given file name has the extension of a supported audio format.
+func IsSupportedFileName(fileName string) bool {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	for _, formatExtensions := range extensions {
+		for _, formatExt := range formatExtensions {
+			if ext == formatExt {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ExtensionsForMIMEType returns all file extensions that are used for the given MIME type.
+func ExtensionsForMIMEType(mimeType string) []string {
+	found := make(map[string]struct{})
+	result := make([]string, 0)
+	for format, formatMIMEType := range mimeTypes {
+		if formatMIMEType != mimeType {
+			continue
+		}
+		for _, ext := range extensions[format] {
+			if _, ok := found[ext]; !ok {
+				found[ext] = struct{}{}
+				result = append(result, ext)
+			}
+		}
+	}
+	return result
+}
+
+// Probe detects the format of the given audio file by its content and determines its duration. An error is returned
+// if the format is not supported or the file is invalid. The file extension must match the detected format.
+func Probe(file string) (Info, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return Info{}, fmt.Errorf("could not open audio file: %v", err)
+	}
+	info, err := probe(f)
+	if closeErr := f.Close(); err == nil && closeErr != nil {
+		return Info{}, fmt.Errorf("could not close audio file: %v", closeErr)
+	}
+	if err != nil {
+		return Info{}, err
+	}
+	if !hasExtension(file, info.Format) {
+		return Info{}, fmt.Errorf("file extension %s does not match detected format %s", filepath.Ext(file),
+			info.Format)
+	}
+	return info, nil
+}
+
+// probe detects the format of the given audio and determines its duration.
+func probe(r io.ReadSeeker) (Info, error) {
+	offset, err := skipID3v2(r)
+	if err != nil {
+		return Info{}, fmt.Errorf("could not skip id3 tag: %v", err)
+	}
+	header := make([]byte, 12)
+	n, err := io.ReadFull(r, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return Info{}, fmt.Errorf("could not read header: %v", err)
+	}
+	header = header[:n]
+	if _, err = r.Seek(offset, io.SeekStart); err != nil {
+		return Info{}, fmt.Errorf("could not seek: %v", err)
+	}
+	var format Format
+	var duration time.Duration
+	switch {
+	case len(header) >= 8 && bytes.Equal(header[4:8], []byte("ftyp")):
+		format = FormatM4A
+		duration, err = mp4Duration(r)
+	case bytes.HasPrefix(header, []byte("OggS")):
+		format, duration, err = oggDuration(r)
+	case bytes.HasPrefix(header, []byte("fLaC")):
+		format = FormatFLAC
+		duration, err = flacDuration(r)
+	case isADTSHeader(header):
+		format = FormatAAC
+		duration, err = adtsDuration(r)
+	case isMPEGAudioHeader(header) || offset > 0:
+		format = FormatMP3
+		duration, err = mp3Duration(r)
+	default:
+		return Info{}, fmt.Errorf("unsupported audio format")
+	}
+	if err != nil {
+		return Info{}, fmt.Errorf("invalid %s file: %v", format, err)
+	}
+	return Info{
+		Format:   format,
+		MIMEType: format.MIMEType(),
+		Duration: duration,
+	}, nil
+}
+
+// hasExtension checks if the given file has one of the extensions of the given Format.
+func hasExtension(file string, format Format) bool {
+	ext := strings.ToLower(filepath.Ext(file))
+	for _, formatExt := range extensions[format] {
+		if ext == formatExt {
+			return true
+		}
+	}
+	return false
+}
+
+// skipID3v2 skips an ID3v2 tag at the start of the given reader and returns the offset of the audio data. If there is
+// no tag, the reader is reset to the start.
+func skipID3v2(r io.ReadSeeker) (int64, error) {
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(r, header); err != nil || !bytes.HasPrefix(header, []byte("ID3")) {
+		_, seekErr := r.Seek(0, io.SeekStart)
+		return 0, seekErr
+	}
+	size := int64(syncSafe(header[6:10])) + 10
+	// Footer present.
+	if header[5]&0x10 != 0 {
+		size += 10
+	}
+	return r.Seek(size, io.SeekStart)
+}
+
+// syncSafe decodes a synchsafe integer as used in ID3v2 where the most significant bit of each byte is zero.
+func syncSafe(b []byte) uint32 {
+	var v uint32
+	for _, c := range b {
+		v = v<<7 | uint32(c&0x7F)
+	}
+	return v
+}
+
+// readUint reads a big endian unsigned integer with the given number of bytes (up to 8).
+func readUint(r io.Reader, size int) (uint64, error) {
+	buf := make([]byte, 8)
+	if _, err := io.ReadFull(r, buf[8-size:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(buf), nil
+}
+
+// samplesDuration returns the duration of the given number of samples at the given sample rate.
+func samplesDuration(samples uint64, sampleRate uint64) time.Duration {
+	if sampleRate == 0 {
+		return 0
+	}
+	seconds := samples / sampleRate
+	remainder := samples % sampleRate
+	return time.Duration(seconds)*time.Second + time.Duration(remainder)*time.Second/time.Duration(sampleRate)
+}
@@ -0,0 +1,178 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type AudioTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func (suite *AudioTestSuite) SetupTest() {
+	var err error
+	suite.dir, err = ioutil.TempDir("", "audio")
+	suite.Require().Nil(err, "creating temp dir should not fail")
+}
+
+func (suite *AudioTestSuite) TearDownTest() {
+	_ = os.RemoveAll(suite.dir)
+}
+
+// write writes the given content to a file with the given name in the temp dir and returns its path.
+func (suite *AudioTestSuite) write(name string, content []byte) string {
+	path := filepath.Join(suite.dir, name)
+	suite.Require().Nil(ioutil.WriteFile(path, content, 0644), "writing file should not fail")
+	return path
+}
+
+// oggPageBytes creates an ogg page with the given granule position and a single packet.
+func oggPageBytes(granulePosition uint64, packet []byte) []byte {
+	header := make([]byte, oggPageHeaderSize)
+	copy(header, "OggS")
+	binary.LittleEndian.PutUint64(header[6:14], granulePosition)
+	binary.LittleEndian.PutUint32(header[14:18], 1)
+	header[26] = 1
+	return append(append(header, byte(len(packet))), packet...)
+}
+
+// mp4BoxBytes creates an mp4 box with the given type and content.
+func mp4BoxBytes(boxType string, content []byte) []byte {
+	box := make([]byte, 8)
+	binary.BigEndian.PutUint32(box, uint32(8+len(content)))
+	copy(box[4:], boxType)
+	return append(box, content...)
+}
+
+func (suite *AudioTestSuite) TestFLAC() {
+	streamInfo := make([]byte, flacStreamInfoSize)
+	// 44100 Hz and 441000 samples.
+	streamInfo[10], streamInfo[11], streamInfo[12] = 0x0A, 0xC4, 0x40
+	binary.BigEndian.PutUint32(streamInfo[14:18], 441000)
+	content := append([]byte{'f', 'L', 'a', 'C', 0x80, 0, 0, flacStreamInfoSize}, streamInfo...)
+	info, err := Probe(suite.write("a.flac", content))
+	suite.Require().Nilf(err, "probing should not fail but got %v", err)
+	suite.Assert().Equal(FormatFLAC, info.Format, "format should match")
+	suite.Assert().Equal("audio/flac", info.MIMEType, "mime type should match")
+	suite.Assert().Equal(10*time.Second, info.Duration, "duration should match")
+}
+
+func (suite *AudioTestSuite) TestVorbis() {
+	idHeader := make([]byte, 30)
+	copy(idHeader, "\x01vorbis")
+	binary.LittleEndian.PutUint32(idHeader[12:16], 48000)
+	var content []byte
+	content = append(content, oggPageBytes(0, idHeader)...)
+	content = append(content, oggPageBytes(96000, []byte{1, 2, 3})...)
+	content = append(content, oggPageBytes(^uint64(0), []byte{4})...)
+	info, err := Probe(suite.write("a.ogg", content))
+	suite.Require().Nilf(err, "probing should not fail but got %v", err)
+	suite.Assert().Equal(FormatVorbis, info.Format, "format should match")
+	suite.Assert().Equal("audio/ogg", info.MIMEType, "mime type should match")
+	suite.Assert().Equal(2*time.Second, info.Duration, "duration should match")
+}
+
+func (suite *AudioTestSuite) TestOpus() {
+	head := make([]byte, 19)
+	copy(head, "OpusHead")
+	binary.LittleEndian.PutUint16(head[10:12], 312)
+	var content []byte
+	content = append(content, oggPageBytes(0, head)...)
+	content = append(content, oggPageBytes(48000*3+312, []byte{1})...)
+	info, err := Probe(suite.write("a.opus", content))
+	suite.Require().Nilf(err, "probing should not fail but got %v", err)
+	suite.Assert().Equal(FormatOpus, info.Format, "format should match")
+	suite.Assert().Equal(3*time.Second, info.Duration, "duration should subtract pre-skip")
+}
+
+func (suite *AudioTestSuite) TestM4A() {
+	mvhd := make([]byte, 20)
+	binary.BigEndian.PutUint32(mvhd[12:16], 1000)
+	binary.BigEndian.PutUint32(mvhd[16:20], 65500)
+	var content []byte
+	content = append(content, mp4BoxBytes("ftyp", []byte("M4A \x00\x00\x00\x00"))...)
+	content = append(content, mp4BoxBytes("free", nil)...)
+	content = append(content, mp4BoxBytes("moov", mp4BoxBytes("mvhd", mvhd))...)
+	info, err := Probe(suite.write("a.m4a", content))
+	suite.Require().Nilf(err, "probing should not fail but got %v", err)
+	suite.Assert().Equal(FormatM4A, info.Format, "format should match")
+	suite.Assert().Equal("audio/mp4", info.MIMEType, "mime type should match")
+	suite.Assert().Equal(65500*time.Millisecond, info.Duration, "duration should match")
+	suite.Assert().Equal(66, info.Seconds(), "seconds should be rounded")
+}
+
+func (suite *AudioTestSuite) TestADTS() {
+	// 44100 Hz, one raw data block and a frame length of 10 bytes.
+	frame := []byte{0xFF, 0xF1, 0x50, 0x80, 0x01, 0x40, 0x00, 0, 0, 0}
+	content := bytes.Repeat(frame, 441)
+	info, err := Probe(suite.write("a.aac", content))
+	suite.Require().Nilf(err, "probing should not fail but got %v", err)
+	suite.Assert().Equal(FormatAAC, info.Format, "format should match")
+	suite.Assert().Equal(10240*time.Millisecond, info.Duration, "duration should match")
+}
+
+// mp3FrameBytes creates an MPEG 1 layer III frame with 128 kbit/s at 44100 Hz which is 417 bytes long.
+func mp3FrameBytes() []byte {
+	frame := make([]byte, 417)
+	copy(frame, []byte{0xFF, 0xFB, 0x90, 0x00})
+	return frame
+}
+
+func (suite *AudioTestSuite) TestMP3CountsFrames() {
+	content := append([]byte("ID3\x03\x00\x00\x00\x00\x00\x02\x00\x00"), bytes.Repeat(mp3FrameBytes(), 100)...)
+	content = append(content, []byte("TAG")...)
+	info, err := Probe(suite.write("a.mp3", content))
+	suite.Require().Nilf(err, "probing should not fail but got %v", err)
+	suite.Assert().Equal(FormatMP3, info.Format, "format should match")
+	suite.Assert().Equal("audio/mpeg", info.MIMEType, "mime type should match")
+	suite.Assert().Equal(samplesDuration(100*1152, 44100), info.Duration, "duration should match all frames")
+}
+
+func (suite *AudioTestSuite) TestMP3Xing() {
+	first := mp3FrameBytes()
+	copy(first[36:], "Xing")
+	binary.BigEndian.PutUint32(first[40:44], 0x01)
+	binary.BigEndian.PutUint32(first[44:48], 3000)
+	content := append(first, bytes.Repeat(mp3FrameBytes(), 10)...)
+	info, err := Probe(suite.write("a.mp3", content))
+	suite.Require().Nilf(err, "probing should not fail but got %v", err)
+	suite.Assert().Equal(samplesDuration(3000*1152, 44100), info.Duration, "duration should match xing frames")
+}
+
+func (suite *AudioTestSuite) TestMP3VBRI() {
+	first := mp3FrameBytes()
+	copy(first[36:], "VBRI")
+	binary.BigEndian.PutUint32(first[50:54], 2000)
+	content := append(first, bytes.Repeat(mp3FrameBytes(), 10)...)
+	info, err := Probe(suite.write("a.mp3", content))
+	suite.Require().Nilf(err, "probing should not fail but got %v", err)
+	suite.Assert().Equal(samplesDuration(2000*1152, 44100), info.Duration, "duration should match vbri frames")
+}
+
+func (suite *AudioTestSuite) TestExtensionMismatch() {
+	content := append([]byte{'f', 'L', 'a', 'C', 0x80, 0, 0, flacStreamInfoSize}, make([]byte, flacStreamInfoSize)...)
+	_, err := Probe(suite.write("a.mp3", content))
+	suite.Assert().NotNil(err, "probing should fail for wrong extension")
+}
+
+func (suite *AudioTestSuite) TestUnsupported() {
+	_, err := Probe(suite.write("a.mp3", []byte("RIFF\x00\x00\x00\x00WAVE")))
+	suite.Assert().NotNil(err, "probing should fail for unsupported format")
+}
+
+func (suite *AudioTestSuite) TestIsSupportedFileName() {
+	suite.Assert().True(IsSupportedFileName("episode.MP3"), "mp3 should be supported")
+	suite.Assert().True(IsSupportedFileName("episode.opus"), "opus should be supported")
+	suite.Assert().False(IsSupportedFileName("episode.wav"), "wav should not be supported")
+}
+
+func Test_audio(t *testing.T) {
+	suite.Run(t, new(AudioTestSuite))
+}
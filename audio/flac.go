@@ -0,0 +1,37 @@
+package audio
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// flacStreamInfoSize is the size of the STREAMINFO metadata block which is always the first one.
+const flacStreamInfoSize = 34
+
+// flacDuration reads the duration from the STREAMINFO block of the given flac stream.
+func flacDuration(r io.ReadSeeker) (time.Duration, error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, fmt.Errorf("could not read header: %v", err)
+	}
+	// Marker followed by the metadata block header.
+	if header[4]&0x7F != 0 {
+		return 0, fmt.Errorf("first metadata block is not STREAMINFO")
+	}
+	streamInfo := make([]byte, flacStreamInfoSize)
+	if _, err := io.ReadFull(r, streamInfo); err != nil {
+		return 0, fmt.Errorf("could not read STREAMINFO: %v", err)
+	}
+	// 20 bits sample rate, 3 bits channels, 5 bits bits per sample and 36 bits total samples.
+	sampleRate := uint64(streamInfo[10])<<12 | uint64(streamInfo[11])<<4 | uint64(streamInfo[12])>>4
+	totalSamples := uint64(streamInfo[13]&0x0F)<<32 | uint64(streamInfo[14])<<24 | uint64(streamInfo[15])<<16 |
+		uint64(streamInfo[16])<<8 | uint64(streamInfo[17])
+	if sampleRate == 0 {
+		return 0, fmt.Errorf("invalid sample rate")
+	}
+	if totalSamples == 0 {
+		return 0, fmt.Errorf("unknown number of samples")
+	}
+	return samplesDuration(totalSamples, sampleRate), nil
+}
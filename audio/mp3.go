@@ -0,0 +1,186 @@
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// MPEG versions as encoded in frame headers.
+const (
+	mpegVersion25 = 0
+	mpegVersion2  = 2
+	mpegVersion1  = 3
+)
+
+// MPEG layers as encoded in frame headers.
+const (
+	mpegLayer3 = 1
+	mpegLayer2 = 2
+	mpegLayer1 = 3
+)
+
+// mpegBitrates holds the bitrates in kbit/s by version (1 or 2/2.5), layer and bitrate index.
+var mpegBitrates = map[bool]map[int][15]int{
+	true: {
+		mpegLayer1: {0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+		mpegLayer2: {0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+		mpegLayer3: {0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+	},
+	false: {
+		mpegLayer1: {0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+		mpegLayer2: {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+		mpegLayer3: {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+	},
+}
+
+// mpegSampleRates holds the sample rates by version and sample rate index.
+var mpegSampleRates = map[int][3]int{
+	mpegVersion1:  {44100, 48000, 32000},
+	mpegVersion2:  {22050, 24000, 16000},
+	mpegVersion25: {11025, 12000, 8000},
+}
+
+// mpegFrameHeaderSize is the size of an MPEG audio frame header.
+const mpegFrameHeaderSize = 4
+
+// maxMPEGSync is the maximum number of bytes that are skipped in order to find the first frame.
+const maxMPEGSync = 64 << 10
+
+// mpegFrame holds the relevant details of an MPEG audio frame header.
+type mpegFrame struct {
+	version         int
+	layer           int
+	sampleRate      int
+	samplesPerFrame int
+	length          int
+	mono            bool
+}
+
+// isMPEGAudioHeader checks if the given bytes start with an MPEG audio frame header.
+func isMPEGAudioHeader(b []byte) bool {
+	_, err := parseMPEGFrameHeader(b)
+	return err == nil
+}
+
+// parseMPEGFrameHeader parses the MPEG audio frame header at the start of the given bytes.
+func parseMPEGFrameHeader(b []byte) (mpegFrame, error) {
+	if len(b) < mpegFrameHeaderSize || b[0] != 0xFF || b[1]&0xE0 != 0xE0 {
+		return mpegFrame{}, fmt.Errorf("no frame sync")
+	}
+	frame := mpegFrame{
+		version: int(b[1]>>3) & 0x03,
+		layer:   int(b[1]>>1) & 0x03,
+		mono:    b[3]>>6 == 0x03,
+	}
+	if frame.version == 1 || frame.layer == 0 {
+		return mpegFrame{}, fmt.Errorf("reserved version or layer")
+	}
+	bitrateIndex := int(b[2] >> 4)
+	sampleRateIndex := int(b[2]>>2) & 0x03
+	if bitrateIndex == 0 || bitrateIndex == 15 || sampleRateIndex == 3 {
+		return mpegFrame{}, fmt.Errorf("unsupported bitrate or sample rate")
+	}
+	padding := int(b[2]>>1) & 0x01
+	bitrate := mpegBitrates[frame.version == mpegVersion1][frame.layer][bitrateIndex] * 1000
+	frame.sampleRate = mpegSampleRates[frame.version][sampleRateIndex]
+	switch {
+	case frame.layer == mpegLayer1:
+		frame.samplesPerFrame = 384
+		frame.length = (12*bitrate/frame.sampleRate + padding) * 4
+	case frame.layer == mpegLayer3 && frame.version != mpegVersion1:
+		frame.samplesPerFrame = 576
+		frame.length = 72*bitrate/frame.sampleRate + padding
+	default:
+		frame.samplesPerFrame = 1152
+		frame.length = 144*bitrate/frame.sampleRate + padding
+	}
+	return frame, nil
+}
+
+// xingOffset returns the offset of a Xing or Info header from the start of the frame which follows the side
+// information.
+func (frame mpegFrame) xingOffset() int {
+	if frame.version == mpegVersion1 {
+		if frame.mono {
+			return mpegFrameHeaderSize + 17
+		}
+		return mpegFrameHeaderSize + 32
+	}
+	if frame.mono {
+		return mpegFrameHeaderSize + 9
+	}
+	return mpegFrameHeaderSize + 17
+}
+
+// vbriOffset is the offset of a VBRI header from the start of the frame.
+const vbriOffset = mpegFrameHeaderSize + 32
+
+// vbrFrameCount reads the number of frames from a Xing/Info or VBRI header in the given first frame. If there is
+// none, false is returned.
+func vbrFrameCount(frame mpegFrame, data []byte) (uint32, bool) {
+	xing := frame.xingOffset()
+	if len(data) >= xing+12 && (bytes.Equal(data[xing:xing+4], []byte("Xing")) ||
+		bytes.Equal(data[xing:xing+4], []byte("Info"))) {
+		flags := binary.BigEndian.Uint32(data[xing+4 : xing+8])
+		// The frame count is optional.
+		if flags&0x01 != 0 {
+			return binary.BigEndian.Uint32(data[xing+8 : xing+12]), true
+		}
+		return 0, false
+	}
+	if len(data) >= vbriOffset+18 && bytes.Equal(data[vbriOffset:vbriOffset+4], []byte("VBRI")) {
+		return binary.BigEndian.Uint32(data[vbriOffset+14 : vbriOffset+18]), true
+	}
+	return 0, false
+}
+
+// mp3Duration returns the exact duration of the given mp3. If the first frame holds a Xing/Info or VBRI header, the
+// frame count is read from it. Otherwise, all frames are counted.
+func mp3Duration(r io.ReadSeeker) (time.Duration, error) {
+	br := bufio.NewReader(r)
+	// Find the first frame as there might be padding after an ID3 tag.
+	var first mpegFrame
+	for skipped := 0; ; skipped++ {
+		header, err := br.Peek(mpegFrameHeaderSize)
+		if err != nil {
+			return 0, fmt.Errorf("could not find first frame: %v", err)
+		}
+		if first, err = parseMPEGFrameHeader(header); err == nil {
+			break
+		}
+		if skipped >= maxMPEGSync {
+			return 0, fmt.Errorf("no frame found in the first %d bytes", maxMPEGSync)
+		}
+		_, _ = br.Discard(1)
+	}
+	// Check for a vbr header.
+	data, err := br.Peek(first.length)
+	if err != nil && err != io.EOF {
+		return 0, fmt.Errorf("could not read first frame: %v", err)
+	}
+	if frames, ok := vbrFrameCount(first, data); ok {
+		return samplesDuration(uint64(frames)*uint64(first.samplesPerFrame), uint64(first.sampleRate)), nil
+	}
+	// Count all frames. Anything after the last valid frame like an ID3v1 tag is ignored.
+	var samples uint64
+	for {
+		header, err := br.Peek(mpegFrameHeaderSize)
+		if err != nil {
+			break
+		}
+		frame, err := parseMPEGFrameHeader(header)
+		if err != nil || frame.sampleRate != first.sampleRate {
+			break
+		}
+		if _, err = br.Discard(frame.length); err != nil {
+			// Truncated last frame.
+			break
+		}
+		samples += uint64(frame.samplesPerFrame)
+	}
+	return samplesDuration(samples, uint64(first.sampleRate)), nil
+}
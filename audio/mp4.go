@@ -0,0 +1,99 @@
+package audio
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// mp4Duration reads the duration from the movie header box of the given mp4 container.
+func mp4Duration(r io.ReadSeeker) (time.Duration, error) {
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, fmt.Errorf("could not seek: %v", err)
+	}
+	end, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, fmt.Errorf("could not seek: %v", err)
+	}
+	moovStart, moovEnd, found, err := findMP4Box(r, start, end, "moov")
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, fmt.Errorf("no moov box found")
+	}
+	mvhdStart, _, found, err := findMP4Box(r, moovStart, moovEnd, "mvhd")
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, fmt.Errorf("no mvhd box found")
+	}
+	if _, err = r.Seek(mvhdStart, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("could not seek: %v", err)
+	}
+	version, err := readUint(r, 1)
+	if err != nil {
+		return 0, fmt.Errorf("could not read mvhd version: %v", err)
+	}
+	// Skip flags, creation and modification time.
+	fieldSize := 4
+	if version == 1 {
+		fieldSize = 8
+	}
+	if _, err = r.Seek(int64(3+2*fieldSize), io.SeekCurrent); err != nil {
+		return 0, fmt.Errorf("could not seek: %v", err)
+	}
+	timescale, err := readUint(r, 4)
+	if err != nil {
+		return 0, fmt.Errorf("could not read timescale: %v", err)
+	}
+	duration, err := readUint(r, fieldSize)
+	if err != nil {
+		return 0, fmt.Errorf("could not read duration: %v", err)
+	}
+	if timescale == 0 {
+		return 0, fmt.Errorf("invalid timescale")
+	}
+	return samplesDuration(duration, timescale), nil
+}
+
+// findMP4Box searches the box with the given type between start and end and returns the start and end of its
+// content.
+func findMP4Box(r io.ReadSeeker, start, end int64, boxType string) (int64, int64, bool, error) {
+	pos := start
+	for pos+8 <= end {
+		if _, err := r.Seek(pos, io.SeekStart); err != nil {
+			return 0, 0, false, fmt.Errorf("could not seek: %v", err)
+		}
+		size, err := readUint(r, 4)
+		if err != nil {
+			return 0, 0, false, fmt.Errorf("could not read box size: %v", err)
+		}
+		typ := make([]byte, 4)
+		if _, err = io.ReadFull(r, typ); err != nil {
+			return 0, 0, false, fmt.Errorf("could not read box type: %v", err)
+		}
+		headerSize := int64(8)
+		switch size {
+		case 0:
+			// Box extends to the end.
+			size = uint64(end - pos)
+		case 1:
+			// 64 bit size follows.
+			if size, err = readUint(r, 8); err != nil {
+				return 0, 0, false, fmt.Errorf("could not read box size: %v", err)
+			}
+			headerSize = 16
+		}
+		if size < uint64(headerSize) || pos+int64(size) > end {
+			return 0, 0, false, fmt.Errorf("invalid size of box %s", typ)
+		}
+		if string(typ) == boxType {
+			return pos + headerSize, pos + int64(size), true, nil
+		}
+		pos += int64(size)
+	}
+	return 0, 0, false, nil
+}
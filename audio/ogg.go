@@ -0,0 +1,96 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// oggPageHeaderSize is the size of an ogg page header without the segment table.
+const oggPageHeaderSize = 27
+
+// opusSampleRate is the rate of opus granule positions regardless of the input sample rate.
+const opusSampleRate = 48000
+
+// oggPage is the relevant part of an ogg page header.
+type oggPage struct {
+	granulePosition uint64
+	serial          uint32
+	bodySize        int64
+}
+
+// readOggPage reads the header of an ogg page. The reader is positioned at the start of the page body afterwards.
+func readOggPage(r io.Reader) (oggPage, error) {
+	header := make([]byte, oggPageHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return oggPage{}, err
+	}
+	if !bytes.HasPrefix(header, []byte("OggS")) {
+		return oggPage{}, fmt.Errorf("invalid page capture pattern")
+	}
+	segmentTable := make([]byte, header[26])
+	if _, err := io.ReadFull(r, segmentTable); err != nil {
+		return oggPage{}, fmt.Errorf("could not read segment table: %v", err)
+	}
+	page := oggPage{
+		granulePosition: binary.LittleEndian.Uint64(header[6:14]),
+		serial:          binary.LittleEndian.Uint32(header[14:18]),
+	}
+	for _, segmentSize := range segmentTable {
+		page.bodySize += int64(segmentSize)
+	}
+	return page, nil
+}
+
+// oggDuration detects whether the given ogg stream contains vorbis or opus and reads the duration from the granule
+// position of its last page.
+func oggDuration(r io.ReadSeeker) (Format, time.Duration, error) {
+	firstPage, err := readOggPage(r)
+	if err != nil {
+		return "", 0, fmt.Errorf("could not read first page: %v", err)
+	}
+	body := make([]byte, firstPage.bodySize)
+	if _, err = io.ReadFull(r, body); err != nil {
+		return "", 0, fmt.Errorf("could not read first page: %v", err)
+	}
+	var format Format
+	var sampleRate, preSkip uint64
+	switch {
+	case bytes.HasPrefix(body, []byte("\x01vorbis")) && len(body) >= 16:
+		format = FormatVorbis
+		sampleRate = uint64(binary.LittleEndian.Uint32(body[12:16]))
+	case bytes.HasPrefix(body, []byte("OpusHead")) && len(body) >= 12:
+		format = FormatOpus
+		sampleRate = opusSampleRate
+		preSkip = uint64(binary.LittleEndian.Uint16(body[10:12]))
+	default:
+		return "", 0, fmt.Errorf("unsupported ogg codec")
+	}
+	// Find the last granule position of the stream.
+	var lastGranulePosition uint64
+	for {
+		page, err := readOggPage(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return format, 0, fmt.Errorf("could not read page: %v", err)
+		}
+		// A granule position of -1 means that no packet finishes on the page.
+		if page.serial == firstPage.serial && page.granulePosition != ^uint64(0) {
+			lastGranulePosition = page.granulePosition
+		}
+		if _, err = r.Seek(page.bodySize, io.SeekCurrent); err != nil {
+			return format, 0, fmt.Errorf("could not seek: %v", err)
+		}
+	}
+	if sampleRate == 0 {
+		return format, 0, fmt.Errorf("invalid sample rate")
+	}
+	if lastGranulePosition < preSkip {
+		return format, 0, fmt.Errorf("no audio data")
+	}
+	return format, samplesDuration(lastGranulePosition-preSkip, sampleRate), nil
+}
@@ -0,0 +1,67 @@
+// Package auth provides API keys and their scopes which are used for authenticating requests to the API.
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// Scope determines what an APIKey is allowed to do.
+type Scope string
+
+const (
+	// ScopeRead allows read-only access.
+	ScopeRead Scope = "read"
+	// ScopeAdmin allows full access including write operations.
+	ScopeAdmin Scope = "admin"
+)
+
+// keyPrefix is the prefix of each generated key which makes it easier to recognize keys.
+const keyPrefix = "pcn_"
+
+// keyLength is the number of random bytes a generated key consists of.
+const keyLength = 32
+
+// APIKey is a key used for authenticating requests. The key itself is never stored, only its hash.
+type APIKey struct {
+	Id      int       `json:"id"`
+	Name    string    `json:"name"`
+	Scope   Scope     `json:"scope"`
+	Created time.Time `json:"created"`
+}
+
+// IsValid checks if the Scope is a known one.
+func (s Scope) IsValid() bool {
+	return s == ScopeRead || s == ScopeAdmin
+}
+
+// Allows checks if the Scope includes the given required one.
+func (s Scope) Allows(required Scope) bool {
+	switch s {
+	case ScopeAdmin:
+		return required == ScopeAdmin || required == ScopeRead
+	case ScopeRead:
+		return required == ScopeRead
+	default:
+		return false
+	}
+}
+
+// GenerateKey generates a new random key. Only its hash created with HashKey should be stored.
+func GenerateKey() (string, error) {
+	raw := make([]byte, keyLength)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("could not read random bytes: %v", err)
+	}
+	return keyPrefix + base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// HashKey hashes the given key for storing and lookup. As keys are random and long, a plain sha256 is sufficient.
+func HashKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
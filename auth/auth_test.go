@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/suite"
+	"strings"
+	"testing"
+)
+
+type ScopeTestSuite struct {
+	suite.Suite
+}
+
+func (suite *ScopeTestSuite) TestAdminAllowsAll() {
+	suite.Assert().True(ScopeAdmin.Allows(ScopeAdmin), "admin should allow admin")
+	suite.Assert().True(ScopeAdmin.Allows(ScopeRead), "admin should allow read")
+}
+
+func (suite *ScopeTestSuite) TestReadAllowsOnlyRead() {
+	suite.Assert().True(ScopeRead.Allows(ScopeRead), "read should allow read")
+	suite.Assert().False(ScopeRead.Allows(ScopeAdmin), "read should not allow admin")
+}
+
+func (suite *ScopeTestSuite) TestUnknownAllowsNothing() {
+	unknown := Scope("unknown")
+	suite.Assert().False(unknown.IsValid(), "unknown scope should not be valid")
+	suite.Assert().False(unknown.Allows(ScopeRead), "unknown scope should not allow read")
+}
+
+func Test_Scope(t *testing.T) {
+	suite.Run(t, new(ScopeTestSuite))
+}
+
+type KeyTestSuite struct {
+	suite.Suite
+}
+
+func (suite *KeyTestSuite) TestGenerateUnique() {
+	a, err := GenerateKey()
+	suite.Require().Nilf(err, "generating key should not fail but got %s", err)
+	b, err := GenerateKey()
+	suite.Require().Nilf(err, "generating key should not fail but got %s", err)
+	suite.Assert().True(strings.HasPrefix(a, keyPrefix), "key should have prefix")
+	suite.Assert().NotEqual(a, b, "generated keys should differ")
+}
+
+func (suite *KeyTestSuite) TestHashStable() {
+	suite.Assert().Equal(HashKey("hello"), HashKey("hello"), "hashes of same key should be equal")
+	suite.Assert().NotEqual(HashKey("hello"), HashKey("hello!"), "hashes of different keys should differ")
+	suite.Assert().NotContains(HashKey("hello"), "hello", "hash should not contain key")
+}
+
+func Test_Key(t *testing.T) {
+	suite.Run(t, new(KeyTestSuite))
+}
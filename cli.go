@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/life-unlimited/podcastination-server/app"
+	"github.com/life-unlimited/podcastination-server/auth"
+	"github.com/pkg/errors"
+	"strconv"
+)
+
+// runCommand runs the CLI command with the given arguments instead of starting the server.
+func runCommand(podcastination *app.App, args []string) error {
+	switch args[0] {
+	case "apikey":
+		return runAPIKeyCommand(podcastination, args[1:])
+	case "integrity":
+		return runIntegrityCommand(podcastination, args[1:])
+	case "task":
+		return runTaskCommand(podcastination, args[1:])
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// runAPIKeyCommand runs the apikey command which allows creating, listing and revoking api keys.
+func runAPIKeyCommand(podcastination *app.App, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: apikey create|list|revoke")
+	}
+	switch args[0] {
+	case "create":
+		flags := flag.NewFlagSet("apikey create", flag.ContinueOnError)
+		name := flags.String("name", "", "Name of the api key for recognizing it")
+		scope := flags.String("scope", string(auth.ScopeRead), "Scope of the api key (read or admin)")
+		if err := flags.Parse(args[1:]); err != nil {
+			return errors.Wrap(err, "parse flags")
+		}
+		if *name == "" {
+			return fmt.Errorf("no name provided")
+		}
+		if !auth.Scope(*scope).IsValid() {
+			return fmt.Errorf("unknown scope %q", *scope)
+		}
+		if err := podcastination.Connect(); err != nil {
+			return errors.Wrap(err, "connect")
+		}
+		key, err := auth.GenerateKey()
+		if err != nil {
+			return errors.Wrap(err, "generate key")
+		}
+		created, err := podcastination.Stores.APIKeys.Create(auth.APIKey{
+			Name:  *name,
+			Scope: auth.Scope(*scope),
+		}, auth.HashKey(key))
+		if err != nil {
+			return errors.Wrap(err, "create api key")
+		}
+		fmt.Printf("created api key %d (%s) with scope %s:\n%s\n", created.Id, created.Name, created.Scope, key)
+		fmt.Println("store it safely as it cannot be shown again.")
+		return nil
+	case "list":
+		if err := podcastination.Connect(); err != nil {
+			return errors.Wrap(err, "connect")
+		}
+		keys, err := podcastination.Stores.APIKeys.All()
+		if err != nil {
+			return errors.Wrap(err, "get all api keys")
+		}
+		for _, key := range keys {
+			fmt.Printf("%d\t%s\t%s\t%s\n", key.Id, key.Scope, key.Created.Format("2006-01-02 15:04:05"), key.Name)
+		}
+		return nil
+	case "revoke":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: apikey revoke <id>")
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid api key id: %v", err)
+		}
+		if err := podcastination.Connect(); err != nil {
+			return errors.Wrap(err, "connect")
+		}
+		if err := podcastination.Stores.APIKeys.Delete(id); err != nil {
+			return errors.Wrap(err, "delete api key")
+		}
+		fmt.Printf("revoked api key %d\n", id)
+		return nil
+	default:
+		return fmt.Errorf("unknown apikey command %q", args[0])
+	}
+}
+
+// runIntegrityCommand runs the integrity check and prints the report as json.
+func runIntegrityCommand(podcastination *app.App, args []string) error {
+	flags := flag.NewFlagSet("integrity", flag.ContinueOnError)
+	checkLengths := flags.Bool("check-lengths", false, "Check mp3 lengths of all episodes")
+	repair := flags.Bool("repair", false, "Repair found issues")
+	if err := flags.Parse(args); err != nil {
+		return errors.Wrap(err, "parse flags")
+	}
+	if err := podcastination.Connect(); err != nil {
+		return errors.Wrap(err, "connect")
+	}
+	report, err := podcastination.CheckIntegrity(*checkLengths, *repair)
+	if err != nil {
+		return errors.Wrap(err, "check integrity")
+	}
+	reportRaw, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "marshal report")
+	}
+	fmt.Println(string(reportRaw))
+	return nil
+}
+
+// runTaskCommand runs the task command which allows listing and requeueing failed import tasks as well as listing
+// warnings of scheduled imports.
+func runTaskCommand(podcastination *app.App, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: task failed|requeue|warnings")
+	}
+	switch args[0] {
+	case "failed":
+		failedTasks, err := podcastination.FailedImportTasks()
+		if err != nil {
+			return errors.Wrap(err, "get failed import tasks")
+		}
+		for _, failedTask := range failedTasks {
+			fmt.Printf("%s\t%s\t%d attempts\t%s\n", failedTask.Name,
+				failedTask.Error.Timestamp.Format("2006-01-02 15:04:05"), failedTask.Error.Attempts,
+				failedTask.Error.Reason)
+		}
+		return nil
+	case "requeue":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: task requeue <name>")
+		}
+		if err := podcastination.RequeueFailedImportTask(args[1]); err != nil {
+			return errors.Wrap(err, "requeue failed import task")
+		}
+		fmt.Printf("requeued task %s\n", args[1])
+		return nil
+	case "warnings":
+		importWarnings, err := podcastination.ImportWarnings()
+		if err != nil {
+			return errors.Wrap(err, "get import warnings")
+		}
+		for _, w := range importWarnings {
+			for _, warning := range w.Warnings {
+				fmt.Printf("%s\t%s\tepisode %d\t%s\n", w.Name, w.Timestamp.Format("2006-01-02 15:04:05"),
+					w.EpisodeId, warning)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown task command %q", args[0])
+	}
+}
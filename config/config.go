@@ -7,6 +7,10 @@ import (
 	"os"
 )
 
+// DefaultMaxImportAttempts is the number of attempts after which a failing task is moved to the failed dir if not
+// configured otherwise.
+const DefaultMaxImportAttempts = 5
+
 // PodcastinationConfig holds all important config values needed in order to run the App.
 type PodcastinationConfig struct {
 	// StaticContentURL is the base url for accessing static content.
@@ -19,8 +23,73 @@ type PodcastinationConfig struct {
 	PodcastDir string `json:"podcast_dir"`
 	// ImportInterval defines the duration in minutes after import tasks are retrieved.
 	ImportInterval int `json:"import_interval"`
+	// WatchPullDir enables watching the PullDir for changes, so that tasks are imported as soon as they are complete
+	// instead of waiting for the next ImportInterval. Polling is still performed as fallback.
+	WatchPullDir bool `json:"watch_pull_dir"`
+	// WatchStabilityDelay is the duration in seconds the files of a watched task must not change before it is
+	// imported. If zero, a default of 10 seconds is used.
+	WatchStabilityDelay int `json:"watch_stability_delay"`
+	// FailedDir is the directory where failed tasks are moved to. If empty, a hidden directory in the PullDir is used.
+	FailedDir string `json:"failed_dir"`
+	// MaxImportAttempts is the number of attempts after which a failing task is moved to the FailedDir. If zero,
+	// DefaultMaxImportAttempts is used.
+	MaxImportAttempts int `json:"max_import_attempts"`
+	// ImportRetryBackoff is the duration in minutes to wait before retrying a failed task. It is doubled with each
+	// attempt. If zero, the ImportInterval is used.
+	ImportRetryBackoff int `json:"import_retry_backoff"`
 	// ServerAddr is the address the static file web_server will listen on (for example 127.0.0.1:8000).
 	ServerAddr string `json:"server_addr"`
+	// APIURL is the public base url of the API, for example https://podcasts.example.com. It is used for the urls of
+	// sub-feeds. If empty, the StaticContentURL without the /static suffix is used.
+	APIURL string `json:"api_url"`
+	// CORSAllowedOrigins are the origins that are allowed to access the API from browsers. If empty, all origins are
+	// allowed.
+	CORSAllowedOrigins []string `json:"cors_allowed_origins"`
+	// MaxUploadSize is the maximum size in megabytes of import tasks uploaded via the API. If zero, there is no limit.
+	MaxUploadSize int `json:"max_upload_size"`
+	// IntegrityCheckLengths enables checking the mp3 lengths of all episodes in the integrity check on startup.
+	IntegrityCheckLengths bool `json:"integrity_check_lengths"`
+	// IntegrityRepair enables repairing issues found in the integrity check on startup.
+	IntegrityRepair bool `json:"integrity_repair"`
+	// OrphanDir is the directory where orphan files found in the integrity check are moved to when repairing. If
+	// empty, they are kept.
+	OrphanDir string `json:"orphan_dir"`
+	// Storage configures an object storage the PodcastDir is mirrored to. If not set, files are only served from the
+	// PodcastDir.
+	Storage StorageConfig `json:"storage"`
+	// WebSub configures the WebSub hubs that are notified about changed feeds.
+	WebSub WebSubConfig `json:"websub"`
+}
+
+// StorageTypeS3 is the StorageConfig type for S3-compatible object storages.
+const StorageTypeS3 = "s3"
+
+// StorageConfig configures the storage media and feeds are served from.
+type StorageConfig struct {
+	// Type is either empty for the PodcastDir only or StorageTypeS3.
+	Type string `json:"type"`
+	// Endpoint is the url of the object storage, for example http://127.0.0.1:9000 for a local MinIO.
+	Endpoint  string `json:"endpoint"`
+	Region    string `json:"region"`
+	Bucket    string `json:"bucket"`
+	AccessKey string `json:"access_key"`
+	SecretKey string `json:"secret_key"`
+	// Prefix is prepended to all keys in the bucket.
+	Prefix string `json:"prefix"`
+	// Delete enables deleting objects below the Prefix that do not exist in the PodcastDir anymore, for example files
+	// of deleted episodes. Only enable it if the bucket or Prefix is used exclusively for the PodcastDir.
+	Delete bool `json:"delete"`
+}
+
+// WebSubConfig configures WebSub hubs. If neither hubs nor the built-in hub are configured, no hubs are announced.
+type WebSubConfig struct {
+	// Hubs are the urls of external hubs like https://pubsubhubbub.appspot.com/.
+	Hubs []string `json:"hubs"`
+	// BuiltInHubURL is the public url of the built-in hub which is served at /websub. If empty, it is disabled.
+	BuiltInHubURL string `json:"built_in_hub_url"`
+	// AllowPrivateCallbacks allows subscribers of the built-in hub with loopback, private and link-local addresses.
+	// This is meant for testing with local subscribers only.
+	AllowPrivateCallbacks bool `json:"allow_private_callbacks"`
 }
 
 // ReadConfig reads a PodcastinationConfig from the given filepath.
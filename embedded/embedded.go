@@ -7,3 +7,47 @@ import _ "embed"
 //go:embed sql/1x0.sql
 // DBMigration1x0 is the initial database setup from first version.
 var DBMigration1x0 string
+
+//go:embed sql/1x1.sql
+// DBMigration1x1 adds api keys.
+var DBMigration1x1 string
+
+//go:embed sql/1x2.sql
+// DBMigration1x2 adds the mime type of episode audio files.
+var DBMigration1x2 string
+
+//go:embed sql/1x3.sql
+// DBMigration1x3 adds the file size of episode audio files.
+var DBMigration1x3 string
+
+//go:embed sql/1x4.sql
+// DBMigration1x4 adds details for the podcast namespace.
+var DBMigration1x4 string
+
+//go:embed sql/1x5.sql
+// DBMigration1x5 adds transcripts of episodes.
+var DBMigration1x5 string
+
+//go:embed sql/1x6.sql
+// DBMigration1x6 adds chapters of episodes.
+var DBMigration1x6 string
+
+//go:embed sql/1x7.sql
+// DBMigration1x7 adds iTunes categories and explicit flags.
+var DBMigration1x7 string
+
+//go:embed sql/1x8.sql
+// DBMigration1x8 adds episode types.
+var DBMigration1x8 string
+
+//go:embed sql/1x9.sql
+// DBMigration1x9 adds publish dates of episodes.
+var DBMigration1x9 string
+
+//go:embed sql/1x10.sql
+// DBMigration1x10 adds guids of episodes and keeps the previous ones as legacy guids.
+var DBMigration1x10 string
+
+//go:embed sql/1x11.sql
+// DBMigration1x11 adds episode-level overrides of the season number and title.
+var DBMigration1x11 string
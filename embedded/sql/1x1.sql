@@ -0,0 +1,13 @@
+create table api_keys
+(
+    id       serial
+        constraint api_keys_pk
+            primary key,
+    name     varchar                 not null,
+    key_hash varchar                 not null,
+    scope    varchar                 not null,
+    created  timestamp default now() not null
+);
+
+create unique index api_keys_key_hash_uindex
+    on api_keys (key_hash);
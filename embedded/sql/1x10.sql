@@ -0,0 +1,17 @@
+alter table episodes
+    add guid uuid;
+
+create unique index episodes_guid_uindex
+    on episodes (guid);
+
+alter table episodes
+    add legacy_guid varchar;
+
+update episodes
+set legacy_guid = coalesce(mp3_location, '');
+
+insert into podcastination (key, value)
+values ('legacy-guids-unresolved', 'true');
+
+insert into podcastination (key, value)
+values ('episode-guids-ungenerated', 'true');
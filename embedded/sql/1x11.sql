@@ -0,0 +1,5 @@
+alter table episodes
+    add season_num integer;
+
+alter table episodes
+    add season_title varchar;
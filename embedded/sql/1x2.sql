@@ -0,0 +1,2 @@
+alter table episodes
+    add mime_type varchar default 'audio/mpeg' not null;
@@ -0,0 +1,2 @@
+alter table episodes
+    add file_size bigint default 0 not null;
@@ -0,0 +1,17 @@
+alter table podcasts
+    add guid varchar;
+
+alter table podcasts
+    add locked boolean default false not null;
+
+alter table podcasts
+    add funding jsonb default '[]' not null;
+
+alter table podcasts
+    add persons jsonb default '[]' not null;
+
+alter table episodes
+    add persons jsonb default '[]' not null;
+
+alter table episodes
+    add alternate_enclosures jsonb default '[]' not null;
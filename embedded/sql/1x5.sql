@@ -0,0 +1,2 @@
+alter table episodes
+    add transcripts jsonb default '[]' not null;
@@ -0,0 +1,15 @@
+create table chapters
+(
+    id         serial
+        constraint chapters_pk
+            primary key,
+    episode_id integer          not null
+        constraint chapters_episodes_id_fk
+            references episodes
+            on delete cascade,
+    start_time double precision not null,
+    title      varchar          not null
+);
+
+create index chapters_episode_id_index
+    on chapters (episode_id);
@@ -0,0 +1,8 @@
+alter table podcasts
+    add categories jsonb default '[]' not null;
+
+alter table podcasts
+    add explicit boolean default false not null;
+
+alter table episodes
+    add explicit boolean default false not null;
@@ -0,0 +1,5 @@
+alter table episodes
+    add episode_type varchar default 'full' not null;
+
+alter table episodes
+    alter column num drop not null;
@@ -0,0 +1,2 @@
+alter table episodes
+    add publish_at timestamp with time zone;
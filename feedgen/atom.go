@@ -0,0 +1,114 @@
+package feedgen
+
+import (
+	"encoding/xml"
+	"github.com/life-unlimited/podcastination-server/podcast_xml"
+	"github.com/pkg/errors"
+	"time"
+)
+
+type atomFeed struct {
+	XMLName    xml.Name       `xml:"feed"`
+	Xmlns      string         `xml:"xmlns,attr"`
+	Lang       string         `xml:"xml:lang,attr,omitempty"`
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Subtitle   string         `xml:"subtitle,omitempty"`
+	Updated    string         `xml:"updated"`
+	Author     atomPerson     `xml:"author"`
+	Rights     string         `xml:"rights,omitempty"`
+	Links      []atomLink     `xml:"link"`
+	Logo       string         `xml:"logo,omitempty"`
+	Categories []atomCategory `xml:"category"`
+	Entries    []atomEntry    `xml:"entry"`
+}
+
+type atomPerson struct {
+	Name  string `xml:"name"`
+	Email string `xml:"email,omitempty"`
+}
+
+type atomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr,omitempty"`
+	Type   string `xml:"type,attr,omitempty"`
+	Length int64  `xml:"length,attr,omitempty"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Updated   string      `xml:"updated"`
+	Published string      `xml:"published"`
+	Author    *atomPerson `xml:"author,omitempty"`
+	Summary   string      `xml:"summary,omitempty"`
+	Links     []atomLink  `xml:"link"`
+}
+
+// renderAtom renders the feed in the Atom Syndication Format.
+func renderAtom(details podcast_xml.CreationDetails) ([]byte, error) {
+	episodes, err := newestFirst(details)
+	if err != nil {
+		return nil, err
+	}
+	podcast := details.Podcast
+	feed := atomFeed{
+		Xmlns:    "http://www.w3.org/2005/Atom",
+		Lang:     string(podcast.Language),
+		ID:       "urn:uuid:" + podcast.GUIDOrDefault(),
+		Title:    podcast.Title,
+		Subtitle: podcast.Subtitle,
+		Updated:  lastUpdated(episodes).Format(time.RFC3339),
+		Author: atomPerson{
+			Name:  details.Owner.Name,
+			Email: details.Owner.Email,
+		},
+		Rights: details.Owner.Copyright,
+		Links: []atomLink{
+			{Href: feedURL(details, atomFileName), Rel: "self", Type: atomContentType},
+		},
+	}
+	if podcast.Link != "" {
+		feed.Links = append(feed.Links, atomLink{Href: podcast.Link, Rel: "alternate"})
+	}
+	for _, hub := range details.Hubs {
+		feed.Links = append(feed.Links, atomLink{Href: hub, Rel: "hub"})
+	}
+	if podcast.ImageLocation != "" {
+		feed.Logo = staticURL(details, podcast.ImageLocation)
+	}
+	for _, category := range podcast.CategoriesOrDefault() {
+		feed.Categories = append(feed.Categories, atomCategory{Term: category.Name})
+	}
+	for _, e := range episodes {
+		episode := e.Episode
+		entry := atomEntry{
+			ID:        episodeIRI(episode),
+			Title:     podcast_xml.EpisodeTitle(episode),
+			Updated:   episode.Date.Format(time.RFC3339),
+			Published: episode.Date.Format(time.RFC3339),
+			Summary:   episode.Description,
+			Links: []atomLink{
+				{
+					Href:   staticURL(details, episode.MP3Location),
+					Rel:    "enclosure",
+					Type:   podcast_xml.EnclosureType(episode),
+					Length: episode.FileSize,
+				},
+			},
+		}
+		if episode.Author != "" {
+			entry.Author = &atomPerson{Name: episode.Author}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	raw, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal atom feed")
+	}
+	return append([]byte(xml.Header), raw...), nil
+}
@@ -1,86 +1,108 @@
-// Package feedgen is used for generating the podcast xml.
+// Package feedgen is used for generating the podcast xml and feeds in further formats.
 package feedgen
 
 import (
-	"encoding/xml"
 	"fmt"
 	"github.com/life-unlimited/podcastination-server/podcast_xml"
-	"github.com/life-unlimited/podcastination-server/podcasts"
 	"github.com/life-unlimited/podcastination-server/stores"
-	"github.com/life-unlimited/podcastination-server/transfer"
 	"github.com/pkg/errors"
-	"io/ioutil"
-	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 )
 
-// RefreshFeedForPodcasts generates feeds for all podcasts.
-func RefreshFeedForPodcasts(store stores.Stores, staticContentURL, podcastDir, feedFileName string) error {
+// maxParallelFeedRefreshes is the maximum number of podcasts whose feeds are refreshed in parallel.
+const maxParallelFeedRefreshes = 4
+
+// RefreshFeedForPodcasts generates feeds for all podcasts. Each podcast is refreshed on its own with bounded
+// parallelism, so that a failing podcast does not keep the other ones from being refreshed. Failures are returned
+// combined after all podcasts have been processed. The given hubs are announced in the feeds. The urls of the changed
+// feeds are returned.
+func RefreshFeedForPodcasts(store stores.Stores, staticContentURL, podcastDir, feedFileName string,
+	hubs []string) ([]string, error) {
 	storePodcasts, err := store.Podcasts.All()
 	if err != nil {
-		return errors.Wrap(err, "get all podcasts from store")
+		return nil, errors.Wrap(err, "get all podcasts from store")
+	}
+	podcastIds := make(chan int)
+	var resultMutex sync.Mutex
+	changed := make([]string, 0)
+	failed := make([]string, 0)
+	var wg sync.WaitGroup
+	for i := 0; i < maxParallelFeedRefreshes; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for podcastId := range podcastIds {
+				changedFeeds, err := RefreshFeedForPodcast(store, staticContentURL, podcastDir, feedFileName, hubs,
+					podcastId)
+				resultMutex.Lock()
+				changed = append(changed, changedFeeds...)
+				if err != nil {
+					failed = append(failed, fmt.Sprintf("podcast %d: %v", podcastId, err))
+				}
+				resultMutex.Unlock()
+			}
+		}()
+	}
+	for _, podcast := range storePodcasts {
+		podcastIds <- podcast.Id
+	}
+	close(podcastIds)
+	wg.Wait()
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return changed, errors.Errorf("could not refresh feeds of %d of %d podcasts: %s", len(failed),
+			len(storePodcasts), strings.Join(failed, "; "))
 	}
-	owners, err := store.Owners.All()
+	return changed, nil
+}
+
+// RefreshFeedForPodcast generates the feeds for the podcast with the given id. The given hubs are announced in the
+// feeds. The urls of the changed feeds are returned.
+func RefreshFeedForPodcast(store stores.Stores, staticContentURL, podcastDir, feedFileName string, hubs []string,
+	podcastId int) ([]string, error) {
+	creationDetails, err := CreationDetailsForPodcast(store, staticContentURL, podcastId)
 	if err != nil {
-		return errors.Wrap(err, "get all owners from store")
+		return nil, errors.Wrap(err, "get creation details")
 	}
-	seasons, err := store.Seasons.All()
+	creationDetails.Hubs = hubs
+	changed, err := WriteFeeds(creationDetails, podcastDir, feedFileName)
 	if err != nil {
-		return errors.Wrap(err, "get all seasons from store")
+		return changed, errors.Wrap(err, "write feeds")
 	}
-	episodes, err := store.Episodes.All()
+	return changed, nil
+}
+
+// CreationDetailsForPodcast retrieves the podcast_xml.CreationDetails for the podcast with the given id.
+func CreationDetailsForPodcast(store stores.Stores, staticContentURL string,
+	podcastId int) (podcast_xml.CreationDetails, error) {
+	podcast, err := store.Podcasts.ById(podcastId)
 	if err != nil {
-		return errors.Wrap(err, "get all episodes from store")
+		return podcast_xml.CreationDetails{}, errors.Wrap(err, "get podcast from store")
 	}
-	// For each podcast, we generate the feed.
-	for _, podcast := range storePodcasts {
-		creationDetails := podcast_xml.CreationDetails{
-			StaticContentURL: staticContentURL,
-			Podcast:          podcast,
-			Seasons:          make([]podcasts.Season, 0),
-			Episodes:         make([]podcasts.Episode, 0),
-		}
-		// Filter owners.
-		found := false
-		for _, owner := range owners {
-			if podcast.OwnerId == owner.Id {
-				creationDetails.Owner = owner
-				found = true
-				break
-			}
-		}
-		if !found {
-			return errors.Wrap(err, fmt.Sprintf("owner %d not found for podcast %d", podcast.OwnerId, podcast.Id))
-		}
-		// Filter seasons.
-		knownSeasonsForPodcast := make(map[int]struct{})
-		for _, season := range seasons {
-			if season.PodcastId == podcast.Id {
-				knownSeasonsForPodcast[season.Id] = struct{}{}
-				creationDetails.Seasons = append(creationDetails.Seasons, season)
-			}
-		}
-		// Filter episodes.
-		for _, episode := range episodes {
-			if _, ok := knownSeasonsForPodcast[episode.SeasonId]; ok {
-				creationDetails.Episodes = append(creationDetails.Episodes, episode)
-			}
-		}
-		// Generate.
-		podcastXML, err := podcast_xml.GeneratePodcastXML(creationDetails)
-		if err != nil {
-			return errors.Wrap(err, "generate podcast xml")
-		}
-		// Marshal.
-		podcastXMLRaw, err := xml.MarshalIndent(podcastXML, "", "  ")
-		if err != nil {
-			return errors.Wrap(err, "marshal podcast xml")
-		}
-		// Write.
-		podcastXMLFilePath := filepath.Join(podcastDir, transfer.GetPodcastFolderName(podcast.Id), feedFileName)
-		err = ioutil.WriteFile(podcastXMLFilePath, podcastXMLRaw, 0633)
-		if err != nil {
-			return errors.Wrap(err, "write podcast xml")
-		}
+	owner, err := store.Owners.ById(podcast.OwnerId)
+	if err != nil {
+		return podcast_xml.CreationDetails{}, errors.Wrap(err, fmt.Sprintf("get owner %d from store", podcast.OwnerId))
+	}
+	seasons, err := store.Seasons.ByPodcast(podcastId)
+	if err != nil {
+		return podcast_xml.CreationDetails{}, errors.Wrap(err, "get seasons from store")
+	}
+	episodes, err := store.Episodes.ByPodcast(podcastId)
+	if err != nil {
+		return podcast_xml.CreationDetails{}, errors.Wrap(err, "get episodes from store")
+	}
+	chapters, err := store.Chapters.ByPodcast(podcastId)
+	if err != nil {
+		return podcast_xml.CreationDetails{}, errors.Wrap(err, "get chapters from store")
 	}
-	return nil
+	return podcast_xml.CreationDetails{
+		StaticContentURL: staticContentURL,
+		Owner:            owner,
+		Podcast:          podcast,
+		Seasons:          seasons,
+		Episodes:         episodes,
+		Chapters:         chapters,
+	}, nil
 }
@@ -0,0 +1,86 @@
+package feedgen
+
+import (
+	"fmt"
+	"github.com/life-unlimited/podcastination-server/podcast_xml"
+	"github.com/life-unlimited/podcastination-server/podcasts"
+	"github.com/pkg/errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrUnknownSeason is returned by Filter.Apply if the podcast has no season with the requested key.
+var ErrUnknownSeason = errors.New("unknown season")
+
+// Filter restricts the episodes of a feed for sub-feeds like the one of a single season. Empty fields are not used
+// for filtering.
+type Filter struct {
+	// SeasonKey is the key of the season episodes must belong to.
+	SeasonKey string
+	// Author is the author episodes must have. Case is ignored.
+	Author string
+	// From is the earliest date of episodes.
+	From time.Time
+	// Until is the date episodes must be before.
+	Until time.Time
+	// Newest limits the feed to the given number of newest episodes.
+	Newest int
+}
+
+// IsEmpty checks if the Filter does not filter anything.
+func (f Filter) IsEmpty() bool {
+	return f == Filter{}
+}
+
+// Apply returns the given podcast_xml.CreationDetails with only the public episodes at the given time that match the
+// Filter. The title of the podcast is extended with the season title and author if filtered by them.
+func (f Filter) Apply(details podcast_xml.CreationDetails, now time.Time) (podcast_xml.CreationDetails, error) {
+	filtered := details
+	seasonId := 0
+	if f.SeasonKey != "" {
+		found := false
+		for _, season := range details.Seasons {
+			if season.Key == f.SeasonKey {
+				filtered.Seasons = []podcasts.Season{season}
+				filtered.Podcast.Title = fmt.Sprintf("%s - %s", filtered.Podcast.Title, season.Title)
+				seasonId = season.Id
+				found = true
+				break
+			}
+		}
+		if !found {
+			return podcast_xml.CreationDetails{}, ErrUnknownSeason
+		}
+	}
+	if f.Author != "" {
+		filtered.Podcast.Title = fmt.Sprintf("%s - %s", filtered.Podcast.Title, f.Author)
+	}
+	episodes := make([]podcasts.Episode, 0)
+	for _, episode := range details.Episodes {
+		if !episode.IsPublic(now) {
+			continue
+		}
+		if seasonId != 0 && episode.SeasonId != seasonId {
+			continue
+		}
+		if f.Author != "" && !strings.EqualFold(strings.TrimSpace(episode.Author), strings.TrimSpace(f.Author)) {
+			continue
+		}
+		if !f.From.IsZero() && episode.Date.Before(f.From) {
+			continue
+		}
+		if !f.Until.IsZero() && !episode.Date.Before(f.Until) {
+			continue
+		}
+		episodes = append(episodes, episode)
+	}
+	if f.Newest > 0 && len(episodes) > f.Newest {
+		sort.SliceStable(episodes, func(i, j int) bool {
+			return episodes[i].Date.After(episodes[j].Date)
+		})
+		episodes = episodes[:f.Newest]
+	}
+	filtered.Episodes = episodes
+	return filtered, nil
+}
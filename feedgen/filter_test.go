@@ -0,0 +1,64 @@
+package feedgen
+
+import (
+	"github.com/life-unlimited/podcastination-server/podcasts"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// episodeIds returns the ids of the given episodes.
+func episodeIds(episodes []podcasts.Episode) []int {
+	ids := make([]int, 0, len(episodes))
+	for _, episode := range episodes {
+		ids = append(ids, episode.Id)
+	}
+	return ids
+}
+
+func TestFilterApply(t *testing.T) {
+	details := testCreationDetails()
+	details.Seasons = append(details.Seasons, podcasts.Season{Id: 7, PodcastId: 2, Num: 2, Key: "series", Title: "Series"})
+	details.Seasons[0].Key = "main"
+	details.Episodes[0].Author = "Jane Doe"
+	details.Episodes = append(details.Episodes, podcasts.Episode{
+		Id: 8, Title: "Other", SeasonId: 7, Num: 1, IsAvailable: true, Author: "John Doe",
+		Date: time.Date(2021, 1, 22, 10, 0, 0, 0, time.UTC),
+	})
+	now := time.Now()
+	tests := []struct {
+		name   string
+		filter Filter
+		ids    []int
+		title  string
+	}{
+		{name: "none", filter: Filter{}, ids: []int{4, 5, 8}, title: "Podcast"},
+		{name: "season", filter: Filter{SeasonKey: "series"}, ids: []int{8}, title: "Podcast - Series"},
+		{name: "author", filter: Filter{Author: "jane doe"}, ids: []int{4}, title: "Podcast - jane doe"},
+		{
+			name: "date range",
+			filter: Filter{
+				From:  time.Date(2021, 1, 8, 0, 0, 0, 0, time.UTC),
+				Until: time.Date(2021, 1, 22, 10, 0, 0, 0, time.UTC),
+			},
+			ids:   []int{5},
+			title: "Podcast",
+		},
+		{name: "newest", filter: Filter{Newest: 2}, ids: []int{8, 5}, title: "Podcast"},
+	}
+	for _, test := range tests {
+		filtered, err := test.filter.Apply(details, now)
+		if !assert.Nil(t, err, "applying %s filter should not fail", test.name) {
+			continue
+		}
+		assert.Equal(t, test.ids, episodeIds(filtered.Episodes), "episodes of %s filter should match", test.name)
+		assert.Equal(t, test.title, filtered.Podcast.Title, "title of %s filter should match", test.name)
+	}
+	_, err := Filter{SeasonKey: "unknown"}.Apply(details, now)
+	assert.Equal(t, ErrUnknownSeason, err, "unknown season should fail")
+	filtered, err := Filter{SeasonKey: "series"}.Apply(details, now)
+	if assert.Nil(t, err, "applying filter should not fail") {
+		_, err = FormatRSS.Render(filtered)
+		assert.Nil(t, err, "rendering filtered feed should not fail")
+	}
+}
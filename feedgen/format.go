@@ -0,0 +1,246 @@
+package feedgen
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/xml"
+	"fmt"
+	"github.com/life-unlimited/podcastination-server/podcast_xml"
+	"github.com/life-unlimited/podcastination-server/podcasts"
+	"github.com/life-unlimited/podcastination-server/transfer"
+	"github.com/pkg/errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format is a feed format which podcast_xml.CreationDetails can be rendered to.
+type Format struct {
+	// Name identifies the format, for example in query parameters.
+	Name string
+	// ContentType is the MIME type of rendered feeds.
+	ContentType string
+	// alternativeContentTypes are further MIME types that are accepted for the format in content negotiation.
+	alternativeContentTypes []string
+	// FileName is the name of the file the feed is written to in the podcast folder. It is empty for RSS as the file
+	// name is provided when refreshing feeds.
+	FileName string
+	render   func(details podcast_xml.CreationDetails) ([]byte, error)
+}
+
+// Render renders the feed for the given podcast_xml.CreationDetails.
+func (f Format) Render(details podcast_xml.CreationDetails) ([]byte, error) {
+	return f.render(details)
+}
+
+const (
+	atomContentType  = "application/atom+xml"
+	atomFileName     = "atom.xml"
+	jsonFeedFileName = "feed.json"
+)
+
+var (
+	// FormatRSS is RSS 2.0 as generated by podcast_xml.
+	FormatRSS = Format{
+		Name:                    "rss",
+		ContentType:             "application/rss+xml",
+		alternativeContentTypes: []string{"application/xml", "text/xml"},
+		render:                  renderRSS,
+	}
+	// FormatAtom is the Atom Syndication Format.
+	FormatAtom = Format{
+		Name:        "atom",
+		ContentType: atomContentType,
+		FileName:    atomFileName,
+		render:      renderAtom,
+	}
+	// FormatJSONFeed is JSON Feed 1.1.
+	FormatJSONFeed = Format{
+		Name:                    "json",
+		ContentType:             "application/feed+json",
+		alternativeContentTypes: []string{"application/json"},
+		FileName:                jsonFeedFileName,
+		render:                  renderJSONFeed,
+	}
+)
+
+// Formats are all supported formats. The first one is the default.
+var Formats = []Format{FormatRSS, FormatAtom, FormatJSONFeed}
+
+// FormatByName returns the Format with the given name.
+func FormatByName(name string) (Format, bool) {
+	for _, format := range Formats {
+		if format.Name == name {
+			return format, true
+		}
+	}
+	return Format{}, false
+}
+
+// NegotiateFormat selects the Format for the given value of an Accept header. If the header is empty, FormatRSS is
+// used. If none of the Formats is acceptable, false is returned.
+func NegotiateFormat(accept string) (Format, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return FormatRSS, true
+	}
+	best := Format{}
+	bestQuality := 0.0
+	for _, format := range Formats {
+		quality := acceptQuality(accept, append([]string{format.ContentType}, format.alternativeContentTypes...))
+		if quality > bestQuality {
+			best = format
+			bestQuality = quality
+		}
+	}
+	return best, bestQuality > 0
+}
+
+// acceptQuality returns the highest quality value of the media ranges in the given Accept header that match one of the
+// given content types.
+func acceptQuality(accept string, contentTypes []string) float64 {
+	quality := 0.0
+	for _, mediaRange := range strings.Split(accept, ",") {
+		params := strings.Split(mediaRange, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		q := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if parsed, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		for _, contentType := range contentTypes {
+			if mediaTypeMatches(mediaType, contentType) && q > quality {
+				quality = q
+			}
+		}
+	}
+	return quality
+}
+
+// mediaTypeMatches checks if the given media type from an Accept header, which may contain wildcards, matches the given
+// content type.
+func mediaTypeMatches(mediaType, contentType string) bool {
+	if mediaType == "*/*" || mediaType == contentType {
+		return true
+	}
+	if strings.HasSuffix(mediaType, "/*") {
+		return strings.HasPrefix(contentType, strings.TrimSuffix(mediaType, "*"))
+	}
+	return false
+}
+
+// WriteFeeds renders the given podcast_xml.CreationDetails to all Formats and writes them to the folder of the podcast.
+// The RSS feed is written to the file with the given feed file name. Unchanged feeds are not rewritten. The urls of the
+// changed feeds are returned, so that WebSub hubs can be notified.
+func WriteFeeds(details podcast_xml.CreationDetails, podcastDir, feedFileName string) ([]string, error) {
+	storage := &transfer.LocalStorage{Dir: podcastDir}
+	changed := make([]string, 0)
+	for _, format := range Formats {
+		raw, err := format.Render(details)
+		if err != nil {
+			return changed, errors.Wrap(err, fmt.Sprintf("render %s feed", format.Name))
+		}
+		fileName := format.FileName
+		if fileName == "" {
+			fileName = feedFileName
+		}
+		key := fmt.Sprintf("%s/%s", transfer.GetPodcastFolderName(details.Podcast.Id), fileName)
+		written, err := writeIfChanged(storage, key, raw)
+		if err != nil {
+			return changed, errors.Wrap(err, fmt.Sprintf("write %s feed", format.Name))
+		}
+		if written {
+			if format.FileName == "" {
+				changed = append(changed, details.SelfURL())
+			} else {
+				changed = append(changed, feedURL(details, format.FileName))
+			}
+		}
+	}
+	return changed, nil
+}
+
+// writeIfChanged writes the given content to the given key if it differs from the existing content by its hash. The
+// content is written atomically by the transfer.LocalStorage, so that clients never fetch partially written feeds.
+// It returns whether the content was written.
+func writeIfChanged(storage *transfer.LocalStorage, key string, content []byte) (bool, error) {
+	existing, err := storage.Get(key)
+	if err == nil {
+		hash := sha256.New()
+		_, err = io.Copy(hash, existing)
+		_ = existing.Close()
+		contentHash := sha256.Sum256(content)
+		if err == nil && bytes.Equal(hash.Sum(nil), contentHash[:]) {
+			return false, nil
+		}
+	} else if err != transfer.ErrObjectNotFound {
+		return false, errors.Wrap(err, "read existing content")
+	}
+	if err = storage.Put(key, bytes.NewReader(content)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// renderRSS renders the podcast xml.
+func renderRSS(details podcast_xml.CreationDetails) ([]byte, error) {
+	podcastXML, err := podcast_xml.GeneratePodcastXML(details)
+	if err != nil {
+		return nil, errors.Wrap(err, "generate podcast xml")
+	}
+	raw, err := xml.MarshalIndent(podcastXML, "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal podcast xml")
+	}
+	return raw, nil
+}
+
+// newestFirst returns the public episodes of the given podcast_xml.CreationDetails ordered by date with the newest one
+// first as common for Atom and JSON Feed.
+func newestFirst(details podcast_xml.CreationDetails) ([]podcast_xml.FeedEpisode, error) {
+	episodes, err := details.FeedEpisodes()
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(episodes, func(i, j int) bool {
+		return episodes[i].Episode.Date.After(episodes[j].Episode.Date)
+	})
+	return episodes, nil
+}
+
+// lastUpdated returns the date of the newest of the given episodes or the unix epoch if there are none. Episodes must
+// be ordered with the newest one first.
+func lastUpdated(episodes []podcast_xml.FeedEpisode) time.Time {
+	if len(episodes) == 0 {
+		return time.Unix(0, 0).UTC()
+	}
+	return episodes[0].Episode.Date
+}
+
+// staticURL returns the url of the given location relative to the static content url.
+func staticURL(details podcast_xml.CreationDetails, location string) string {
+	return fmt.Sprintf("%s/%s", details.StaticContentURL, location)
+}
+
+// feedURL returns the url of the feed with the given file name in the podcast folder or the FeedURL of the
+// podcast_xml.CreationDetails if set.
+func feedURL(details podcast_xml.CreationDetails, fileName string) string {
+	if details.FeedURL != "" {
+		return details.FeedURL
+	}
+	return staticURL(details, fmt.Sprintf("%s/%s", transfer.GetPodcastFolderName(details.Podcast.Id), fileName))
+}
+
+// episodeIRI returns the guid of the given episode as IRI. Legacy guids are urls already, other ones are uuids.
+func episodeIRI(episode podcasts.Episode) string {
+	guid := episode.FeedGUID()
+	if strings.Contains(guid, "://") {
+		return guid
+	}
+	return "urn:uuid:" + guid
+}
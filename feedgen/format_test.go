@@ -0,0 +1,153 @@
+package feedgen
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"github.com/life-unlimited/podcastination-server/podcast_xml"
+	"github.com/life-unlimited/podcastination-server/podcasts"
+	"github.com/life-unlimited/podcastination-server/transfer"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		accept string
+		format string
+		ok     bool
+	}{
+		{accept: "", format: "rss", ok: true},
+		{accept: "*/*", format: "rss", ok: true},
+		{accept: "application/atom+xml", format: "atom", ok: true},
+		{accept: "application/feed+json", format: "json", ok: true},
+		{accept: "application/json", format: "json", ok: true},
+		{accept: "text/html, application/xml;q=0.9, application/feed+json;q=0.5", format: "rss", ok: true},
+		{accept: "application/rss+xml;q=0.2, application/atom+xml;q=0.8", format: "atom", ok: true},
+		{accept: "text/html", ok: false},
+	}
+	for _, test := range tests {
+		format, ok := NegotiateFormat(test.accept)
+		assert.Equal(t, test.ok, ok, "ok should match for %q", test.accept)
+		assert.Equal(t, test.format, format.Name, "format should match for %q", test.accept)
+	}
+}
+
+// testCreationDetails returns podcast_xml.CreationDetails with two public episodes and an embargoed one.
+func testCreationDetails() podcast_xml.CreationDetails {
+	publishAt := time.Now().Add(time.Hour)
+	return podcast_xml.CreationDetails{
+		StaticContentURL: "https://example.com/static",
+		Owner:            podcasts.Owner{Id: 1, Name: "Owner", Email: "owner@example.com"},
+		Podcast: podcasts.Podcast{
+			Id:       2,
+			Title:    "Podcast",
+			OwnerId:  1,
+			Language: "de",
+			FeedLink: "https://example.com/static/2/podcast.xml",
+		},
+		Seasons: []podcasts.Season{{Id: 3, PodcastId: 2, Num: 1, Title: "Season"}},
+		Episodes: []podcasts.Episode{
+			{
+				Id: 4, Title: "First", SeasonId: 3, Num: 1, IsAvailable: true, MP3Location: "2/3/1.mp3",
+				Date: time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC), GUID: "1d4e8a90-3b0c-4f5e-9a57-6f0f3c2b1a77",
+				LegacyGUID: "https://example.com/static/2/3/1.mp3",
+			},
+			{
+				Id: 5, Title: "Second", Subtitle: "Part 2", SeasonId: 3, Num: 2, IsAvailable: true,
+				MP3Location: "2/3/2.mp3", Date: time.Date(2021, 1, 8, 10, 0, 0, 0, time.UTC),
+				GUID: "7c0b2f3e-6a4d-4b8e-9f1a-2d3c4b5a6e7f", FileSize: 1024, MP3Length: 60,
+			},
+			{
+				Id: 6, Title: "Third", SeasonId: 3, Num: 3, IsAvailable: true, MP3Location: "2/3/3.mp3",
+				Date: time.Date(2021, 1, 15, 10, 0, 0, 0, time.UTC), PublishAt: &publishAt,
+				GUID: "0f1e2d3c-4b5a-4697-8877-665544332211",
+			},
+		},
+	}
+}
+
+func TestRenderAtom(t *testing.T) {
+	details := testCreationDetails()
+	details.Hubs = []string{"https://example.com/websub"}
+	raw, err := FormatAtom.Render(details)
+	if !assert.Nil(t, err, "rendering should not fail") {
+		return
+	}
+	var feed atomFeed
+	if !assert.Nil(t, xml.Unmarshal(raw, &feed), "unmarshalling should not fail") {
+		return
+	}
+	assert.Equal(t, "Podcast", feed.Title, "title should match")
+	assert.Equal(t, "2021-01-08T10:00:00Z", feed.Updated, "updated should be the date of the newest episode")
+	assert.Equal(t, "https://example.com/static/2/atom.xml", feed.Links[0].Href, "self link should match")
+	assert.Contains(t, feed.Links, atomLink{Href: "https://example.com/websub", Rel: "hub"}, "hub should be announced")
+	if assert.Len(t, feed.Entries, 2, "only public episodes should be included") {
+		assert.Equal(t, "urn:uuid:7c0b2f3e-6a4d-4b8e-9f1a-2d3c4b5a6e7f", feed.Entries[0].ID, "id should match")
+		assert.Equal(t, "Second - Part 2", feed.Entries[0].Title, "title should include subtitle")
+		assert.Equal(t, "https://example.com/static/2/3/1.mp3", feed.Entries[1].ID, "legacy guid should be used")
+	}
+}
+
+func TestRenderJSONFeed(t *testing.T) {
+	raw, err := FormatJSONFeed.Render(testCreationDetails())
+	if !assert.Nil(t, err, "rendering should not fail") {
+		return
+	}
+	var feed jsonFeed
+	if !assert.Nil(t, json.Unmarshal(raw, &feed), "unmarshalling should not fail") {
+		return
+	}
+	assert.Equal(t, jsonFeedVersion, feed.Version, "version should match")
+	assert.Equal(t, "https://example.com/static/2/feed.json", feed.FeedURL, "feed url should match")
+	if assert.Len(t, feed.Items, 2, "only public episodes should be included") {
+		item := feed.Items[0]
+		assert.Equal(t, "7c0b2f3e-6a4d-4b8e-9f1a-2d3c4b5a6e7f", item.ID, "id should match")
+		assert.Equal(t, []jsonFeedAttachment{{
+			URL:               "https://example.com/static/2/3/2.mp3",
+			MIMEType:          "audio/mpeg",
+			SizeInBytes:       1024,
+			DurationInSeconds: 60,
+		}}, item.Attachments, "attachments should match")
+	}
+}
+
+func TestWriteFeeds(t *testing.T) {
+	dir, err := ioutil.TempDir("", "feeds")
+	if !assert.Nil(t, err, "creating temp dir should not fail") {
+		return
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	details := testCreationDetails()
+	changed, err := WriteFeeds(details, dir, "podcast.xml")
+	if !assert.Nil(t, err, "writing feeds should not fail") {
+		return
+	}
+	assert.Equal(t, []string{
+		"https://example.com/static/2/podcast.xml",
+		"https://example.com/static/2/atom.xml",
+		"https://example.com/static/2/feed.json",
+	}, changed, "all feeds should be reported as changed")
+	for _, fileName := range []string{"podcast.xml", atomFileName, jsonFeedFileName} {
+		_, err = os.Stat(filepath.Join(dir, "2", fileName))
+		assert.Nil(t, err, "%s should be written", fileName)
+	}
+	storage := &transfer.LocalStorage{Dir: dir}
+	raw, err := FormatRSS.Render(details)
+	if !assert.Nil(t, err, "rendering should not fail") {
+		return
+	}
+	written, err := writeIfChanged(storage, "2/podcast.xml", raw)
+	assert.Nil(t, err, "writing should not fail")
+	assert.False(t, written, "unchanged feed should not be rewritten")
+	written, err = writeIfChanged(storage, "2/podcast.xml", append(raw, '\n'))
+	assert.Nil(t, err, "writing should not fail")
+	assert.True(t, written, "changed feed should be rewritten")
+	files, err := ioutil.ReadDir(filepath.Join(dir, "2"))
+	if assert.Nil(t, err, "reading dir should not fail") {
+		assert.Len(t, files, 3, "no temporary files should be left")
+	}
+}
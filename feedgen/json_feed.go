@@ -0,0 +1,108 @@
+package feedgen
+
+import (
+	"encoding/json"
+	"github.com/life-unlimited/podcastination-server/podcast_xml"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// jsonFeedVersion is the version url of JSON Feed 1.1.
+const jsonFeedVersion = "https://jsonfeed.org/version/1.1"
+
+type jsonFeed struct {
+	Version     string           `json:"version"`
+	Title       string           `json:"title"`
+	HomePageURL string           `json:"home_page_url,omitempty"`
+	FeedURL     string           `json:"feed_url"`
+	Description string           `json:"description,omitempty"`
+	Icon        string           `json:"icon,omitempty"`
+	Authors     []jsonFeedAuthor `json:"authors,omitempty"`
+	Language    string           `json:"language,omitempty"`
+	Hubs        []jsonFeedHub    `json:"hubs,omitempty"`
+	Items       []jsonFeedItem   `json:"items"`
+}
+
+type jsonFeedHub struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+type jsonFeedItem struct {
+	ID            string               `json:"id"`
+	Title         string               `json:"title"`
+	ContentText   string               `json:"content_text"`
+	Summary       string               `json:"summary,omitempty"`
+	Image         string               `json:"image,omitempty"`
+	DatePublished string               `json:"date_published"`
+	Authors       []jsonFeedAuthor     `json:"authors,omitempty"`
+	Attachments   []jsonFeedAttachment `json:"attachments"`
+}
+
+type jsonFeedAttachment struct {
+	URL               string `json:"url"`
+	MIMEType          string `json:"mime_type"`
+	SizeInBytes       int64  `json:"size_in_bytes,omitempty"`
+	DurationInSeconds int    `json:"duration_in_seconds,omitempty"`
+}
+
+// renderJSONFeed renders the feed as JSON Feed 1.1.
+func renderJSONFeed(details podcast_xml.CreationDetails) ([]byte, error) {
+	episodes, err := newestFirst(details)
+	if err != nil {
+		return nil, err
+	}
+	podcast := details.Podcast
+	feed := jsonFeed{
+		Version:     jsonFeedVersion,
+		Title:       podcast.Title,
+		HomePageURL: podcast.Link,
+		FeedURL:     feedURL(details, jsonFeedFileName),
+		Description: podcast.Description,
+		Language:    string(podcast.Language),
+		Items:       make([]jsonFeedItem, 0, len(episodes)),
+	}
+	if podcast.ImageLocation != "" {
+		feed.Icon = staticURL(details, podcast.ImageLocation)
+	}
+	for _, hub := range details.Hubs {
+		feed.Hubs = append(feed.Hubs, jsonFeedHub{Type: "WebSub", URL: hub})
+	}
+	if details.Owner.Name != "" {
+		feed.Authors = []jsonFeedAuthor{{Name: details.Owner.Name}}
+	}
+	for _, e := range episodes {
+		episode := e.Episode
+		item := jsonFeedItem{
+			ID:            episode.FeedGUID(),
+			Title:         podcast_xml.EpisodeTitle(episode),
+			ContentText:   episode.Description,
+			Summary:       episode.Subtitle,
+			DatePublished: episode.Date.Format(time.RFC3339),
+			Attachments: []jsonFeedAttachment{
+				{
+					URL:               staticURL(details, episode.MP3Location),
+					MIMEType:          podcast_xml.EnclosureType(episode),
+					SizeInBytes:       episode.FileSize,
+					DurationInSeconds: episode.MP3Length,
+				},
+			},
+		}
+		if episode.ImageLocation != "" {
+			item.Image = staticURL(details, episode.ImageLocation)
+		}
+		if episode.Author != "" {
+			item.Authors = []jsonFeedAuthor{{Name: episode.Author}}
+		}
+		feed.Items = append(feed.Items, item)
+	}
+	raw, err := json.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal json feed")
+	}
+	return raw, nil
+}
@@ -0,0 +1,116 @@
+package id3
+
+import (
+	"encoding/binary"
+	"sort"
+	"time"
+)
+
+// ctocFlagTopLevel marks the table of contents that is the root of all others.
+const ctocFlagTopLevel = 0x02
+
+// Chapter is a chapter from a CHAP frame.
+type Chapter struct {
+	ElementID string
+	Start     time.Duration
+	End       time.Duration
+	// Title is taken from the TIT2 sub-frame and empty if there is none.
+	Title string
+}
+
+// tableOfContents is a table of contents from a CTOC frame.
+type tableOfContents struct {
+	elementID string
+	topLevel  bool
+	children  []string
+}
+
+// Chapters returns the chapters of the Tag ordered by start time. If there is a top-level table of contents, only the
+// chapters referenced by it are returned. Invalid frames are ignored.
+func (tag *Tag) Chapters() []Chapter {
+	chapters := make(map[string]Chapter)
+	tocs := make(map[string]tableOfContents)
+	var topLevel *tableOfContents
+	for _, frame := range tag.Frames {
+		switch frame.ID {
+		case "CHAP":
+			if chapter, ok := parseChapter(frame.Data, tag.Version); ok {
+				chapters[chapter.ElementID] = chapter
+			}
+		case "CTOC":
+			if toc, ok := parseTableOfContents(frame.Data); ok {
+				tocs[toc.elementID] = toc
+				if toc.topLevel && topLevel == nil {
+					topLevel = &toc
+				}
+			}
+		}
+	}
+	result := make([]Chapter, 0, len(chapters))
+	if topLevel != nil {
+		// Collect the referenced chapters including the ones in nested tables of contents.
+		visited := make(map[string]struct{})
+		var collect func(toc tableOfContents)
+		collect = func(toc tableOfContents) {
+			visited[toc.elementID] = struct{}{}
+			for _, child := range toc.children {
+				if chapter, ok := chapters[child]; ok {
+					result = append(result, chapter)
+					delete(chapters, child)
+				} else if nested, ok := tocs[child]; ok {
+					if _, ok := visited[child]; !ok {
+						collect(nested)
+					}
+				}
+			}
+		}
+		collect(*topLevel)
+	} else {
+		for _, chapter := range chapters {
+			result = append(result, chapter)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Start < result[j].Start
+	})
+	return result
+}
+
+// parseChapter parses the content of a CHAP frame.
+func parseChapter(data []byte, version byte) (Chapter, bool) {
+	elementID, rest := splitTerminated(encodingISO88591, data)
+	if len(rest) < 16 {
+		return Chapter{}, false
+	}
+	chapter := Chapter{
+		ElementID: elementID,
+		Start:     time.Duration(binary.BigEndian.Uint32(rest[0:4])) * time.Millisecond,
+		End:       time.Duration(binary.BigEndian.Uint32(rest[4:8])) * time.Millisecond,
+	}
+	subFrames, err := parseFrames(rest[16:], version, false)
+	if err == nil {
+		subTag := Tag{Version: version, Frames: subFrames}
+		chapter.Title = subTag.Text("TIT2")
+	}
+	return chapter, true
+}
+
+// parseTableOfContents parses the content of a CTOC frame.
+func parseTableOfContents(data []byte) (tableOfContents, bool) {
+	elementID, rest := splitTerminated(encodingISO88591, data)
+	if len(rest) < 2 {
+		return tableOfContents{}, false
+	}
+	toc := tableOfContents{
+		elementID: elementID,
+		topLevel:  rest[0]&ctocFlagTopLevel != 0,
+	}
+	entries := int(rest[1])
+	rest = rest[2:]
+	for i := 0; i < entries && len(rest) > 0; i++ {
+		var child string
+		child, rest = splitTerminated(encodingISO88591, rest)
+		toc.children = append(toc.children, child)
+	}
+	return toc, true
+}
@@ -0,0 +1,264 @@
+// Package id3 is used for reading ID3v2 tags of mp3 files. Versions 2.3 and 2.4 are supported.
+package id3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf16"
+)
+
+// ErrNoTag is returned if there is no ID3v2 tag.
+var ErrNoTag = errors.New("no id3v2 tag found")
+
+// headerSize is the size of the tag header as well as of frame headers.
+const headerSize = 10
+
+// Tag header flags.
+const (
+	tagFlagUnsynchronisation = 0x80
+	tagFlagExtendedHeader    = 0x40
+)
+
+// Frame format flags by version.
+const (
+	frameFlagV3Compression         = 0x0080
+	frameFlagV3Encryption          = 0x0040
+	frameFlagV3Grouping            = 0x0020
+	frameFlagV4Grouping            = 0x0040
+	frameFlagV4Compression         = 0x0008
+	frameFlagV4Encryption          = 0x0004
+	frameFlagV4Unsynchronisation   = 0x0002
+	frameFlagV4DataLengthIndicator = 0x0001
+)
+
+// Text encodings as used in text frames.
+const (
+	encodingISO88591 = 0
+	encodingUTF16    = 1
+	encodingUTF16BE  = 2
+	encodingUTF8     = 3
+)
+
+// Tag is an ID3v2 tag.
+type Tag struct {
+	// Version is the major version (3 or 4).
+	Version byte
+	Frames  []Frame
+}
+
+// Frame is a frame of a Tag. Compressed and encrypted frames are skipped while reading.
+type Frame struct {
+	ID   string
+	Data []byte
+}
+
+// ReadFile reads the ID3v2 tag at the start of the given file. If there is none, ErrNoTag is returned.
+func ReadFile(file string) (*Tag, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not open file: %v", err)
+	}
+	tag, err := Read(f)
+	if closeErr := f.Close(); err == nil && closeErr != nil {
+		return nil, fmt.Errorf("could not close file: %v", closeErr)
+	}
+	return tag, err
+}
+
+// Read reads the ID3v2 tag at the start of the given reader. If there is none, ErrNoTag is returned.
+func Read(r io.Reader) (*Tag, error) {
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, header); err != nil || !bytes.HasPrefix(header, []byte("ID3")) {
+		return nil, ErrNoTag
+	}
+	version := header[3]
+	if version != 3 && version != 4 {
+		return nil, fmt.Errorf("unsupported id3 version 2.%d", version)
+	}
+	flags := header[5]
+	data := make([]byte, syncSafe(header[6:10]))
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, fmt.Errorf("could not read tag: %v", err)
+	}
+	// In version 2.3, the whole tag is unsynchronised while in 2.4 it is done for each frame.
+	if version == 3 && flags&tagFlagUnsynchronisation != 0 {
+		data = removeUnsynchronisation(data)
+	}
+	if flags&tagFlagExtendedHeader != 0 {
+		if len(data) < 4 {
+			return nil, fmt.Errorf("invalid extended header")
+		}
+		// The size excludes itself in version 2.3.
+		size := int(syncSafe(data[:4]))
+		if version == 3 {
+			size = int(binary.BigEndian.Uint32(data[:4])) + 4
+		}
+		if size > len(data) {
+			return nil, fmt.Errorf("invalid extended header size %d", size)
+		}
+		data = data[size:]
+	}
+	frames, err := parseFrames(data, version, version == 4 && flags&tagFlagUnsynchronisation != 0)
+	if err != nil {
+		return nil, err
+	}
+	return &Tag{
+		Version: version,
+		Frames:  frames,
+	}, nil
+}
+
+// parseFrames parses all frames in the given data until the padding or end is reached.
+func parseFrames(data []byte, version byte, unsynchronised bool) ([]Frame, error) {
+	frames := make([]Frame, 0)
+	for len(data) >= headerSize && data[0] != 0 {
+		id := string(data[:4])
+		size := int(binary.BigEndian.Uint32(data[4:8]))
+		if version == 4 {
+			size = int(syncSafe(data[4:8]))
+		}
+		flags := binary.BigEndian.Uint16(data[8:10])
+		if size > len(data)-headerSize {
+			return nil, fmt.Errorf("frame %s exceeds tag size", id)
+		}
+		content := data[headerSize : headerSize+size]
+		data = data[headerSize+size:]
+		content, ok := frameContent(content, version, flags, unsynchronised)
+		if !ok {
+			continue
+		}
+		frames = append(frames, Frame{
+			ID:   id,
+			Data: content,
+		})
+	}
+	return frames, nil
+}
+
+// frameContent returns the content of a frame without additional data indicated by the given flags. If the frame is
+// compressed or encrypted, false is returned.
+func frameContent(content []byte, version byte, flags uint16, unsynchronised bool) ([]byte, bool) {
+	if version == 3 {
+		if flags&(frameFlagV3Compression|frameFlagV3Encryption) != 0 {
+			return nil, false
+		}
+		if flags&frameFlagV3Grouping != 0 && len(content) > 0 {
+			content = content[1:]
+		}
+		return content, true
+	}
+	if flags&(frameFlagV4Compression|frameFlagV4Encryption) != 0 {
+		return nil, false
+	}
+	if flags&frameFlagV4Grouping != 0 && len(content) > 0 {
+		content = content[1:]
+	}
+	if flags&frameFlagV4DataLengthIndicator != 0 && len(content) >= 4 {
+		content = content[4:]
+	}
+	if unsynchronised || flags&frameFlagV4Unsynchronisation != 0 {
+		content = removeUnsynchronisation(content)
+	}
+	return content, true
+}
+
+// Frame returns the first frame with the given id. If there is none, false is returned.
+func (tag *Tag) Frame(id string) (Frame, bool) {
+	for _, frame := range tag.Frames {
+		if frame.ID == id {
+			return frame, true
+		}
+	}
+	return Frame{}, false
+}
+
+// Text returns the value of the text frame with the given id (for example TIT2). If there is none, an empty string is
+// returned.
+func (tag *Tag) Text(id string) string {
+	frame, ok := tag.Frame(id)
+	if !ok {
+		return ""
+	}
+	return frame.Text()
+}
+
+// Text decodes the Frame as text frame. Multiple values are joined with a slash.
+func (frame Frame) Text() string {
+	if len(frame.Data) == 0 {
+		return ""
+	}
+	text := decodeText(frame.Data[0], frame.Data[1:])
+	return strings.ReplaceAll(strings.TrimRight(text, "\x00"), "\x00", "/")
+}
+
+// decodeText decodes the given text with the given encoding.
+func decodeText(encoding byte, b []byte) string {
+	switch encoding {
+	case encodingUTF16, encodingUTF16BE:
+		var order binary.ByteOrder = binary.BigEndian
+		if len(b) >= 2 && encoding == encodingUTF16 {
+			if b[0] == 0xFF && b[1] == 0xFE {
+				order = binary.LittleEndian
+			}
+			if (b[0] == 0xFF && b[1] == 0xFE) || (b[0] == 0xFE && b[1] == 0xFF) {
+				b = b[2:]
+			}
+		}
+		units := make([]uint16, 0, len(b)/2)
+		for i := 0; i+1 < len(b); i += 2 {
+			units = append(units, order.Uint16(b[i:i+2]))
+		}
+		return string(utf16.Decode(units))
+	case encodingUTF8:
+		return string(b)
+	default:
+		runes := make([]rune, len(b))
+		for i, c := range b {
+			runes[i] = rune(c)
+		}
+		return string(runes)
+	}
+}
+
+// splitTerminated splits the given bytes at the first terminator of the given encoding and returns the decoded text as
+// well as the remaining bytes. If there is no terminator, everything is considered text.
+func splitTerminated(encoding byte, b []byte) (string, []byte) {
+	if encoding == encodingUTF16 || encoding == encodingUTF16BE {
+		for i := 0; i+1 < len(b); i += 2 {
+			if b[i] == 0 && b[i+1] == 0 {
+				return decodeText(encoding, b[:i]), b[i+2:]
+			}
+		}
+		return decodeText(encoding, b), nil
+	}
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		return decodeText(encoding, b[:i]), b[i+1:]
+	}
+	return decodeText(encoding, b), nil
+}
+
+// removeUnsynchronisation reverts the unsynchronisation scheme by removing the zero bytes inserted after 0xFF.
+func removeUnsynchronisation(b []byte) []byte {
+	result := make([]byte, 0, len(b))
+	for i := 0; i < len(b); i++ {
+		result = append(result, b[i])
+		if b[i] == 0xFF && i+1 < len(b) && b[i+1] == 0x00 {
+			i++
+		}
+	}
+	return result
+}
+
+// syncSafe decodes a synchsafe integer where the most significant bit of each byte is zero.
+func syncSafe(b []byte) uint32 {
+	var v uint32
+	for _, c := range b {
+		v = v<<7 | uint32(c&0x7F)
+	}
+	return v
+}
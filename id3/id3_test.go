@@ -0,0 +1,163 @@
+package id3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type ID3TestSuite struct {
+	suite.Suite
+}
+
+// frameBytes creates a frame with the given id and content. For version 4, the size is synchsafe.
+func frameBytes(version byte, id string, content []byte) []byte {
+	frame := make([]byte, headerSize)
+	copy(frame, id)
+	binary.BigEndian.PutUint32(frame[4:8], uint32(len(content)))
+	if version == 4 {
+		copy(frame[4:8], syncSafeBytes(uint32(len(content))))
+	}
+	return append(frame, content...)
+}
+
+// tagBytes creates a tag with the given version, flags and frames followed by some padding.
+func tagBytes(version byte, flags byte, frames ...[]byte) []byte {
+	content := append(bytes.Join(frames, nil), make([]byte, 16)...)
+	header := []byte{'I', 'D', '3', version, 0, flags}
+	return append(append(header, syncSafeBytes(uint32(len(content)))...), content...)
+}
+
+// chapBytes creates the content of a CHAP frame.
+func chapBytes(version byte, elementID string, start, end uint32, title string) []byte {
+	content := append([]byte(elementID), 0)
+	times := make([]byte, 16)
+	binary.BigEndian.PutUint32(times[0:4], start)
+	binary.BigEndian.PutUint32(times[4:8], end)
+	binary.BigEndian.PutUint32(times[8:12], 0xFFFFFFFF)
+	binary.BigEndian.PutUint32(times[12:16], 0xFFFFFFFF)
+	content = append(content, times...)
+	if title != "" {
+		content = append(content, frameBytes(version, "TIT2", append([]byte{encodingUTF8}, title...))...)
+	}
+	return content
+}
+
+// ctocBytes creates the content of a top-level CTOC frame.
+func ctocBytes(elementID string, children ...string) []byte {
+	content := append([]byte(elementID), 0, ctocFlagTopLevel|0x01, byte(len(children)))
+	for _, child := range children {
+		content = append(append(content, child...), 0)
+	}
+	return content
+}
+
+func (suite *ID3TestSuite) TestNoTag() {
+	_, err := Read(bytes.NewReader([]byte{0xFF, 0xFB, 0x90, 0x00}))
+	suite.Assert().Equal(ErrNoTag, err, "should return no tag error")
+}
+
+func (suite *ID3TestSuite) TestUnsupportedVersion() {
+	_, err := Read(bytes.NewReader([]byte{'I', 'D', '3', 2, 0, 0, 0, 0, 0, 0}))
+	suite.Assert().NotNil(err, "reading should fail for version 2.2")
+	suite.Assert().NotEqual(ErrNoTag, err, "should not return no tag error")
+}
+
+func (suite *ID3TestSuite) TestTextFrames() {
+	utf16Title := []byte{encodingUTF16, 0xFF, 0xFE, 'H', 0, 'i', 0}
+	tag, err := Read(bytes.NewReader(tagBytes(3, 0,
+		frameBytes(3, "TIT2", utf16Title),
+		frameBytes(3, "TPE1", []byte("\x00J\xf6rg\x00Jane")))))
+	suite.Require().Nilf(err, "reading should not fail but got %v", err)
+	suite.Assert().EqualValues(3, tag.Version, "version should match")
+	suite.Assert().Equal("Hi", tag.Text("TIT2"), "utf-16 title should match")
+	suite.Assert().Equal("Jörg/Jane", tag.Text("TPE1"), "latin-1 artists should match")
+	suite.Assert().Equal("", tag.Text("TALB"), "missing frame should be empty")
+}
+
+func (suite *ID3TestSuite) TestUnsynchronisedV4Frame() {
+	frame := frameBytes(4, "TIT2", []byte{encodingISO88591, 'a', 0xFF, 0x00, 'b'})
+	// Set unsynchronisation flag.
+	frame[9] = frameFlagV4Unsynchronisation
+	tag, err := Read(bytes.NewReader(tagBytes(4, 0, frame)))
+	suite.Require().Nilf(err, "reading should not fail but got %v", err)
+	suite.Assert().Equal("aÿb", tag.Text("TIT2"), "title should be resynchronised")
+}
+
+func (suite *ID3TestSuite) TestChaptersWithTableOfContents() {
+	tag, err := Read(bytes.NewReader(tagBytes(4, 0,
+		frameBytes(4, "CHAP", chapBytes(4, "ch1", 60000, 120000, "Sermon")),
+		frameBytes(4, "CHAP", chapBytes(4, "ch0", 0, 60000, "Worship")),
+		frameBytes(4, "CHAP", chapBytes(4, "unused", 10000, 20000, "Unused")),
+		frameBytes(4, "CTOC", ctocBytes("toc", "ch0", "ch1")))))
+	suite.Require().Nilf(err, "reading should not fail but got %v", err)
+	chapters := tag.Chapters()
+	suite.Require().Len(chapters, 2, "should only return chapters of table of contents")
+	suite.Assert().Equal(Chapter{ElementID: "ch0", Start: 0, End: time.Minute, Title: "Worship"}, chapters[0],
+		"first chapter should match")
+	suite.Assert().Equal(Chapter{ElementID: "ch1", Start: time.Minute, End: 2 * time.Minute, Title: "Sermon"},
+		chapters[1], "second chapter should match")
+}
+
+func (suite *ID3TestSuite) TestChaptersWithoutTableOfContents() {
+	tag, err := Read(bytes.NewReader(tagBytes(3, 0,
+		frameBytes(3, "CHAP", chapBytes(3, "b", 30500, 40000, "")),
+		frameBytes(3, "CHAP", chapBytes(3, "a", 0, 30500, "Intro")),
+		frameBytes(3, "CHAP", []byte("broken\x00")))))
+	suite.Require().Nilf(err, "reading should not fail but got %v", err)
+	chapters := tag.Chapters()
+	suite.Require().Len(chapters, 2, "should return all valid chapters")
+	suite.Assert().Equal("Intro", chapters[0].Title, "chapters should be ordered by start")
+	suite.Assert().Equal(30500*time.Millisecond, chapters[1].Start, "start should match")
+	suite.Assert().Equal("", chapters[1].Title, "title should be empty")
+}
+
+func (suite *ID3TestSuite) TestWriteFile() {
+	dir, err := ioutil.TempDir("", "id3")
+	suite.Require().Nil(err, "creating temp dir should not fail")
+	defer func() { _ = os.RemoveAll(dir) }()
+	audio := bytes.Repeat([]byte{0xFF, 0xFB, 0x90, 0x00}, 64)
+	id3v1 := append([]byte("TAG"), make([]byte, id3v1Size-3)...)
+	old := tagBytes(3, 0, frameBytes(3, "TIT2", []byte("\x00Track 01")))
+	file := filepath.Join(dir, "a.mp3")
+	suite.Require().Nil(ioutil.WriteFile(file, append(append(old, audio...), id3v1...), 0644),
+		"writing file should not fail")
+	tag := NewTag()
+	tag.SetText("TIT2", "Gnade – Teil 1")
+	tag.SetText("TALB", "Römer")
+	tag.SetText("TRCK", "")
+	tag.SetPicture(Picture{MIMEType: "image/png", Type: PictureTypeFrontCover, Data: []byte("png")})
+	tag.SetChapters([]Chapter{
+		{Start: 0, End: time.Minute, Title: "Worship"},
+		{Start: time.Minute, End: 2 * time.Minute, Title: "Sermon"},
+	})
+	suite.Require().Nil(WriteFile(file, tag), "writing tag should not fail")
+	content, err := ioutil.ReadFile(file)
+	suite.Require().Nil(err, "reading file should not fail")
+	suite.Assert().True(bytes.HasSuffix(content, audio), "audio should be kept and id3v1 tag removed")
+	read, err := Read(bytes.NewReader(content))
+	suite.Require().Nilf(err, "reading tag should not fail but got %v", err)
+	suite.Assert().Equal("Gnade – Teil 1", read.Title(), "utf-16 title should match")
+	suite.Assert().Equal("Römer", read.Text("TALB"), "latin-1 album should match")
+	_, ok := read.Frame("TRCK")
+	suite.Assert().False(ok, "empty track should not be written")
+	picture, ok := read.Picture()
+	suite.Require().True(ok, "picture should exist")
+	suite.Assert().Equal([]byte("png"), picture.Data, "picture data should match")
+	chapters := read.Chapters()
+	suite.Require().Len(chapters, 2, "chapters should be written")
+	suite.Assert().Equal("Sermon", chapters[1].Title, "chapter title should match")
+	suite.Assert().Equal(time.Minute, chapters[1].Start, "chapter start should match")
+	files, err := ioutil.ReadDir(dir)
+	suite.Require().Nil(err, "reading dir should not fail")
+	suite.Assert().Len(files, 1, "temporary file should be removed")
+}
+
+func Test_id3(t *testing.T) {
+	suite.Run(t, new(ID3TestSuite))
+}
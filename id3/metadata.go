@@ -0,0 +1,134 @@
+package id3
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PictureTypeFrontCover is the picture type of the front cover in APIC frames.
+const PictureTypeFrontCover = 0x03
+
+// recordingTimeLayouts are the layouts of recording times by their length. Timestamps in ID3v2.4 may have any of
+// these precisions.
+var recordingTimeLayouts = map[int]string{
+	4:  "2006",
+	7:  "2006-01",
+	10: "2006-01-02",
+	13: "2006-01-02T15",
+	16: "2006-01-02T15:04",
+	19: "2006-01-02T15:04:05",
+}
+
+// Picture is an attached picture from an APIC frame.
+type Picture struct {
+	MIMEType    string
+	Type        byte
+	Description string
+	Data        []byte
+}
+
+// Title returns the title from the TIT2 frame.
+func (tag *Tag) Title() string {
+	return strings.TrimSpace(tag.Text("TIT2"))
+}
+
+// Artist returns the lead artist from the TPE1 frame.
+func (tag *Tag) Artist() string {
+	return strings.TrimSpace(tag.Text("TPE1"))
+}
+
+// Comment returns the text of the first COMM frame without content description. Comments with content description
+// are usually used by applications for storing custom data and are therefore ignored.
+func (tag *Tag) Comment() string {
+	for _, frame := range tag.Frames {
+		// Encoding and language are required.
+		if frame.ID != "COMM" || len(frame.Data) < 4 {
+			continue
+		}
+		encoding := frame.Data[0]
+		description, text := splitTerminated(encoding, frame.Data[4:])
+		if description != "" {
+			continue
+		}
+		return strings.TrimSpace(strings.TrimRight(decodeText(encoding, text), "\x00"))
+	}
+	return ""
+}
+
+// RecordingTime returns the recording time from the TDRC frame or in version 2.3 from the TYER, TDAT and TIME frames.
+// The layout describes the precision of the time, for example 2006 if only the year is known. If there is no
+// recording time or it is invalid, false is returned.
+func (tag *Tag) RecordingTime() (time.Time, string, bool) {
+	value := strings.TrimSpace(tag.Text("TDRC"))
+	if tag.Version == 3 {
+		value = strings.TrimSpace(tag.Text("TYER"))
+		// TDAT is in the format DDMM and TIME in HHMM.
+		if date := strings.TrimSpace(tag.Text("TDAT")); len(value) == 4 && len(date) == 4 {
+			value = fmt.Sprintf("%s-%s-%s", value, date[2:4], date[0:2])
+			if t := strings.TrimSpace(tag.Text("TIME")); len(t) == 4 {
+				value = fmt.Sprintf("%sT%s:%s", value, t[0:2], t[2:4])
+			}
+		}
+	}
+	layout, ok := recordingTimeLayouts[len(value)]
+	if !ok {
+		return time.Time{}, "", false
+	}
+	recordingTime, err := time.Parse(layout, value)
+	if err != nil {
+		return time.Time{}, "", false
+	}
+	return recordingTime, layout, true
+}
+
+// Picture returns the front cover from the APIC frames. If there is none, the first picture is returned. If there is
+// no picture at all, false is returned.
+func (tag *Tag) Picture() (Picture, bool) {
+	var found *Picture
+	for _, frame := range tag.Frames {
+		if frame.ID != "APIC" {
+			continue
+		}
+		picture, ok := parsePicture(frame.Data)
+		if !ok {
+			continue
+		}
+		if picture.Type == PictureTypeFrontCover {
+			return picture, true
+		}
+		if found == nil {
+			found = &picture
+		}
+	}
+	if found == nil {
+		return Picture{}, false
+	}
+	return *found, true
+}
+
+// parsePicture parses the content of an APIC frame.
+func parsePicture(data []byte) (Picture, bool) {
+	if len(data) < 2 {
+		return Picture{}, false
+	}
+	encoding := data[0]
+	mimeType, rest := splitTerminated(encodingISO88591, data[1:])
+	if len(rest) < 1 {
+		return Picture{}, false
+	}
+	picture := Picture{
+		MIMEType: strings.ToLower(mimeType),
+		Type:     rest[0],
+	}
+	picture.Description, rest = splitTerminated(encoding, rest[1:])
+	if len(rest) == 0 {
+		return Picture{}, false
+	}
+	picture.Data = rest
+	// Some writers use the file extension instead of the MIME type.
+	if !strings.Contains(picture.MIMEType, "/") {
+		picture.MIMEType = "image/" + picture.MIMEType
+	}
+	return picture, true
+}
@@ -0,0 +1,236 @@
+package id3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+	"unicode/utf16"
+)
+
+// id3v1Size is the size of an ID3v1 tag at the end of a file.
+const id3v1Size = 128
+
+// tocElementID is the element id of the table of contents written by SetChapters.
+const tocElementID = "toc"
+
+// NewTag creates an empty ID3v2.3 Tag which is the version supported by most players.
+func NewTag() *Tag {
+	return &Tag{
+		Version: 3,
+		Frames:  make([]Frame, 0),
+	}
+}
+
+// removeFrames removes all frames with the given ids.
+func (tag *Tag) removeFrames(ids ...string) {
+	frames := make([]Frame, 0, len(tag.Frames))
+	for _, frame := range tag.Frames {
+		remove := false
+		for _, id := range ids {
+			remove = remove || frame.ID == id
+		}
+		if !remove {
+			frames = append(frames, frame)
+		}
+	}
+	tag.Frames = frames
+}
+
+// SetText replaces the text frame with the given id. If the value is empty, the frame is removed.
+func (tag *Tag) SetText(id, value string) {
+	tag.removeFrames(id)
+	if value == "" {
+		return
+	}
+	encoding, encoded := encodeText(value)
+	tag.Frames = append(tag.Frames, Frame{
+		ID:   id,
+		Data: append([]byte{encoding}, encoded...),
+	})
+}
+
+// SetPicture replaces all attached pictures with the given one.
+func (tag *Tag) SetPicture(picture Picture) {
+	tag.removeFrames("APIC")
+	encoding, description := encodeText(picture.Description)
+	data := append([]byte{encoding}, picture.MIMEType...)
+	data = append(data, 0, picture.Type)
+	data = append(data, terminated(encoding, description)...)
+	tag.Frames = append(tag.Frames, Frame{
+		ID:   "APIC",
+		Data: append(data, picture.Data...),
+	})
+}
+
+// SetChapters replaces all chapters and tables of contents with the given chapters and a top-level table of contents
+// referencing them in the given order. Element ids of the chapters are generated if empty.
+func (tag *Tag) SetChapters(chapters []Chapter) {
+	tag.removeFrames("CHAP", "CTOC")
+	if len(chapters) == 0 {
+		return
+	}
+	// The number of entries in a table of contents is limited.
+	if len(chapters) > 255 {
+		chapters = chapters[:255]
+	}
+	toc := append([]byte(tocElementID), 0, ctocFlagTopLevel|0x01, byte(len(chapters)))
+	chapterFrames := make([]Frame, 0, len(chapters))
+	for i, chapter := range chapters {
+		elementID := chapter.ElementID
+		if elementID == "" {
+			elementID = fmt.Sprintf("chp%d", i)
+		}
+		toc = append(append(toc, elementID...), 0)
+		data := append([]byte(elementID), 0)
+		times := make([]byte, 16)
+		binary.BigEndian.PutUint32(times[0:4], uint32(chapter.Start/time.Millisecond))
+		binary.BigEndian.PutUint32(times[4:8], uint32(chapter.End/time.Millisecond))
+		// Byte offsets are not used.
+		binary.BigEndian.PutUint32(times[8:12], 0xFFFFFFFF)
+		binary.BigEndian.PutUint32(times[12:16], 0xFFFFFFFF)
+		data = append(data, times...)
+		if chapter.Title != "" {
+			subTag := Tag{Version: tag.Version}
+			subTag.SetText("TIT2", chapter.Title)
+			data = append(data, subTag.frameBytes()...)
+		}
+		chapterFrames = append(chapterFrames, Frame{ID: "CHAP", Data: data})
+	}
+	tag.Frames = append(append(tag.Frames, Frame{ID: "CTOC", Data: toc}), chapterFrames...)
+}
+
+// Bytes serializes the Tag including its header.
+func (tag *Tag) Bytes() []byte {
+	frames := tag.frameBytes()
+	header := []byte{'I', 'D', '3', tag.Version, 0, 0}
+	header = append(header, syncSafeBytes(uint32(len(frames)))...)
+	return append(header, frames...)
+}
+
+// frameBytes serializes the frames of the Tag.
+func (tag *Tag) frameBytes() []byte {
+	var buf bytes.Buffer
+	for _, frame := range tag.Frames {
+		header := make([]byte, headerSize)
+		copy(header, frame.ID)
+		if tag.Version == 4 {
+			copy(header[4:8], syncSafeBytes(uint32(len(frame.Data))))
+		} else {
+			binary.BigEndian.PutUint32(header[4:8], uint32(len(frame.Data)))
+		}
+		buf.Write(header)
+		buf.Write(frame.Data)
+	}
+	return buf.Bytes()
+}
+
+// WriteFile replaces the ID3v2 tag of the given file with the given Tag. An existing ID3v1 tag is removed as well. The
+// remaining content is copied as it is. The file is replaced atomically by writing to a temporary file first which is
+// synced to disk before.
+func WriteFile(file string, tag *Tag) error {
+	src, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("could not open file: %v", err)
+	}
+	defer func() { _ = src.Close() }()
+	info, err := src.Stat()
+	if err != nil {
+		return fmt.Errorf("could not stat file: %v", err)
+	}
+	start, end, err := contentRange(src, info.Size())
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(file), fmt.Sprintf(".%s.*.tmp", filepath.Base(file)))
+	if err != nil {
+		return fmt.Errorf("could not create temporary file: %v", err)
+	}
+	// Removing fails after successful renaming which is fine.
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err = tmp.Write(tag.Bytes()); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("could not write tag: %v", err)
+	}
+	if _, err = io.Copy(tmp, io.NewSectionReader(src, start, end-start)); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("could not copy content: %v", err)
+	}
+	if err = tmp.Chmod(info.Mode().Perm()); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("could not set file mode: %v", err)
+	}
+	// Sync before replacing, so that a crash cannot leave a truncated file instead of the original one.
+	if err = tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("could not sync temporary file: %v", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("could not close temporary file: %v", err)
+	}
+	if err = os.Rename(tmp.Name(), file); err != nil {
+		return fmt.Errorf("could not replace file: %v", err)
+	}
+	return nil
+}
+
+// contentRange returns the range of the given file with the given size without ID3v2 and ID3v1 tags.
+func contentRange(f io.ReaderAt, size int64) (int64, int64, error) {
+	var start int64
+	header := make([]byte, headerSize)
+	if _, err := f.ReadAt(header, 0); err == nil && bytes.HasPrefix(header, []byte("ID3")) {
+		start = int64(syncSafe(header[6:10])) + headerSize
+		// Footer present.
+		if header[5]&0x10 != 0 {
+			start += headerSize
+		}
+	}
+	end := size
+	if size-id3v1Size >= start {
+		trailer := make([]byte, 3)
+		if _, err := f.ReadAt(trailer, size-id3v1Size); err != nil {
+			return 0, 0, fmt.Errorf("could not read id3v1 tag: %v", err)
+		}
+		if bytes.Equal(trailer, []byte("TAG")) {
+			end -= id3v1Size
+		}
+	}
+	if start > end {
+		return 0, 0, fmt.Errorf("id3 tag exceeds file size")
+	}
+	return start, end, nil
+}
+
+// encodeText encodes the given text as ISO-8859-1 if possible and as UTF-16 with byte order mark otherwise.
+func encodeText(s string) (byte, []byte) {
+	latin1 := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r > 0xFF {
+			units := utf16.Encode([]rune(s))
+			encoded := []byte{0xFF, 0xFE}
+			for _, unit := range units {
+				encoded = append(encoded, byte(unit), byte(unit>>8))
+			}
+			return encodingUTF16, encoded
+		}
+		latin1 = append(latin1, byte(r))
+	}
+	return encodingISO88591, latin1
+}
+
+// terminated appends the terminator of the given encoding to the given encoded text.
+func terminated(encoding byte, encoded []byte) []byte {
+	if encoding == encodingUTF16 || encoding == encodingUTF16BE {
+		return append(encoded, 0, 0)
+	}
+	return append(encoded, 0)
+}
+
+// syncSafeBytes encodes the given value as synchsafe integer.
+func syncSafeBytes(v uint32) []byte {
+	return []byte{byte(v >> 21 & 0x7F), byte(v >> 14 & 0x7F), byte(v >> 7 & 0x7F), byte(v & 0x7F)}
+}
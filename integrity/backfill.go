@@ -0,0 +1,37 @@
+package integrity
+
+import (
+	"fmt"
+	"github.com/life-unlimited/podcastination-server/stores"
+	"github.com/pkg/errors"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// BackfillFileSizes sets the file size of all episodes that have an audio file but no file size yet, which is the case
+// for episodes imported before file sizes were stored. Episodes whose file could not be read are skipped and only
+// logged. The number of updated episodes is returned.
+func BackfillFileSizes(podcastDir string, episodeStore stores.EpisodeStore) (int, error) {
+	episodes, err := episodeStore.All()
+	if err != nil {
+		return 0, errors.Wrap(err, "get all episodes from store")
+	}
+	updated := 0
+	for _, episode := range episodes {
+		if episode.FileSize != 0 || episode.MP3Location == "" {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(podcastDir, episode.MP3Location))
+		if err != nil {
+			log.Printf("could not backfill file size of episode %d: %v", episode.Id, err)
+			continue
+		}
+		episode.FileSize = info.Size()
+		if err = episodeStore.Update(episode); err != nil {
+			return updated, errors.Wrap(err, fmt.Sprintf("update episode %d", episode.Id))
+		}
+		updated++
+	}
+	return updated, nil
+}
@@ -0,0 +1,513 @@
+// Package integrity is used for checking that the podcast directory and the stores match.
+package integrity
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/life-unlimited/podcastination-server/audio"
+	"github.com/life-unlimited/podcastination-server/feedgen"
+	"github.com/life-unlimited/podcastination-server/podcast_xml"
+	"github.com/life-unlimited/podcastination-server/podcasts"
+	"github.com/life-unlimited/podcastination-server/tasks"
+	"github.com/life-unlimited/podcastination-server/transcripts"
+	"github.com/life-unlimited/podcastination-server/transfer"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Checker checks the integrity of the podcast directory and the stores.
+type Checker struct {
+	// PodcastDir is the directory where podcasts are stored.
+	PodcastDir string
+	// FeedFileName is the file name of the generated feed in each podcast folder.
+	FeedFileName string
+	// Stores are used for retrieving podcasts, seasons and episodes and for repairing them.
+	Stores Stores
+	// CheckLengths enables checking the stored mp3 length against the file. As this requires reading all mp3 files, it
+	// might take a while.
+	CheckLengths bool
+	// Repair enables repairing found issues. Episodes with missing mp3 files are set unavailable, missing image and pdf
+	// locations are removed, missing chapters files are regenerated, stuck episodes are either completed or removed and
+	// mismatching mp3 lengths are updated.
+	Repair bool
+	// OrphanDir is the directory where orphan files and folders are moved to when repairing. If empty, they are kept.
+	OrphanDir string
+	// PullDir is the directory of the import tasks. Stuck episodes whose import journal is found there are removed
+	// when repairing, even if they have no episode folder.
+	PullDir string
+}
+
+// Stores are the stores needed by the Checker. They are implemented by the ones in the stores package.
+type Stores struct {
+	Podcasts PodcastStore
+	Seasons  SeasonStore
+	Episodes EpisodeStore
+	Chapters ChapterStore
+}
+
+// PodcastStore provides all podcasts.
+type PodcastStore interface {
+	All() ([]podcasts.Podcast, error)
+}
+
+// SeasonStore provides all seasons.
+type SeasonStore interface {
+	All() ([]podcasts.Season, error)
+}
+
+// EpisodeStore provides all episodes and allows updating and deleting them for repairs.
+type EpisodeStore interface {
+	All() ([]podcasts.Episode, error)
+	Update(e podcasts.Episode) error
+	Delete(id int) error
+}
+
+// ChapterStore provides all chapters.
+type ChapterStore interface {
+	All() ([]podcasts.Chapter, error)
+}
+
+// FileKind is the kind of file referenced by an episode.
+type FileKind string
+
+const (
+	FileKindMP3        FileKind = "mp3"
+	FileKindImage      FileKind = "image"
+	FileKindPDF        FileKind = "pdf"
+	FileKindTranscript FileKind = "transcript"
+	FileKindChapters   FileKind = "chapters"
+)
+
+// MissingFile is a file referenced by an episode that does not exist.
+type MissingFile struct {
+	EpisodeId int      `json:"episode_id"`
+	Kind      FileKind `json:"kind"`
+	Location  string   `json:"location"`
+}
+
+// StuckEpisode is an episode that is not available and has no mp3 location which happens when an import was
+// interrupted. However, episodes created manually without files look the same, so only those with an episode folder or
+// an import journal are considered interrupted.
+type StuckEpisode struct {
+	EpisodeId int `json:"episode_id"`
+	// Interrupted is true if there is evidence of an interrupted import.
+	Interrupted bool `json:"interrupted"`
+	// Complete is true if all files exist at their expected locations.
+	Complete bool `json:"complete"`
+}
+
+// LengthMismatch is an episode whose stored mp3 length does not match the actual one.
+type LengthMismatch struct {
+	EpisodeId int `json:"episode_id"`
+	Stored    int `json:"stored"`
+	Actual    int `json:"actual"`
+}
+
+// Report holds all issues found by the Checker as well as performed repairs.
+type Report struct {
+	MissingFiles     []MissingFile    `json:"missing_files"`
+	OrphanFiles      []string         `json:"orphan_files"`
+	OrphanDirs       []string         `json:"orphan_dirs"`
+	StuckEpisodes    []StuckEpisode   `json:"stuck_episodes"`
+	LengthMismatches []LengthMismatch `json:"length_mismatches"`
+	// Repairs are descriptions of the performed repairs.
+	Repairs []string `json:"repairs"`
+	// RepairErrors are descriptions of repairs that failed.
+	RepairErrors []string `json:"repair_errors"`
+}
+
+// IsClean checks if no issues were found.
+func (r *Report) IsClean() bool {
+	return len(r.MissingFiles) == 0 && len(r.OrphanFiles) == 0 && len(r.OrphanDirs) == 0 &&
+		len(r.StuckEpisodes) == 0 && len(r.LengthMismatches) == 0
+}
+
+// Issues returns human-readable descriptions of all found issues.
+func (r *Report) Issues() []string {
+	issues := make([]string, 0)
+	for _, f := range r.MissingFiles {
+		issues = append(issues, fmt.Sprintf("missing %s file %s for episode %d", f.Kind, f.Location, f.EpisodeId))
+	}
+	for _, f := range r.OrphanFiles {
+		issues = append(issues, fmt.Sprintf("orphan file %s", f))
+	}
+	for _, d := range r.OrphanDirs {
+		issues = append(issues, fmt.Sprintf("orphan folder %s", d))
+	}
+	for _, e := range r.StuckEpisodes {
+		if !e.Interrupted {
+			issues = append(issues, fmt.Sprintf("episode %d is unavailable without mp3 file", e.EpisodeId))
+			continue
+		}
+		issues = append(issues, fmt.Sprintf("episode %d stuck from interrupted import (files complete: %v)",
+			e.EpisodeId, e.Complete))
+	}
+	for _, m := range r.LengthMismatches {
+		issues = append(issues, fmt.Sprintf("mp3 length of episode %d is %d but stored as %d", m.EpisodeId,
+			m.Actual, m.Stored))
+	}
+	return issues
+}
+
+// Check performs the integrity check and repairs issues if enabled.
+func (c *Checker) Check() (Report, error) {
+	report := Report{
+		MissingFiles:     make([]MissingFile, 0),
+		OrphanFiles:      make([]string, 0),
+		OrphanDirs:       make([]string, 0),
+		StuckEpisodes:    make([]StuckEpisode, 0),
+		LengthMismatches: make([]LengthMismatch, 0),
+		Repairs:          make([]string, 0),
+		RepairErrors:     make([]string, 0),
+	}
+	storePodcasts, err := c.Stores.Podcasts.All()
+	if err != nil {
+		return Report{}, errors.Wrap(err, "get all podcasts from store")
+	}
+	seasons, err := c.Stores.Seasons.All()
+	if err != nil {
+		return Report{}, errors.Wrap(err, "get all seasons from store")
+	}
+	episodes, err := c.Stores.Episodes.All()
+	if err != nil {
+		return Report{}, errors.Wrap(err, "get all episodes from store")
+	}
+	storeChapters, err := c.Stores.Chapters.All()
+	if err != nil {
+		return Report{}, errors.Wrap(err, "get all chapters from store")
+	}
+	journaledEpisodes := make(map[int]struct{})
+	if c.PullDir != "" {
+		journaledEpisodes, err = tasks.JournaledEpisodeIds(c.PullDir)
+		if err != nil {
+			return Report{}, errors.Wrap(err, "get journaled episodes from pull dir")
+		}
+	}
+	chaptersOfEpisode := make(map[int][]podcasts.Chapter)
+	for _, chapter := range storeChapters {
+		chaptersOfEpisode[chapter.EpisodeId] = append(chaptersOfEpisode[chapter.EpisodeId], chapter)
+	}
+	podcastOfSeason := make(map[int]int)
+	for _, season := range seasons {
+		podcastOfSeason[season.Id] = season.PodcastId
+	}
+	// Collect known files.
+	known := newKnownLocations()
+	for _, podcast := range storePodcasts {
+		known.addDir(transfer.GetPodcastFolderName(podcast.Id))
+		known.addFile(filepath.Join(transfer.GetPodcastFolderName(podcast.Id), c.FeedFileName))
+		for _, format := range feedgen.Formats {
+			if format.FileName != "" {
+				known.addFile(filepath.Join(transfer.GetPodcastFolderName(podcast.Id), format.FileName))
+			}
+		}
+		known.addFile(podcast.ImageLocation)
+	}
+	for _, season := range seasons {
+		known.addFile(season.ImageLocation)
+	}
+	// Check episodes.
+	for _, episode := range episodes {
+		podcastId := podcastOfSeason[episode.SeasonId]
+		if !episode.IsAvailable && episode.MP3Location == "" {
+			_, journaled := journaledEpisodes[episode.Id]
+			locations, stuck, err := c.checkStuckEpisode(episode, podcastId, journaled, &report)
+			if err != nil {
+				return Report{}, errors.Wrap(err, fmt.Sprintf("check stuck episode %d", episode.Id))
+			}
+			if stuck.Complete {
+				known.addFile(locations.MP3FullPath())
+				known.addFile(locations.ImageFullPath())
+				known.addFile(locations.PDFFullPath())
+				for _, ext := range transcripts.Extensions() {
+					known.addFile(locations.TranscriptFullPath(ext))
+				}
+				known.addFile(locations.ChaptersFullPath())
+			}
+			continue
+		}
+		known.addFile(episode.MP3Location)
+		known.addFile(episode.ImageLocation)
+		known.addFile(episode.PDFLocation)
+		for _, transcript := range episode.Transcripts {
+			known.addFile(transcript.Location)
+		}
+		if len(chaptersOfEpisode[episode.Id]) > 0 {
+			known.addFile(transfer.ChaptersLocation(episode.MP3Location))
+		}
+		c.checkEpisodeFiles(episode, chaptersOfEpisode[episode.Id], &report)
+	}
+	// Walk the podcast dir.
+	err = filepath.Walk(c.PodcastDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(c.PodcastDir, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		if info.IsDir() {
+			if !known.hasDir(rel) {
+				report.OrphanDirs = append(report.OrphanDirs, rel)
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !known.hasFile(rel) {
+			report.OrphanFiles = append(report.OrphanFiles, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return Report{}, errors.Wrap(err, "walk podcast dir")
+	}
+	if c.Repair && c.OrphanDir != "" {
+		for _, orphan := range append(append([]string{}, report.OrphanDirs...), report.OrphanFiles...) {
+			c.repair(&report, fmt.Sprintf("move orphan %s to %s", orphan, c.OrphanDir), func() error {
+				return c.moveOrphan(orphan)
+			})
+		}
+	}
+	return report, nil
+}
+
+// checkEpisodeFiles checks if all files of the given episode with the given chapters exist and if the mp3 length
+// matches.
+func (c *Checker) checkEpisodeFiles(episode podcasts.Episode, chapters []podcasts.Chapter, report *Report) {
+	// Check mp3.
+	if !c.exists(episode.MP3Location) {
+		report.MissingFiles = append(report.MissingFiles, MissingFile{
+			EpisodeId: episode.Id,
+			Kind:      FileKindMP3,
+			Location:  episode.MP3Location,
+		})
+		if episode.IsAvailable {
+			c.repair(report, fmt.Sprintf("set episode %d unavailable", episode.Id), func() error {
+				episode.IsAvailable = false
+				return c.Stores.Episodes.Update(episode)
+			})
+		}
+	} else if c.CheckLengths {
+		info, err := audio.Probe(filepath.Join(c.PodcastDir, episode.MP3Location))
+		actual := info.Seconds()
+		if err != nil {
+			report.RepairErrors = append(report.RepairErrors, fmt.Sprintf("could not get mp3 length of episode %d: %v",
+				episode.Id, err))
+		} else if actual != episode.MP3Length {
+			report.LengthMismatches = append(report.LengthMismatches, LengthMismatch{
+				EpisodeId: episode.Id,
+				Stored:    episode.MP3Length,
+				Actual:    actual,
+			})
+			c.repair(report, fmt.Sprintf("update mp3 length of episode %d", episode.Id), func() error {
+				episode.MP3Length = actual
+				return c.Stores.Episodes.Update(episode)
+			})
+		}
+	}
+	// Check image.
+	if episode.ImageLocation != "" && !c.exists(episode.ImageLocation) {
+		report.MissingFiles = append(report.MissingFiles, MissingFile{
+			EpisodeId: episode.Id,
+			Kind:      FileKindImage,
+			Location:  episode.ImageLocation,
+		})
+		c.repair(report, fmt.Sprintf("remove image location of episode %d", episode.Id), func() error {
+			episode.ImageLocation = ""
+			return c.Stores.Episodes.Update(episode)
+		})
+	}
+	// Check pdf.
+	if episode.PDFLocation != "" && !c.exists(episode.PDFLocation) {
+		report.MissingFiles = append(report.MissingFiles, MissingFile{
+			EpisodeId: episode.Id,
+			Kind:      FileKindPDF,
+			Location:  episode.PDFLocation,
+		})
+		c.repair(report, fmt.Sprintf("remove pdf location of episode %d", episode.Id), func() error {
+			episode.PDFLocation = ""
+			return c.Stores.Episodes.Update(episode)
+		})
+	}
+	// Check transcripts.
+	existingTranscripts := make([]podcasts.Transcript, 0, len(episode.Transcripts))
+	for _, transcript := range episode.Transcripts {
+		if c.exists(transcript.Location) {
+			existingTranscripts = append(existingTranscripts, transcript)
+			continue
+		}
+		report.MissingFiles = append(report.MissingFiles, MissingFile{
+			EpisodeId: episode.Id,
+			Kind:      FileKindTranscript,
+			Location:  transcript.Location,
+		})
+	}
+	if len(existingTranscripts) != len(episode.Transcripts) {
+		c.repair(report, fmt.Sprintf("remove missing transcripts of episode %d", episode.Id), func() error {
+			episode.Transcripts = existingTranscripts
+			return c.Stores.Episodes.Update(episode)
+		})
+	}
+	// Check chapters.
+	if chaptersLocation := transfer.ChaptersLocation(episode.MP3Location); len(chapters) > 0 &&
+		!c.exists(chaptersLocation) {
+		report.MissingFiles = append(report.MissingFiles, MissingFile{
+			EpisodeId: episode.Id,
+			Kind:      FileKindChapters,
+			Location:  chaptersLocation,
+		})
+		c.repair(report, fmt.Sprintf("regenerate chapters file of episode %d", episode.Id), func() error {
+			raw, err := json.MarshalIndent(podcast_xml.GenerateChaptersJSON(chapters), "", "  ")
+			if err != nil {
+				return err
+			}
+			return ioutil.WriteFile(filepath.Join(c.PodcastDir, chaptersLocation), raw, 0644)
+		})
+	}
+}
+
+// checkStuckEpisode checks an episode from a possibly interrupted import. If the mp3 file exists at its expected
+// location, the import only failed when finally updating the episode and can be completed. Otherwise, the episode is
+// removed as the task is still in the pull dir and will be imported again. This requires either the episode folder or
+// the import journal (journaled) to exist as the episode might have been created manually and is only reported then.
+func (c *Checker) checkStuckEpisode(episode podcasts.Episode, podcastId int, journaled bool,
+	report *Report) (transfer.EpisodeFileLocations, StuckEpisode, error) {
+	folder, found, err := transfer.FindEpisodeFolder(c.PodcastDir, episode, podcastId)
+	if err != nil {
+		return transfer.EpisodeFileLocations{}, StuckEpisode{}, errors.Wrap(err, "find episode folder")
+	}
+	stuck := StuckEpisode{
+		EpisodeId:   episode.Id,
+		Interrupted: found || journaled,
+	}
+	// The extension of the audio file is not stored, so all extensions used for its mime type are tried.
+	locations := transfer.GetEpisodeFileLocations(episode, podcastId, "")
+	locations.BaseDir = folder
+	for _, ext := range audio.ExtensionsForMIMEType(episode.MIMEType) {
+		locations = transfer.GetEpisodeFileLocations(episode, podcastId, ext)
+		locations.BaseDir = folder
+		if found && c.exists(locations.MP3FullPath()) {
+			stuck.Complete = true
+			break
+		}
+	}
+	report.StuckEpisodes = append(report.StuckEpisodes, stuck)
+	if stuck.Complete {
+		c.repair(report, fmt.Sprintf("complete stuck episode %d", episode.Id), func() error {
+			episode.MP3Location = locations.MP3FullPath()
+			if info, err := os.Stat(filepath.Join(c.PodcastDir, episode.MP3Location)); err == nil {
+				episode.FileSize = info.Size()
+			}
+			if c.exists(locations.ImageFullPath()) {
+				episode.ImageLocation = locations.ImageFullPath()
+			}
+			if c.exists(locations.PDFFullPath()) {
+				episode.PDFLocation = locations.PDFFullPath()
+			}
+			episode.Transcripts = make([]podcasts.Transcript, 0)
+			for _, ext := range transcripts.Extensions() {
+				if location := locations.TranscriptFullPath(ext); c.exists(location) {
+					mimeType, _ := transcripts.MIMETypeForFileName(location)
+					episode.Transcripts = append(episode.Transcripts, podcasts.Transcript{
+						Location: location,
+						Type:     mimeType,
+					})
+				}
+			}
+			episode.IsAvailable = true
+			return c.Stores.Episodes.Update(episode)
+		})
+		return locations, stuck, nil
+	}
+	if !stuck.Interrupted {
+		return locations, stuck, nil
+	}
+	c.repair(report, fmt.Sprintf("remove stuck episode %d", episode.Id), func() error {
+		if err := c.Stores.Episodes.Delete(episode.Id); err != nil {
+			return err
+		}
+		if !found {
+			return nil
+		}
+		return os.RemoveAll(filepath.Join(c.PodcastDir, locations.BaseDir))
+	})
+	return locations, stuck, nil
+}
+
+// repair performs the given repair if repairing is enabled and adds the result to the report.
+func (c *Checker) repair(report *Report, description string, repair func() error) {
+	if !c.Repair {
+		return
+	}
+	if err := repair(); err != nil {
+		report.RepairErrors = append(report.RepairErrors, fmt.Sprintf("%s: %v", description, err))
+		return
+	}
+	report.Repairs = append(report.Repairs, description)
+}
+
+// moveOrphan moves the orphan file or folder with the given location to the OrphanDir while keeping its path.
+func (c *Checker) moveOrphan(location string) error {
+	destination := filepath.Join(c.OrphanDir, location)
+	if err := os.MkdirAll(filepath.Dir(destination), 0744); err != nil {
+		return err
+	}
+	return os.Rename(filepath.Join(c.PodcastDir, location), destination)
+}
+
+// exists checks if the file with the given location exists in the podcast dir.
+func (c *Checker) exists(location string) bool {
+	if location == "" {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(c.PodcastDir, location))
+	return err == nil && !info.IsDir()
+}
+
+// knownLocations holds all files and folders in the podcast dir that are referenced.
+type knownLocations struct {
+	files map[string]struct{}
+	dirs  map[string]struct{}
+}
+
+func newKnownLocations() *knownLocations {
+	return &knownLocations{
+		files: make(map[string]struct{}),
+		dirs:  make(map[string]struct{}),
+	}
+}
+
+// addFile adds the given file location as well as all of its parent folders.
+func (k *knownLocations) addFile(location string) {
+	if location == "" {
+		return
+	}
+	location = filepath.Clean(location)
+	k.files[location] = struct{}{}
+	k.addDir(filepath.Dir(location))
+}
+
+// addDir adds the given folder location as well as all of its parent folders.
+func (k *knownLocations) addDir(location string) {
+	location = filepath.Clean(location)
+	for location != "." && location != string(filepath.Separator) && !strings.HasPrefix(location, "..") {
+		k.dirs[location] = struct{}{}
+		location = filepath.Dir(location)
+	}
+}
+
+func (k *knownLocations) hasFile(location string) bool {
+	_, ok := k.files[filepath.Clean(location)]
+	return ok
+}
+
+func (k *knownLocations) hasDir(location string) bool {
+	_, ok := k.dirs[filepath.Clean(location)]
+	return ok
+}
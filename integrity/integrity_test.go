@@ -0,0 +1,333 @@
+package integrity
+
+import (
+	"fmt"
+	"github.com/life-unlimited/podcastination-server/podcasts"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeStores implements all stores needed by the Checker in memory.
+type fakeStores struct {
+	podcasts []podcasts.Podcast
+	seasons  []podcasts.Season
+	episodes []podcasts.Episode
+	chapters []podcasts.Chapter
+	// updated holds the last update of each episode.
+	updated map[int]podcasts.Episode
+	// deleted holds the ids of deleted episodes.
+	deleted []int
+	// err is returned by all repairs if set.
+	err error
+}
+
+type fakePodcastStore struct{ *fakeStores }
+
+func (s fakePodcastStore) All() ([]podcasts.Podcast, error) {
+	return s.podcasts, nil
+}
+
+type fakeSeasonStore struct{ *fakeStores }
+
+func (s fakeSeasonStore) All() ([]podcasts.Season, error) {
+	return s.seasons, nil
+}
+
+type fakeEpisodeStore struct{ *fakeStores }
+
+func (s fakeEpisodeStore) All() ([]podcasts.Episode, error) {
+	return s.episodes, nil
+}
+
+func (s fakeEpisodeStore) Update(e podcasts.Episode) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.updated[e.Id] = e
+	return nil
+}
+
+func (s fakeEpisodeStore) Delete(id int) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.deleted = append(s.deleted, id)
+	return nil
+}
+
+type fakeChapterStore struct{ *fakeStores }
+
+func (s fakeChapterStore) All() ([]podcasts.Chapter, error) {
+	return s.chapters, nil
+}
+
+type CheckerTestSuite struct {
+	suite.Suite
+	podcastDir string
+	orphanDir  string
+	pullDir    string
+	stores     *fakeStores
+	checker    Checker
+}
+
+func (suite *CheckerTestSuite) SetupTest() {
+	var err error
+	suite.podcastDir, err = ioutil.TempDir("", "podcasts")
+	suite.Require().Nil(err, "creating podcast dir should not fail")
+	suite.orphanDir, err = ioutil.TempDir("", "orphans")
+	suite.Require().Nil(err, "creating orphan dir should not fail")
+	suite.pullDir, err = ioutil.TempDir("", "pull")
+	suite.Require().Nil(err, "creating pull dir should not fail")
+	suite.stores = &fakeStores{
+		podcasts: []podcasts.Podcast{{Id: 1}},
+		seasons:  []podcasts.Season{{Id: 2, PodcastId: 1}},
+		updated:  make(map[int]podcasts.Episode),
+	}
+	suite.checker = Checker{
+		PodcastDir:   suite.podcastDir,
+		FeedFileName: "podcast.xml",
+		Stores: Stores{
+			Podcasts: fakePodcastStore{suite.stores},
+			Seasons:  fakeSeasonStore{suite.stores},
+			Episodes: fakeEpisodeStore{suite.stores},
+			Chapters: fakeChapterStore{suite.stores},
+		},
+		OrphanDir: suite.orphanDir,
+		PullDir:   suite.pullDir,
+	}
+	suite.writeFile(filepath.Join("1", "podcast.xml"))
+}
+
+func (suite *CheckerTestSuite) TearDownTest() {
+	_ = os.RemoveAll(suite.podcastDir)
+	_ = os.RemoveAll(suite.orphanDir)
+	_ = os.RemoveAll(suite.pullDir)
+}
+
+// writeFile creates the file with the given location in the podcast dir.
+func (suite *CheckerTestSuite) writeFile(location string) {
+	path := filepath.Join(suite.podcastDir, location)
+	suite.Require().Nil(os.MkdirAll(filepath.Dir(path), 0744), "creating dirs should not fail")
+	suite.Require().Nil(ioutil.WriteFile(path, []byte("content"), 0644), "writing file should not fail")
+}
+
+// exists checks if the file or folder with the given location exists in the given dir.
+func (suite *CheckerTestSuite) exists(dir string, location string) bool {
+	_, err := os.Stat(filepath.Join(dir, location))
+	return err == nil
+}
+
+// episode returns an available episode with the given id whose files are referenced but not created.
+func (suite *CheckerTestSuite) episode(id int) podcasts.Episode {
+	folder := filepath.Join("1", fmt.Sprintf("20210101_120000_%d", id))
+	return podcasts.Episode{
+		Id:            id,
+		Title:         "Episode",
+		Date:          time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC),
+		SeasonId:      2,
+		MP3Location:   filepath.Join(folder, fmt.Sprintf("%d_Episode.mp3", id)),
+		MIMEType:      "audio/mpeg",
+		ImageLocation: filepath.Join(folder, "thumb.png"),
+		PDFLocation:   filepath.Join(folder, fmt.Sprintf("%d_Episode.pdf", id)),
+		IsAvailable:   true,
+	}
+}
+
+func (suite *CheckerTestSuite) TestClean() {
+	episode := suite.episode(3)
+	suite.writeFile(episode.MP3Location)
+	suite.writeFile(episode.ImageLocation)
+	suite.writeFile(episode.PDFLocation)
+	suite.stores.episodes = []podcasts.Episode{episode}
+	report, err := suite.checker.Check()
+	suite.Require().Nil(err, "check should not fail")
+	suite.Assert().True(report.IsClean(), "report should be clean but has issues %v", report.Issues())
+}
+
+func (suite *CheckerTestSuite) TestMissingFilesWithoutRepair() {
+	suite.stores.episodes = []podcasts.Episode{suite.episode(3)}
+	report, err := suite.checker.Check()
+	suite.Require().Nil(err, "check should not fail")
+	suite.Assert().ElementsMatch([]FileKind{FileKindMP3, FileKindImage, FileKindPDF}, fileKinds(report.MissingFiles),
+		"missing files should be reported")
+	suite.Assert().Empty(report.Repairs, "nothing should be repaired")
+	suite.Assert().Empty(suite.stores.updated, "no episode should be updated")
+}
+
+func (suite *CheckerTestSuite) TestMissingFilesWithRepair() {
+	suite.checker.Repair = true
+	suite.stores.episodes = []podcasts.Episode{suite.episode(3)}
+	report, err := suite.checker.Check()
+	suite.Require().Nil(err, "check should not fail")
+	suite.Assert().Len(report.MissingFiles, 3, "missing files should be reported")
+	suite.Assert().Len(report.Repairs, 3, "all repairs should be performed")
+	suite.Assert().Empty(report.RepairErrors, "repairs should not fail")
+	updated := suite.stores.updated[3]
+	suite.Assert().False(updated.IsAvailable, "episode should be set unavailable")
+	suite.Assert().Empty(updated.ImageLocation, "image location should be removed")
+	suite.Assert().Empty(updated.PDFLocation, "pdf location should be removed")
+}
+
+func (suite *CheckerTestSuite) TestFailingRepair() {
+	suite.checker.Repair = true
+	suite.stores.err = fmt.Errorf("db down")
+	episode := suite.episode(3)
+	episode.ImageLocation = ""
+	episode.PDFLocation = ""
+	suite.stores.episodes = []podcasts.Episode{episode}
+	report, err := suite.checker.Check()
+	suite.Require().Nil(err, "check should not fail")
+	suite.Assert().Empty(report.Repairs, "no repair should succeed")
+	suite.Assert().Equal([]string{"set episode 3 unavailable: db down"}, report.RepairErrors,
+		"failed repair should be reported")
+}
+
+func (suite *CheckerTestSuite) TestOrphansWithoutRepair() {
+	suite.writeFile(filepath.Join("1", "unknown.mp3"))
+	suite.writeFile(filepath.Join("1", "20210101_120000_9", "9_Removed.mp3"))
+	report, err := suite.checker.Check()
+	suite.Require().Nil(err, "check should not fail")
+	suite.Assert().Equal([]string{filepath.Join("1", "unknown.mp3")}, report.OrphanFiles,
+		"orphan file should be reported")
+	suite.Assert().Equal([]string{filepath.Join("1", "20210101_120000_9")}, report.OrphanDirs,
+		"orphan folder should be reported")
+	suite.Assert().True(suite.exists(suite.podcastDir, filepath.Join("1", "unknown.mp3")), "orphan file should be kept")
+}
+
+func (suite *CheckerTestSuite) TestOrphansWithRepair() {
+	suite.checker.Repair = true
+	suite.writeFile(filepath.Join("1", "unknown.mp3"))
+	suite.writeFile(filepath.Join("1", "20210101_120000_9", "9_Removed.mp3"))
+	report, err := suite.checker.Check()
+	suite.Require().Nil(err, "check should not fail")
+	suite.Assert().Len(report.Repairs, 2, "orphans should be moved")
+	suite.Assert().Empty(report.RepairErrors, "moving orphans should not fail")
+	suite.Assert().False(suite.exists(suite.podcastDir, filepath.Join("1", "unknown.mp3")),
+		"orphan file should be moved")
+	suite.Assert().True(suite.exists(suite.orphanDir, filepath.Join("1", "unknown.mp3")),
+		"orphan file should be moved to the orphan dir with its path")
+	suite.Assert().False(suite.exists(suite.podcastDir, filepath.Join("1", "20210101_120000_9")),
+		"orphan folder should be moved")
+	suite.Assert().True(suite.exists(suite.orphanDir, filepath.Join("1", "20210101_120000_9", "9_Removed.mp3")),
+		"orphan folder should be moved to the orphan dir with its content")
+	suite.Assert().True(suite.exists(suite.podcastDir, filepath.Join("1", "podcast.xml")), "feed should be kept")
+}
+
+func (suite *CheckerTestSuite) TestOrphansWithoutOrphanDir() {
+	suite.checker.Repair = true
+	suite.checker.OrphanDir = ""
+	suite.writeFile(filepath.Join("1", "unknown.mp3"))
+	report, err := suite.checker.Check()
+	suite.Require().Nil(err, "check should not fail")
+	suite.Assert().Len(report.OrphanFiles, 1, "orphan file should be reported")
+	suite.Assert().Empty(report.Repairs, "orphan should not be moved")
+	suite.Assert().True(suite.exists(suite.podcastDir, filepath.Join("1", "unknown.mp3")), "orphan file should be kept")
+}
+
+func (suite *CheckerTestSuite) TestCompleteStuckEpisode() {
+	suite.checker.Repair = true
+	episode := suite.episode(3)
+	suite.writeFile(episode.MP3Location)
+	suite.writeFile(episode.ImageLocation)
+	mp3Location := episode.MP3Location
+	imageLocation := episode.ImageLocation
+	episode.MP3Location = ""
+	episode.ImageLocation = ""
+	episode.PDFLocation = ""
+	episode.IsAvailable = false
+	suite.stores.episodes = []podcasts.Episode{episode}
+	report, err := suite.checker.Check()
+	suite.Require().Nil(err, "check should not fail")
+	suite.Assert().Equal([]StuckEpisode{{EpisodeId: 3, Interrupted: true, Complete: true}}, report.StuckEpisodes,
+		"stuck episode should be reported as complete")
+	suite.Assert().Empty(report.OrphanFiles, "files of stuck episode should not be orphans")
+	suite.Assert().Empty(report.RepairErrors, "completing should not fail")
+	updated := suite.stores.updated[3]
+	suite.Assert().True(updated.IsAvailable, "episode should be available")
+	suite.Assert().Equal(mp3Location, updated.MP3Location, "mp3 location should be set")
+	suite.Assert().Equal(imageLocation, updated.ImageLocation, "image location should be set")
+	suite.Assert().Empty(updated.PDFLocation, "missing pdf should not be set")
+	suite.Assert().Equal(int64(len("content")), updated.FileSize, "file size should be set")
+}
+
+func (suite *CheckerTestSuite) TestIncompleteStuckEpisode() {
+	suite.checker.Repair = true
+	episode := suite.episode(3)
+	suite.writeFile(episode.ImageLocation)
+	folder := filepath.Dir(episode.MP3Location)
+	episode.MP3Location = ""
+	episode.IsAvailable = false
+	suite.stores.episodes = []podcasts.Episode{episode}
+	report, err := suite.checker.Check()
+	suite.Require().Nil(err, "check should not fail")
+	suite.Assert().Equal([]StuckEpisode{{EpisodeId: 3, Interrupted: true, Complete: false}}, report.StuckEpisodes,
+		"stuck episode should be reported as incomplete")
+	suite.Assert().Empty(report.RepairErrors, "removing should not fail")
+	suite.Assert().Equal([]int{3}, suite.stores.deleted, "episode should be deleted")
+	suite.Assert().Empty(suite.stores.updated, "episode should not be updated")
+	suite.Assert().False(suite.exists(suite.podcastDir, folder), "episode folder should be removed")
+}
+
+func (suite *CheckerTestSuite) TestStuckEpisodeWithoutRepair() {
+	episode := suite.episode(3)
+	suite.writeFile(episode.ImageLocation)
+	folder := filepath.Dir(episode.MP3Location)
+	episode.MP3Location = ""
+	episode.IsAvailable = false
+	suite.stores.episodes = []podcasts.Episode{episode}
+	report, err := suite.checker.Check()
+	suite.Require().Nil(err, "check should not fail")
+	suite.Assert().Len(report.StuckEpisodes, 1, "stuck episode should be reported")
+	suite.Assert().Empty(suite.stores.deleted, "episode should not be deleted")
+	suite.Assert().True(suite.exists(suite.podcastDir, folder), "episode folder should be kept")
+}
+
+func (suite *CheckerTestSuite) TestUnavailableEpisodeWithoutFolder() {
+	suite.checker.Repair = true
+	episode := suite.episode(3)
+	episode.MP3Location = ""
+	episode.IsAvailable = false
+	suite.stores.episodes = []podcasts.Episode{episode}
+	report, err := suite.checker.Check()
+	suite.Require().Nil(err, "check should not fail")
+	suite.Assert().Equal([]StuckEpisode{{EpisodeId: 3, Interrupted: false, Complete: false}}, report.StuckEpisodes,
+		"unavailable episode should be reported as not interrupted")
+	suite.Assert().Empty(report.Repairs, "nothing should be repaired")
+	suite.Assert().Empty(suite.stores.deleted, "episode should not be deleted")
+	suite.Assert().Empty(suite.stores.updated, "episode should not be updated")
+}
+
+func (suite *CheckerTestSuite) TestJournaledStuckEpisodeWithoutFolder() {
+	suite.checker.Repair = true
+	taskDir := filepath.Join(suite.pullDir, "task")
+	suite.Require().Nil(os.Mkdir(taskDir, 0744), "creating task dir should not fail")
+	suite.Require().Nil(ioutil.WriteFile(filepath.Join(taskDir, ".import-journal.json"),
+		[]byte(`{"state":"started","episode_id":3,"copied_files":[]}`), 0644), "writing journal should not fail")
+	episode := suite.episode(3)
+	episode.MP3Location = ""
+	episode.IsAvailable = false
+	suite.stores.episodes = []podcasts.Episode{episode}
+	report, err := suite.checker.Check()
+	suite.Require().Nil(err, "check should not fail")
+	suite.Assert().Equal([]StuckEpisode{{EpisodeId: 3, Interrupted: true, Complete: false}}, report.StuckEpisodes,
+		"stuck episode should be reported as interrupted")
+	suite.Assert().Empty(report.RepairErrors, "removing should not fail")
+	suite.Assert().Equal([]int{3}, suite.stores.deleted, "episode should be deleted")
+}
+
+func TestChecker(t *testing.T) {
+	suite.Run(t, new(CheckerTestSuite))
+}
+
+// fileKinds returns the kinds of the given missing files.
+func fileKinds(files []MissingFile) []FileKind {
+	kinds := make([]FileKind, 0, len(files))
+	for _, f := range files {
+		kinds = append(kinds, f.Kind)
+	}
+	return kinds
+}
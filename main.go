@@ -19,9 +19,20 @@ func main() {
 	if err != nil {
 		log.Fatalf("could not read config: %v", err)
 	}
-	printDirs(podcastinationConfig)
 	// Create the app.
 	podcastination := app.NewApp(podcastinationConfig)
+	// Run command if provided.
+	if flag.NArg() > 0 {
+		err = runCommand(podcastination, flag.Args())
+		if shutdownErr := podcastination.Shutdown(); shutdownErr != nil {
+			log.Printf("could not shutdown podcastination: %v", shutdownErr)
+		}
+		if err != nil {
+			log.Fatalf("could not run command: %v", err)
+		}
+		return
+	}
+	printDirs(podcastinationConfig)
 	// Boot.
 	log.Println("starting...")
 	if err := podcastination.Boot(); err != nil {
@@ -0,0 +1,43 @@
+package podcast_xml
+
+import (
+	"github.com/life-unlimited/podcastination-server/podcasts"
+	"sort"
+)
+
+// ChaptersJSONType is the MIME type of the JSON chapters format of the podcast namespace.
+const ChaptersJSONType = "application/json+chapters"
+
+// chaptersJSONVersion is the version of the JSON chapters format.
+const chaptersJSONVersion = "1.2.0"
+
+// ChaptersJSON is a chapters file in the JSON chapters format of the podcast namespace (see
+// https://github.com/Podcastindex-org/podcast-namespace/blob/main/chapters/jsonChapters.md).
+type ChaptersJSON struct {
+	Version  string        `json:"version"`
+	Chapters []chapterJSON `json:"chapters"`
+}
+
+type chapterJSON struct {
+	StartTime float64 `json:"startTime"`
+	Title     string  `json:"title"`
+}
+
+// GenerateChaptersJSON generates a ChaptersJSON from the given chapters of an episode.
+func GenerateChaptersJSON(chapters []podcasts.Chapter) ChaptersJSON {
+	sorted := append([]podcasts.Chapter{}, chapters...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].StartTime < sorted[j].StartTime
+	})
+	chaptersJSON := ChaptersJSON{
+		Version:  chaptersJSONVersion,
+		Chapters: make([]chapterJSON, 0, len(sorted)),
+	}
+	for _, chapter := range sorted {
+		chaptersJSON.Chapters = append(chaptersJSON.Chapters, chapterJSON{
+			StartTime: chapter.StartTime,
+			Title:     chapter.Title,
+		})
+	}
+	return chaptersJSON
+}
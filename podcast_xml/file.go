@@ -9,32 +9,38 @@ type PodcastXML struct {
 	XmlnsITunes  string   `xml:"xmlns:itunes,attr"`
 	XmlnsGPlay   string   `xml:"xmlns:googleplay,attr"`
 	XmlnsMedia   string   `xml:"xmlns:media,attr"`
+	XmlnsPodcast string   `xml:"xmlns:podcast,attr"`
 	Version      string   `xml:"version,attr"`
 	Channel      channel  `xml:"channel"`
 }
 
 type channel struct {
-	Title          string         `xml:"title"`
-	Link           string         `xml:"link"`
-	Language       string         `xml:"language"`
-	AtomLink       atomLink       `xml:"atom:link"`
-	Copyright      string         `xml:"copyright"`
-	ITunesSubtitle string         `xml:"itunes:subtitle"`
-	ITunesAuthor   string         `xml:"itunes:author"`
-	ITunesSummary  string         `xml:"itunes:summary"`
-	ITunesKeywords string         `xml:"itunes:keywords"`
-	Description    string         `xml:"description"`
-	ITunesOwner    iTunesOwner    `xml:"itunes:owner"`
-	Image          image          `xml:"image"`
-	ITunesImage    iTunesImage    `xml:"itunes:image"`
-	ITunesCategory iTunesCategory `xml:"itunes:category"`
-	Items          []item         `xml:"item"`
+	Title          string           `xml:"title"`
+	Link           string           `xml:"link"`
+	Language       string           `xml:"language"`
+	AtomLinks      []atomLink       `xml:"atom:link"`
+	Copyright      string           `xml:"copyright"`
+	ITunesSubtitle string           `xml:"itunes:subtitle"`
+	ITunesAuthor   string           `xml:"itunes:author"`
+	ITunesSummary  string           `xml:"itunes:summary"`
+	ITunesKeywords string           `xml:"itunes:keywords"`
+	Description    string           `xml:"description"`
+	ITunesOwner    iTunesOwner      `xml:"itunes:owner"`
+	Image          image            `xml:"image"`
+	ITunesImage    iTunesImage      `xml:"itunes:image"`
+	ITunesCategory []iTunesCategory `xml:"itunes:category"`
+	ITunesExplicit string           `xml:"itunes:explicit"`
+	PodcastGUID    string           `xml:"podcast:guid"`
+	PodcastLocked  podcastLocked    `xml:"podcast:locked"`
+	PodcastFunding []podcastFunding `xml:"podcast:funding"`
+	PodcastPersons []podcastPerson  `xml:"podcast:person"`
+	Items          []item           `xml:"item"`
 }
 
 type atomLink struct {
 	Href string `xml:"href,attr"`
 	Rel  string `xml:"rel,attr"`
-	Type string `xml:"type,attr"`
+	Type string `xml:"type,attr,omitempty"`
 }
 
 type iTunesOwner struct {
@@ -57,20 +63,26 @@ type iTunesCategory struct {
 
 type item struct {
 	// Title holds the title as well as the ITunesSubTitle.
-	Title             string      `xml:"title"`
-	ITunesTitle       string      `xml:"itunes:title,omitempty"`
-	ITunesAuthor      string      `xml:"itunes:author,omitempty"`
-	ITunesSubTitle    string      `xml:"itunes:subtitle,omitempty"`
-	ITunesSummary     string      `xml:"itunes:summary,omitempty"`
-	ITunesImage       iTunesImage `xml:"itunes:image,omitempty"`
-	Enclosure         enclosure   `xml:"enclosure,omitempty"`
-	ITunesDuration    int         `xml:"itunes:duration"`
-	ITunesSeason      int         `xml:"itunes:season"`
-	ITunesEpisode     int         `xml:"itunes:episode"`
-	ITunesEpisodeType string      `xml:"itunes:episodeType,omitempty"`
-	Guid              guid        `xml:"guid"`
-	PubDate           string      `xml:"pubDate"`
-	ITunesExplicit    string      `xml:"itunes:explicit,omitempty"`
+	Title                      string                      `xml:"title"`
+	ITunesTitle                string                      `xml:"itunes:title,omitempty"`
+	ITunesAuthor               string                      `xml:"itunes:author,omitempty"`
+	ITunesSubTitle             string                      `xml:"itunes:subtitle,omitempty"`
+	ITunesSummary              string                      `xml:"itunes:summary,omitempty"`
+	ITunesImage                iTunesImage                 `xml:"itunes:image,omitempty"`
+	Enclosure                  enclosure                   `xml:"enclosure,omitempty"`
+	ITunesDuration             string                      `xml:"itunes:duration"`
+	ITunesSeason               int                         `xml:"itunes:season"`
+	ITunesEpisode              int                         `xml:"itunes:episode,omitempty"`
+	ITunesEpisodeType          string                      `xml:"itunes:episodeType,omitempty"`
+	Guid                       guid                        `xml:"guid"`
+	PubDate                    string                      `xml:"pubDate"`
+	ITunesExplicit             string                      `xml:"itunes:explicit,omitempty"`
+	PodcastSeason              *podcastSeason              `xml:"podcast:season,omitempty"`
+	PodcastEpisode             int                         `xml:"podcast:episode,omitempty"`
+	PodcastPersons             []podcastPerson             `xml:"podcast:person"`
+	PodcastAlternateEnclosures []podcastAlternateEnclosure `xml:"podcast:alternateEnclosure"`
+	PodcastTranscripts         []podcastTranscript         `xml:"podcast:transcript"`
+	PodcastChapters            *podcastChapters            `xml:"podcast:chapters,omitempty"`
 }
 
 type enclosure struct {
@@ -83,3 +95,52 @@ type guid struct {
 	IsPermaLink bool   `xml:"isPermaLink"`
 	Location    string `xml:",chardata"`
 }
+
+type podcastLocked struct {
+	Owner  string `xml:"owner,attr,omitempty"`
+	Locked string `xml:",chardata"`
+}
+
+type podcastFunding struct {
+	URL   string `xml:"url,attr"`
+	Title string `xml:",chardata"`
+}
+
+type podcastPerson struct {
+	Role  string `xml:"role,attr,omitempty"`
+	Group string `xml:"group,attr,omitempty"`
+	Img   string `xml:"img,attr,omitempty"`
+	Href  string `xml:"href,attr,omitempty"`
+	Name  string `xml:",chardata"`
+}
+
+type podcastSeason struct {
+	Name string `xml:"name,attr,omitempty"`
+	Num  int    `xml:",chardata"`
+}
+
+type podcastAlternateEnclosure struct {
+	Type    string          `xml:"type,attr"`
+	Length  int64           `xml:"length,attr,omitempty"`
+	Bitrate float64         `xml:"bitrate,attr,omitempty"`
+	Title   string          `xml:"title,attr,omitempty"`
+	Lang    string          `xml:"lang,attr,omitempty"`
+	Default bool            `xml:"default,attr,omitempty"`
+	Sources []podcastSource `xml:"podcast:source"`
+}
+
+type podcastSource struct {
+	URI string `xml:"uri,attr"`
+}
+
+type podcastTranscript struct {
+	URL      string `xml:"url,attr"`
+	Type     string `xml:"type,attr"`
+	Language string `xml:"language,attr,omitempty"`
+	Rel      string `xml:"rel,attr,omitempty"`
+}
+
+type podcastChapters struct {
+	URL  string `xml:"url,attr"`
+	Type string `xml:"type,attr"`
+}
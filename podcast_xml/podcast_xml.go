@@ -3,6 +3,8 @@ package podcast_xml
 import (
 	"fmt"
 	"github.com/life-unlimited/podcastination-server/podcasts"
+	"github.com/life-unlimited/podcastination-server/transcripts"
+	"github.com/life-unlimited/podcastination-server/transfer"
 	"sort"
 	"strconv"
 	"strings"
@@ -15,7 +17,22 @@ type CreationDetails struct {
 	Owner            podcasts.Owner
 	Podcast          podcasts.Podcast
 	Seasons          []podcasts.Season
-	Episodes         []podcasts.Episode
+	// Episodes are the episodes of the podcast. Only public ones are included in the feed.
+	Episodes []podcasts.Episode
+	// Chapters are the chapters of the episodes. For episodes that have chapters, the chapters file is referenced.
+	Chapters []podcasts.Chapter
+	// FeedURL is the url of the feed if it differs from the feed link of the podcast, for example for sub-feeds.
+	FeedURL string
+	// Hubs are the urls of WebSub hubs that are notified about changes of the feed.
+	Hubs []string
+}
+
+// SelfURL returns the FeedURL if set and the feed link of the podcast otherwise.
+func (details *CreationDetails) SelfURL() string {
+	if details.FeedURL != "" {
+		return details.FeedURL
+	}
+	return details.Podcast.FeedLink
 }
 
 // nestedCreationDetails represent a nested version of CreationDetails and are easier to use when creating a PodcastXML.
@@ -72,7 +89,12 @@ func (details *CreationDetails) nested() (nestedCreationDetails, error) {
 		Id:    -1,
 		Index: -1,
 	}
+	now := time.Now()
 	for _, episode := range details.Episodes {
+		// Unavailable and embargoed episodes are left out.
+		if !episode.IsPublic(now) {
+			continue
+		}
 		// Assure existing season id.
 		if episode.SeasonId <= 0 {
 			return nestedCreationDetails{}, fmt.Errorf("invalid season id (%d) for episode %d",
@@ -114,6 +136,10 @@ func (details *CreationDetails) nested() (nestedCreationDetails, error) {
 		sort.SliceStable(season.Episodes, func(i, j int) bool {
 			vi := season.Episodes[i].Num
 			vj := season.Episodes[j].Num
+			// Unnumbered episodes are ordered by date.
+			if vi == vj {
+				return season.Episodes[i].Date.Before(season.Episodes[j].Date)
+			}
 			return vi < vj
 		})
 	}
@@ -121,6 +147,47 @@ func (details *CreationDetails) nested() (nestedCreationDetails, error) {
 	return nested, nil
 }
 
+// FeedEpisode is an episode as included in feeds together with its season.
+type FeedEpisode struct {
+	Episode podcasts.Episode
+	Season  podcasts.Season
+}
+
+// FeedEpisodes validates the CreationDetails and returns the public episodes in the order they appear in the
+// PodcastXML. This allows rendering the same episodes in other feed formats.
+func (details *CreationDetails) FeedEpisodes() ([]FeedEpisode, error) {
+	nested, err := details.nested()
+	if err != nil {
+		return nil, fmt.Errorf("invalid creation details: %v", err)
+	}
+	episodes := make([]FeedEpisode, 0)
+	for _, season := range nested.Seasons {
+		for _, episode := range season.Episodes {
+			episodes = append(episodes, FeedEpisode{
+				Episode: episode,
+				Season:  season.Details,
+			})
+		}
+	}
+	return episodes, nil
+}
+
+// EpisodeTitle returns the title of the given episode as used in feeds which includes the subtitle if existing.
+func EpisodeTitle(episode podcasts.Episode) string {
+	if episode.Subtitle != "" {
+		return fmt.Sprintf("%s - %s", episode.Title, episode.Subtitle)
+	}
+	return episode.Title
+}
+
+// EnclosureType returns the MIME type of the audio file of the given episode. Episodes without one are mp3.
+func EnclosureType(episode podcasts.Episode) string {
+	if episode.MIMEType == "" {
+		return "audio/mpeg"
+	}
+	return episode.MIMEType
+}
+
 // createEmptyPodcastXML creates a new PodcastXML filled with default values.
 func createEmptyPodcastXML() *PodcastXML {
 	return &PodcastXML{
@@ -129,6 +196,7 @@ func createEmptyPodcastXML() *PodcastXML {
 		XmlnsITunes:  "http://www.itunes.com/dtds/podcast-1.0.dtd",
 		XmlnsGPlay:   "http://www.google.com/schemas/play-podcasts/1.0",
 		XmlnsMedia:   "http://www.rssboard.org/media-rss",
+		XmlnsPodcast: "https://podcastindex.org/namespace/1.0",
 		Version:      "2.0",
 		Channel:      channel{},
 	}
@@ -152,11 +220,6 @@ func (xml *PodcastXML) setPodcastDetails(podcast podcasts.Podcast, staticContent
 	c.Title = podcast.Title
 	c.Link = podcast.Link
 	c.Language = string(podcast.Language)
-	c.AtomLink = atomLink{
-		Href: podcast.FeedLink,
-		Rel:  "self",
-		Type: "application/rss+xml",
-	}
 	c.ITunesSubtitle = podcast.Subtitle
 	c.ITunesSummary = podcast.Description
 	c.ITunesKeywords = strings.Join(podcast.Keywords, ",")
@@ -169,18 +232,132 @@ func (xml *PodcastXML) setPodcastDetails(podcast podcasts.Podcast, staticContent
 			Href: fmt.Sprintf("%s/%s", staticContentURL, podcast.ImageLocation),
 		}
 	}
-	c.ITunesCategory = iTunesCategory{
-		Text:          "Religion & Spirituality",
-		SubCategories: []iTunesCategory{{Text: "Christianity"}},
+	c.ITunesCategory = make([]iTunesCategory, 0)
+	for _, category := range podcast.CategoriesOrDefault() {
+		subCategories := make([]iTunesCategory, 0, len(category.Subcategories))
+		for _, subcategory := range category.Subcategories {
+			subCategories = append(subCategories, iTunesCategory{Text: subcategory})
+		}
+		c.ITunesCategory = append(c.ITunesCategory, iTunesCategory{
+			Text:          category.Name,
+			SubCategories: subCategories,
+		})
+	}
+	c.ITunesExplicit = strconv.FormatBool(podcast.Explicit)
+	xml.Channel = c
+}
+
+// setAtomLinks sets the self link with the given feed url as well as links to the given WebSub hubs for a PodcastXML.
+func (xml *PodcastXML) setAtomLinks(feedURL string, hubs []string) {
+	xml.Channel.AtomLinks = []atomLink{
+		{
+			Href: feedURL,
+			Rel:  "self",
+			Type: "application/rss+xml",
+		},
+	}
+	for _, hub := range hubs {
+		xml.Channel.AtomLinks = append(xml.Channel.AtomLinks, atomLink{
+			Href: hub,
+			Rel:  "hub",
+		})
+	}
+}
+
+// setPodcastNamespaceDetails sets the channel details of the podcast namespace for a PodcastXML.
+func (xml *PodcastXML) setPodcastNamespaceDetails(podcast podcasts.Podcast, owner podcasts.Owner) {
+	c := xml.Channel
+	c.PodcastGUID = podcast.GUIDOrDefault()
+	c.PodcastLocked = podcastLocked{
+		Owner:  owner.Email,
+		Locked: "no",
+	}
+	if podcast.Locked {
+		c.PodcastLocked.Locked = "yes"
 	}
+	for _, funding := range podcast.Funding {
+		c.PodcastFunding = append(c.PodcastFunding, podcastFunding{
+			URL:   funding.URL,
+			Title: funding.Title,
+		})
+	}
+	c.PodcastPersons = podcastPersons(podcast.Persons)
 	xml.Channel = c
 }
 
-// setItems sets the episodes and seasons for a PodcastXML.
-func (xml *PodcastXML) setItems(seasons []nestedSeasonDetails, staticContentURL string) {
+// podcastPersons converts the given persons for the podcast namespace.
+func podcastPersons(persons []podcasts.Person) []podcastPerson {
+	var converted []podcastPerson
+	for _, person := range persons {
+		converted = append(converted, podcastPerson{
+			Role:  person.Role,
+			Group: person.Group,
+			Img:   person.Img,
+			Href:  person.Href,
+			Name:  person.Name,
+		})
+	}
+	return converted
+}
+
+// podcastAlternateEnclosures converts the given alternate enclosures for the podcast namespace. Sources without
+// scheme are considered to be relative to the static content url.
+func podcastAlternateEnclosures(enclosures []podcasts.AlternateEnclosure,
+	staticContentURL string) []podcastAlternateEnclosure {
+	var converted []podcastAlternateEnclosure
+	for _, e := range enclosures {
+		alternateEnclosure := podcastAlternateEnclosure{
+			Type:    e.Type,
+			Length:  e.Length,
+			Bitrate: e.Bitrate,
+			Title:   e.Title,
+			Lang:    e.Lang,
+			Default: e.Default,
+		}
+		for _, source := range e.Sources {
+			if !strings.Contains(source, "://") {
+				source = fmt.Sprintf("%s/%s", staticContentURL, source)
+			}
+			alternateEnclosure.Sources = append(alternateEnclosure.Sources, podcastSource{URI: source})
+		}
+		converted = append(converted, alternateEnclosure)
+	}
+	return converted
+}
+
+// podcastTranscripts converts the given transcripts for the podcast namespace. Formats with timing information are
+// marked as captions.
+func podcastTranscripts(episodeTranscripts []podcasts.Transcript, staticContentURL string) []podcastTranscript {
+	var converted []podcastTranscript
+	for _, transcript := range episodeTranscripts {
+		t := podcastTranscript{
+			URL:      fmt.Sprintf("%s/%s", staticContentURL, transcript.Location),
+			Type:     transcript.Type,
+			Language: transcript.Language,
+		}
+		if transcripts.IsCaptions(transcript.Type) {
+			t.Rel = "captions"
+		}
+		converted = append(converted, t)
+	}
+	return converted
+}
+
+// setItems sets the episodes and seasons for a PodcastXML. The given chapters are used for referencing chapters files.
+func (xml *PodcastXML) setItems(seasons []nestedSeasonDetails, chapters []podcasts.Chapter, staticContentURL string) {
+	episodesWithChapters := make(map[int]struct{})
+	for _, chapter := range chapters {
+		episodesWithChapters[chapter.EpisodeId] = struct{}{}
+	}
 	for _, season := range seasons {
 		for _, episode := range season.Episodes {
 			xml.appendEpisode(episode, season.Details, staticContentURL)
+			if _, ok := episodesWithChapters[episode.Id]; ok {
+				xml.Channel.Items[len(xml.Channel.Items)-1].PodcastChapters = &podcastChapters{
+					URL:  fmt.Sprintf("%s/%s", staticContentURL, transfer.ChaptersLocation(episode.MP3Location)),
+					Type: ChaptersJSONType,
+				}
+			}
 		}
 	}
 }
@@ -189,19 +366,15 @@ func (xml *PodcastXML) setItems(seasons []nestedSeasonDetails, staticContentURL
 //
 // Warning: Always add episodes in the correct order!
 func (xml *PodcastXML) appendEpisode(episode podcasts.Episode, season podcasts.Season, staticContentURL string) {
+	season = episode.FeedSeason(season)
 	var iTunesImageVal iTunesImage
 	if episode.ImageLocation != "" {
 		iTunesImageVal = iTunesImage{
 			Href: fmt.Sprintf("%s/%s", staticContentURL, episode.ImageLocation),
 		}
 	}
-	episodeTitle := episode.Title
-	// Append subtitle to title if existing.
-	if episode.Subtitle != "" {
-		episodeTitle = fmt.Sprintf("%s - %s", episode.Title, episode.Subtitle)
-	}
 	e := item{
-		Title:          episodeTitle,
+		Title:          EpisodeTitle(episode),
 		ITunesTitle:    episode.Title,
 		ITunesAuthor:   episode.Author,
 		ITunesSubTitle: episode.Subtitle,
@@ -209,19 +382,27 @@ func (xml *PodcastXML) appendEpisode(episode podcasts.Episode, season podcasts.S
 		ITunesImage:    iTunesImageVal,
 		Enclosure: enclosure{
 			URL:    fmt.Sprintf("%s/%s", staticContentURL, episode.MP3Location),
-			Length: strconv.Itoa(episode.MP3Length),
-			Type:   "audio/mpeg",
+			Length: strconv.FormatInt(episode.FileSize, 10),
+			Type:   EnclosureType(episode),
 		},
-		ITunesDuration:    episode.MP3Length,
+		ITunesDuration:    formatDuration(episode.MP3Length),
 		ITunesSeason:      season.Num,
 		ITunesEpisode:     episode.Num,
-		ITunesEpisodeType: "full",
+		ITunesEpisodeType: string(episode.TypeOrDefault()),
 		Guid: guid{
 			IsPermaLink: false,
-			Location:    fmt.Sprintf("%s/%s", staticContentURL, episode.MP3Location),
+			Location:    episode.FeedGUID(),
 		},
 		PubDate:        episode.Date.Format(time.RFC1123Z),
-		ITunesExplicit: "NO", // I guess that this will always be no.
+		ITunesExplicit: strconv.FormatBool(episode.Explicit),
+		PodcastSeason: &podcastSeason{
+			Name: season.Title,
+			Num:  season.Num,
+		},
+		PodcastEpisode:             episode.Num,
+		PodcastPersons:             podcastPersons(episode.Persons),
+		PodcastAlternateEnclosures: podcastAlternateEnclosures(episode.AlternateEnclosures, staticContentURL),
+		PodcastTranscripts:         podcastTranscripts(episode.Transcripts, staticContentURL),
 	}
 	xml.Channel.Items = append(xml.Channel.Items, e)
 }
@@ -235,6 +416,13 @@ func GeneratePodcastXML(details CreationDetails) (PodcastXML, error) {
 	xml := createEmptyPodcastXML()
 	xml.setOwner(nested.Owner)
 	xml.setPodcastDetails(nested.Podcast, details.StaticContentURL)
-	xml.setItems(nested.Seasons, details.StaticContentURL)
+	xml.setAtomLinks(details.SelfURL(), details.Hubs)
+	xml.setPodcastNamespaceDetails(nested.Podcast, nested.Owner)
+	xml.setItems(nested.Seasons, details.Chapters, details.StaticContentURL)
 	return *xml, nil
 }
+
+// formatDuration formats the given seconds as HH:MM:SS.
+func formatDuration(seconds int) string {
+	return fmt.Sprintf("%02d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
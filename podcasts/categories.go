@@ -0,0 +1,89 @@
+package podcasts
+
+import "fmt"
+
+// iTunesCategories are the official Apple Podcasts categories with their subcategories.
+var iTunesCategories = map[string][]string{
+	"Arts":       {"Books", "Design", "Fashion & Beauty", "Food", "Performing Arts", "Visual Arts"},
+	"Business":   {"Careers", "Entrepreneurship", "Investing", "Management", "Marketing", "Non-Profit"},
+	"Comedy":     {"Comedy Interviews", "Improv", "Stand-Up"},
+	"Education":  {"Courses", "How To", "Language Learning", "Self-Improvement"},
+	"Fiction":    {"Comedy Fiction", "Drama", "Science Fiction"},
+	"Government": {},
+	"History":    {},
+	"Health & Fitness": {"Alternative Health", "Fitness", "Medicine", "Mental Health", "Nutrition",
+		"Sexuality"},
+	"Kids & Family": {"Education for Kids", "Parenting", "Pets & Animals", "Stories for Kids"},
+	"Leisure": {"Animation & Manga", "Automotive", "Aviation", "Crafts", "Games", "Hobbies", "Home & Garden",
+		"Video Games"},
+	"Music": {"Music Commentary", "Music History", "Music Interviews"},
+	"News": {"Business News", "Daily News", "Entertainment News", "News Commentary", "Politics", "Sports News",
+		"Tech News"},
+	"Religion & Spirituality": {"Buddhism", "Christianity", "Hinduism", "Islam", "Judaism", "Religion",
+		"Spirituality"},
+	"Science": {"Astronomy", "Chemistry", "Earth Sciences", "Life Sciences", "Mathematics", "Natural Sciences",
+		"Nature", "Physics", "Social Sciences"},
+	"Society & Culture": {"Documentary", "Personal Journals", "Philosophy", "Places & Travel", "Relationships"},
+	"Sports": {"Baseball", "Basketball", "Cricket", "Fantasy Sports", "Football", "Golf", "Hockey", "Rugby",
+		"Running", "Soccer", "Swimming", "Tennis", "Volleyball", "Wilderness", "Wrestling"},
+	"Technology": {},
+	"True Crime": {},
+	"TV & Film":  {"After Shows", "Film History", "Film Interviews", "Film Reviews", "TV Reviews"},
+}
+
+// DefaultCategories are used for podcasts without categories.
+var DefaultCategories = []Category{
+	{Name: "Religion & Spirituality", Subcategories: []string{"Christianity"}},
+}
+
+// Category is an iTunes category of a podcast.
+type Category struct {
+	// Name is the name of the category as defined by Apple.
+	Name string `json:"name"`
+	// Subcategories must belong to the category.
+	Subcategories []string `json:"subcategories"`
+}
+
+// IsValid checks if the Category and its subcategories are part of the official Apple Podcasts categories.
+func (c *Category) IsValid() (bool, error) {
+	subcategories, ok := iTunesCategories[c.Name]
+	if !ok {
+		return false, fmt.Errorf("unknown category %q", c.Name)
+	}
+	seen := make(map[string]struct{})
+	for _, subcategory := range c.Subcategories {
+		if !containsString(subcategories, subcategory) {
+			return false, fmt.Errorf("unknown subcategory %q of category %q", subcategory, c.Name)
+		}
+		if _, ok := seen[subcategory]; ok {
+			return false, fmt.Errorf("duplicate subcategory %q of category %q", subcategory, c.Name)
+		}
+		seen[subcategory] = struct{}{}
+	}
+	return true, nil
+}
+
+// validateCategories checks if all given categories are valid and each category is only provided once.
+func validateCategories(categories []Category) (bool, error) {
+	seen := make(map[string]struct{})
+	for _, category := range categories {
+		if _, err := category.IsValid(); err != nil {
+			return false, err
+		}
+		if _, ok := seen[category.Name]; ok {
+			return false, fmt.Errorf("duplicate category %q", category.Name)
+		}
+		seen[category.Name] = struct{}{}
+	}
+	return true, nil
+}
+
+// containsString checks if the given string is part of the given slice.
+func containsString(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
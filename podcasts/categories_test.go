@@ -0,0 +1,30 @@
+package podcasts
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestValidateCategories(t *testing.T) {
+	valid := []Category{
+		{Name: "Religion & Spirituality", Subcategories: []string{"Christianity", "Spirituality"}},
+		{Name: "History"},
+	}
+	_, err := validateCategories(valid)
+	assert.Nil(t, err, "valid categories should pass")
+	_, err = validateCategories([]Category{{Name: "Religion"}})
+	assert.NotNil(t, err, "unknown category should fail")
+	_, err = validateCategories([]Category{{Name: "Arts", Subcategories: []string{"Christianity"}}})
+	assert.NotNil(t, err, "subcategory of other category should fail")
+	_, err = validateCategories([]Category{{Name: "Arts", Subcategories: []string{"Design", "Design"}}})
+	assert.NotNil(t, err, "duplicate subcategory should fail")
+	_, err = validateCategories(append(valid, Category{Name: "History"}))
+	assert.NotNil(t, err, "duplicate category should fail")
+}
+
+func TestCategoriesOrDefault(t *testing.T) {
+	podcast := Podcast{}
+	assert.Equal(t, DefaultCategories, podcast.CategoriesOrDefault(), "default should be used without categories")
+	podcast.Categories = []Category{{Name: "Education"}}
+	assert.Equal(t, podcast.Categories, podcast.CategoriesOrDefault(), "categories should be used if set")
+}
@@ -0,0 +1,42 @@
+package podcasts
+
+import "fmt"
+
+// Chapter is a chapter of an episode.
+type Chapter struct {
+	Id        int `json:"id"`
+	EpisodeId int `json:"episode_id"`
+	// StartTime is the start of the chapter in seconds from the beginning of the episode.
+	StartTime float64 `json:"start_time"`
+	Title     string  `json:"title"`
+}
+
+// IsValid checks if the Chapter has all needed properties in order to be stored.
+func (c *Chapter) IsValid() (bool, error) {
+	if len(c.Title) == 0 {
+		return false, fmt.Errorf("no chapter title provided")
+	}
+	if c.StartTime < 0 {
+		return false, fmt.Errorf("negative start time for chapter %s", c.Title)
+	}
+	return true, nil
+}
+
+// ValidateChapters checks if the given chapters are valid and have distinct start times within the given length in
+// seconds.
+func ValidateChapters(chapters []Chapter, length float64) (bool, error) {
+	startTimes := make(map[float64]struct{})
+	for _, chapter := range chapters {
+		if _, err := chapter.IsValid(); err != nil {
+			return false, err
+		}
+		if chapter.StartTime >= length {
+			return false, fmt.Errorf("start time of chapter %s exceeds episode length", chapter.Title)
+		}
+		if _, ok := startTimes[chapter.StartTime]; ok {
+			return false, fmt.Errorf("multiple chapters start at %v", chapter.StartTime)
+		}
+		startTimes[chapter.StartTime] = struct{}{}
+	}
+	return true, nil
+}
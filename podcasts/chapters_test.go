@@ -0,0 +1,18 @@
+package podcasts
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestValidateChapters(t *testing.T) {
+	valid := []Chapter{{StartTime: 0, Title: "Worship"}, {StartTime: 930.5, Title: "Sermon"}}
+	_, err := ValidateChapters(valid, 3600)
+	assert.Nil(t, err, "valid chapters should pass")
+	_, err = ValidateChapters([]Chapter{{StartTime: 0}}, 3600)
+	assert.NotNil(t, err, "missing title should fail")
+	_, err = ValidateChapters([]Chapter{{StartTime: 3600, Title: "Late"}}, 3600)
+	assert.NotNil(t, err, "start time after end should fail")
+	_, err = ValidateChapters(append(valid, Chapter{StartTime: 930.5, Title: "Again"}), 3600)
+	assert.NotNil(t, err, "duplicate start time should fail")
+}
@@ -1,6 +1,9 @@
 package podcasts
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 type Episode struct {
 	Id            int       `json:"id"`
@@ -13,8 +16,169 @@ type Episode struct {
 	PDFLocation   string    `json:"pdf_location"`
 	MP3Location   string    `json:"mp3_location"`
 	MP3Length     int       `json:"mp3_length"`
+	MIMEType      string    `json:"mime_type"`
+	FileSize      int64     `json:"file_size"`
 	SeasonId      int       `json:"season_id"`
 	Num           int       `json:"num"`
 	YouTubeURL    string    `json:"yt_url"`
 	IsAvailable   bool      `json:"is_available"`
+	// Type is the episode type. If empty, EpisodeTypeFull is assumed. Trailers and bonus episodes may be unnumbered
+	// with a Num of 0.
+	Type EpisodeType `json:"episode_type"`
+	// SeasonNum optionally overrides the number of the season in feeds and tags if not zero, for example for a trailer
+	// of the next season that is published in the current one.
+	SeasonNum int `json:"season_num"`
+	// SeasonTitle optionally overrides the title of the season in feeds and tags if not empty.
+	SeasonTitle string `json:"season_title"`
+	// PublishAt is the optional time the episode is embargoed until. Before, it is hidden from feeds and listings.
+	PublishAt *time.Time `json:"publish_at"`
+	// Persons are the persons involved in the episode in addition to the ones of the podcast.
+	Persons             []Person             `json:"persons"`
+	AlternateEnclosures []AlternateEnclosure `json:"alternate_enclosures"`
+	Transcripts         []Transcript         `json:"transcripts"`
+	// Explicit marks the episode as containing explicit content.
+	Explicit bool `json:"explicit"`
+	// GUID is the persistent guid of the episode which is generated when the episode is created.
+	GUID string `json:"guid"`
+	// LegacyGUID is the guid of episodes that existed before GUID was introduced, which is the static url of the mp3
+	// file at that time.
+	LegacyGUID string `json:"legacy_guid,omitempty"`
+}
+
+// FeedGUID returns the guid to use for the Episode in feeds. This is the LegacyGUID if set, so that subscribers do
+// not see existing episodes as new ones, and the GUID otherwise.
+func (e *Episode) FeedGUID() string {
+	if e.LegacyGUID != "" {
+		return e.LegacyGUID
+	}
+	return e.GUID
+}
+
+// IsPublished checks if the Episode is not embargoed anymore at the given time.
+func (e *Episode) IsPublished(now time.Time) bool {
+	return e.PublishAt == nil || !e.PublishAt.After(now)
+}
+
+// EpisodeStatus is the visibility status of an episode.
+type EpisodeStatus string
+
+const (
+	// EpisodeStatusUnavailable means that the files of the episode are not available, for example after a failed
+	// import.
+	EpisodeStatusUnavailable EpisodeStatus = "unavailable"
+	// EpisodeStatusEmbargoed means that the episode is available but not published yet.
+	EpisodeStatusEmbargoed EpisodeStatus = "embargoed"
+	// EpisodeStatusPublic means that the episode is available and published.
+	EpisodeStatusPublic EpisodeStatus = "public"
+)
+
+// Status returns the EpisodeStatus of the Episode at the given time.
+func (e *Episode) Status(now time.Time) EpisodeStatus {
+	if !e.IsAvailable {
+		return EpisodeStatusUnavailable
+	}
+	if !e.IsPublished(now) {
+		return EpisodeStatusEmbargoed
+	}
+	return EpisodeStatusPublic
+}
+
+// IsPublic checks if the Episode is available and published at the given time, so that it can be part of feeds and
+// public listings.
+func (e *Episode) IsPublic(now time.Time) bool {
+	return e.Status(now) == EpisodeStatusPublic
+}
+
+// PublicEpisodes returns the given episodes without the ones that are not public at the given time.
+func PublicEpisodes(episodes []Episode, now time.Time) []Episode {
+	public := make([]Episode, 0, len(episodes))
+	for _, episode := range episodes {
+		if episode.IsPublic(now) {
+			public = append(public, episode)
+		}
+	}
+	return public
+}
+
+// EpisodeType is the type of an episode as defined by iTunes.
+type EpisodeType string
+
+const (
+	// EpisodeTypeFull is a regular episode.
+	EpisodeTypeFull EpisodeType = "full"
+	// EpisodeTypeTrailer is a short preview of a season or the podcast.
+	EpisodeTypeTrailer EpisodeType = "trailer"
+	// EpisodeTypeBonus is extra content for a season or the podcast like a Q&A.
+	EpisodeTypeBonus EpisodeType = "bonus"
+)
+
+// IsValid checks if the EpisodeType is one of the known types or empty.
+func (t EpisodeType) IsValid() (bool, error) {
+	switch t {
+	case "", EpisodeTypeFull, EpisodeTypeTrailer, EpisodeTypeBonus:
+		return true, nil
+	}
+	return false, fmt.Errorf("unknown episode type %q", t)
+}
+
+// TypeOrDefault returns the Type or EpisodeTypeFull if not set.
+func (e *Episode) TypeOrDefault() EpisodeType {
+	if e.Type != "" {
+		return e.Type
+	}
+	return EpisodeTypeFull
+}
+
+// FeedSeason returns the given season of the Episode with the SeasonNum and SeasonTitle overrides applied.
+func (e *Episode) FeedSeason(season Season) Season {
+	if e.SeasonNum != 0 {
+		season.Num = e.SeasonNum
+	}
+	if e.SeasonTitle != "" {
+		season.Title = e.SeasonTitle
+	}
+	return season
+}
+
+// MayBeUnnumbered checks if episodes of the EpisodeType may be left out of the numbering of their season which is the
+// case for trailers and bonus episodes.
+func (t EpisodeType) MayBeUnnumbered() bool {
+	return t == EpisodeTypeTrailer || t == EpisodeTypeBonus
+}
+
+// Transcript is a transcript file of an episode.
+type Transcript struct {
+	Location string `json:"location"`
+	// Type is the MIME type of the transcript.
+	Type string `json:"type"`
+	// Language is the language of the transcript. If empty, the language of the podcast is assumed.
+	Language string `json:"language"`
+}
+
+// IsValid checks if the Episode has all needed properties in order to be stored.
+func (e *Episode) IsValid() (bool, error) {
+	if len(e.Title) == 0 {
+		return false, fmt.Errorf("no title provided")
+	}
+	if e.Date.IsZero() {
+		return false, fmt.Errorf("no date provided")
+	}
+	if e.SeasonId <= 0 {
+		return false, fmt.Errorf("no season provided")
+	}
+	if _, err := e.Type.IsValid(); err != nil {
+		return false, err
+	}
+	if e.Num < 0 || (e.Num == 0 && !e.Type.MayBeUnnumbered()) {
+		return false, fmt.Errorf("no episode number provided")
+	}
+	if e.SeasonNum < 0 {
+		return false, fmt.Errorf("season number override must not be negative")
+	}
+	for _, enclosure := range e.AlternateEnclosures {
+		if _, err := enclosure.IsValid(); err != nil {
+			return false, err
+		}
+	}
+	return validatePersons(e.Persons)
 }
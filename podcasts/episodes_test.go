@@ -0,0 +1,65 @@
+package podcasts
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestEpisodeNumbering(t *testing.T) {
+	episode := Episode{Title: "Trailer", Date: time.Now(), SeasonId: 1}
+	_, err := episode.IsValid()
+	assert.NotNil(t, err, "full episode without number should fail")
+	episode.Type = EpisodeTypeTrailer
+	_, err = episode.IsValid()
+	assert.Nil(t, err, "trailer without number should pass")
+	episode.Type = "teaser"
+	_, err = episode.IsValid()
+	assert.NotNil(t, err, "unknown type should fail")
+	episode.Type = ""
+	assert.Equal(t, EpisodeTypeFull, episode.TypeOrDefault(), "full should be the default type")
+}
+
+func TestEpisodeIsPublished(t *testing.T) {
+	now := time.Now()
+	episode := Episode{}
+	assert.True(t, episode.IsPublished(now), "episode without publish date should be published")
+	publishAt := now.Add(time.Hour)
+	episode.PublishAt = &publishAt
+	assert.False(t, episode.IsPublished(now), "embargoed episode should not be published")
+	assert.True(t, episode.IsPublished(publishAt), "episode should be published when embargo lifts")
+}
+
+func TestEpisodeStatus(t *testing.T) {
+	now := time.Now()
+	publishAt := now.Add(time.Hour)
+	episodes := []Episode{
+		{Id: 1, IsAvailable: false},
+		{Id: 2, IsAvailable: true, PublishAt: &publishAt},
+		{Id: 3, IsAvailable: true},
+	}
+	assert.Equal(t, EpisodeStatusUnavailable, episodes[0].Status(now), "status of unavailable episode should match")
+	assert.Equal(t, EpisodeStatusEmbargoed, episodes[1].Status(now), "status of embargoed episode should match")
+	assert.Equal(t, EpisodeStatusPublic, episodes[2].Status(now), "status of public episode should match")
+	assert.Equal(t, []Episode{episodes[2]}, PublicEpisodes(episodes, now), "only public episodes should be returned")
+}
+
+func TestEpisodeFeedGUID(t *testing.T) {
+	episode := Episode{GUID: "1d4e8a90-3b0c-4f5e-9a57-6f0f3c2b1a77"}
+	assert.Equal(t, episode.GUID, episode.FeedGUID(), "guid should be used")
+	episode.LegacyGUID = "https://example.com/static/1/2/episode.mp3"
+	assert.Equal(t, episode.LegacyGUID, episode.FeedGUID(), "legacy guid should be preferred")
+}
+
+func TestEpisodeFeedSeason(t *testing.T) {
+	season := Season{Id: 1, Title: "Series", Num: 2}
+	episode := Episode{Title: "Trailer", Date: time.Now(), SeasonId: 1, Num: 1}
+	assert.Equal(t, season, episode.FeedSeason(season), "season should be kept without overrides")
+	episode.SeasonNum = 3
+	episode.SeasonTitle = "Next series"
+	assert.Equal(t, Season{Id: 1, Title: "Next series", Num: 3}, episode.FeedSeason(season),
+		"overrides should be applied")
+	episode.SeasonNum = -1
+	_, err := episode.IsValid()
+	assert.NotNil(t, err, "negative season number should fail")
+}
@@ -0,0 +1,94 @@
+package podcasts
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"strings"
+)
+
+// podcastGUIDNamespace is the namespace for generating podcast guids as defined by the podcast namespace.
+var podcastGUIDNamespace = uuid.MustParse("ead4c236-bf58-58c6-a2c6-a6b28d128cb6")
+
+// GUIDForFeedLink generates the podcast guid for the given feed link which is a UUIDv5 of the link without scheme and
+// trailing slashes.
+func GUIDForFeedLink(feedLink string) string {
+	link := feedLink
+	if i := strings.Index(link, "://"); i != -1 {
+		link = link[i+3:]
+	}
+	link = strings.TrimRight(link, "/")
+	return uuid.NewSHA1(podcastGUIDNamespace, []byte(link)).String()
+}
+
+// Funding is a link for supporting a podcast.
+type Funding struct {
+	URL string `json:"url"`
+	// Title is the text of the link.
+	Title string `json:"title"`
+}
+
+// IsValid checks if the Funding has all needed properties.
+func (f *Funding) IsValid() (bool, error) {
+	if len(f.URL) == 0 {
+		return false, fmt.Errorf("no funding url provided")
+	}
+	return true, nil
+}
+
+// Person is a person involved in a podcast or episode like a host or guest.
+type Person struct {
+	Name string `json:"name"`
+	// Role is for example host or guest. See the podcast taxonomy for possible values.
+	Role string `json:"role"`
+	// Group is the group of the role, for example cast.
+	Group string `json:"group"`
+	// Img is the url of a picture of the person.
+	Img string `json:"img"`
+	// Href is the url of a website of the person.
+	Href string `json:"href"`
+}
+
+// IsValid checks if the Person has all needed properties.
+func (p *Person) IsValid() (bool, error) {
+	if len(p.Name) == 0 {
+		return false, fmt.Errorf("no person name provided")
+	}
+	return true, nil
+}
+
+// AlternateEnclosure is an alternative media file for an episode, for example in a different quality or format.
+type AlternateEnclosure struct {
+	// Type is the MIME type of the media.
+	Type string `json:"type"`
+	// Length is the file size in bytes.
+	Length int64 `json:"length"`
+	// Bitrate is the average bitrate in bit/s.
+	Bitrate float64 `json:"bitrate"`
+	Title   string  `json:"title"`
+	Lang    string  `json:"lang"`
+	// Default marks the enclosure as the same as the main one.
+	Default bool `json:"default"`
+	// Sources are the urls of the media. Locations relative to the static content url are allowed as well.
+	Sources []string `json:"sources"`
+}
+
+// IsValid checks if the AlternateEnclosure has all needed properties.
+func (e *AlternateEnclosure) IsValid() (bool, error) {
+	if len(e.Type) == 0 {
+		return false, fmt.Errorf("no alternate enclosure type provided")
+	}
+	if len(e.Sources) == 0 {
+		return false, fmt.Errorf("no alternate enclosure source provided")
+	}
+	return true, nil
+}
+
+// validatePersons checks if all given persons are valid.
+func validatePersons(persons []Person) (bool, error) {
+	for _, person := range persons {
+		if _, err := person.IsValid(); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,14 @@
+package podcasts
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGUIDForFeedLink(t *testing.T) {
+	// Example from the podcast namespace specification.
+	expected := "917393e3-1b1e-5cef-ace4-edaa54e1f810"
+	assert.Equal(t, expected, GUIDForFeedLink("https://mp3s.nashownotes.com/pc20rss.xml"), "guid should match")
+	assert.Equal(t, expected, GUIDForFeedLink("http://mp3s.nashownotes.com/pc20rss.xml/"),
+		"scheme and trailing slashes should be ignored")
+}
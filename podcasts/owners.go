@@ -1,8 +1,21 @@
 package podcasts
 
+import "fmt"
+
 type Owner struct {
 	Id        int    `json:"id"`
 	Name      string `json:"name"`
 	Email     string `json:"email"`
 	Copyright string `json:"copyright"`
 }
+
+// IsValid checks if the Owner has all needed properties in order to be stored.
+func (o *Owner) IsValid() (bool, error) {
+	if len(o.Name) == 0 {
+		return false, fmt.Errorf("no name provided")
+	}
+	if len(o.Email) == 0 {
+		return false, fmt.Errorf("no email provided")
+	}
+	return true, nil
+}
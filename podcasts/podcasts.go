@@ -1,5 +1,7 @@
 package podcasts
 
+import "fmt"
+
 type Podcast struct {
 	Id            int         `json:"id"`
 	Title         string      `json:"title"`
@@ -13,6 +15,32 @@ type Podcast struct {
 	ImageLocation string      `json:"image_location"`
 	PodcastType   PodcastType `json:"podcast_type"`
 	Key           string      `json:"key"`
+	// GUID is the podcast guid. If empty, it is generated from the FeedLink.
+	GUID string `json:"guid"`
+	// Locked tells other platforms that they are not allowed to import the podcast.
+	Locked  bool      `json:"locked"`
+	Funding []Funding `json:"funding"`
+	Persons []Person  `json:"persons"`
+	// Categories are the iTunes categories. If empty, DefaultCategories are used.
+	Categories []Category `json:"categories"`
+	// Explicit marks the podcast as containing explicit content.
+	Explicit bool `json:"explicit"`
+}
+
+// GUIDOrDefault returns the GUID or the one generated from the FeedLink if not set.
+func (p *Podcast) GUIDOrDefault() string {
+	if p.GUID != "" {
+		return p.GUID
+	}
+	return GUIDForFeedLink(p.FeedLink)
+}
+
+// CategoriesOrDefault returns the Categories or DefaultCategories if not set.
+func (p *Podcast) CategoriesOrDefault() []Category {
+	if len(p.Categories) != 0 {
+		return p.Categories
+	}
+	return DefaultCategories
 }
 
 type PodcastType string
@@ -29,3 +57,28 @@ const (
 	LangDE Language = "de-de"
 	LangEN Language = "en-us"
 )
+
+// IsValid checks if the Podcast has all needed properties in order to be stored.
+func (p *Podcast) IsValid() (bool, error) {
+	if len(p.Title) == 0 {
+		return false, fmt.Errorf("no title provided")
+	}
+	if p.OwnerId <= 0 {
+		return false, fmt.Errorf("no owner provided")
+	}
+	if len(p.Key) == 0 {
+		return false, fmt.Errorf("no key provided")
+	}
+	if len(p.FeedLink) == 0 {
+		return false, fmt.Errorf("no feed link provided")
+	}
+	for _, funding := range p.Funding {
+		if _, err := funding.IsValid(); err != nil {
+			return false, err
+		}
+	}
+	if _, err := validateCategories(p.Categories); err != nil {
+		return false, err
+	}
+	return validatePersons(p.Persons)
+}
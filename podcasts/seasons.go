@@ -1,5 +1,7 @@
 package podcasts
 
+import "fmt"
+
 type Season struct {
 	Id            int    `json:"id"`
 	Title         string `json:"title"`
@@ -10,3 +12,17 @@ type Season struct {
 	Num           int    `json:"num"`
 	Key           string `json:"key"`
 }
+
+// IsValid checks if the Season has all needed properties in order to be stored.
+func (s *Season) IsValid() (bool, error) {
+	if len(s.Title) == 0 {
+		return false, fmt.Errorf("no title provided")
+	}
+	if s.PodcastId <= 0 {
+		return false, fmt.Errorf("no podcast provided")
+	}
+	if len(s.Key) == 0 {
+		return false, fmt.Errorf("no key provided")
+	}
+	return true, nil
+}
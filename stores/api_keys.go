@@ -0,0 +1,95 @@
+package stores
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/life-unlimited/podcastination-server/auth"
+	"time"
+)
+
+const apiKeySelect = "select id, name, scope, created from api_keys"
+
+type APIKeyStore struct {
+	DB *sql.DB
+}
+
+// All retrieves all api keys from the store.
+func (s *APIKeyStore) All() ([]auth.APIKey, error) {
+	rows, err := s.DB.Query(fmt.Sprintf("%s order by id;", apiKeySelect))
+	if err != nil {
+		return nil, fmt.Errorf("could not query db for api keys: %v", err)
+	}
+	defer CloseRows(rows)
+
+	keys, err := parseRowsAsAPIKeys(rows)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse api key rows: %v", err)
+	}
+	return keys, nil
+}
+
+// ByHash retrieves the api key with the given key hash from the store. If no key was found, false is returned.
+func (s *APIKeyStore) ByHash(keyHash string) (auth.APIKey, bool, error) {
+	rows, err := s.DB.Query(fmt.Sprintf("%s where key_hash = $1", apiKeySelect), keyHash)
+	if err != nil {
+		return auth.APIKey{}, false, fmt.Errorf("could not query db for api key by hash: %v", err)
+	}
+	defer CloseRows(rows)
+
+	keys, err := parseRowsAsAPIKeys(rows)
+	if err != nil {
+		return auth.APIKey{}, false, fmt.Errorf("could not parse api key row: %v", err)
+	}
+	if len(keys) != 1 {
+		return auth.APIKey{}, false, nil
+	}
+	return keys[0], true, nil
+}
+
+// parseRowsAsAPIKeys parses rows retrieved from db as api keys.
+func parseRowsAsAPIKeys(rows *sql.Rows) ([]auth.APIKey, error) {
+	var (
+		id      int
+		name    string
+		scope   string
+		created time.Time
+	)
+
+	keys := make([]auth.APIKey, 0)
+	for rows.Next() {
+		err := rows.Scan(&id, &name, &scope, &created)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, auth.APIKey{
+			Id:      id,
+			Name:    name,
+			Scope:   auth.Scope(scope),
+			Created: created,
+		})
+	}
+	return keys, nil
+}
+
+const apiKeyInsert = `INSERT INTO api_keys (name, key_hash, scope)
+VALUES ($1, $2, $3)
+RETURNING id, created`
+
+// Create inserts a new api key with the given key hash into db and returns it with the assigned id.
+func (s *APIKeyStore) Create(key auth.APIKey, keyHash string) (auth.APIKey, error) {
+	res := key
+	err := s.DB.QueryRow(apiKeyInsert, key.Name, keyHash, key.Scope).Scan(&res.Id, &res.Created)
+	if err != nil {
+		return auth.APIKey{}, fmt.Errorf("could not insert api key into db: %v", err)
+	}
+	return res, nil
+}
+
+// Delete deletes the api key with the given id from the db.
+func (s *APIKeyStore) Delete(id int) error {
+	result, err := s.DB.Exec("DELETE FROM api_keys WHERE id=$1", id)
+	if err != nil {
+		return fmt.Errorf("could not delete api key from db: %v", err)
+	}
+	return assureAffected(result, fmt.Sprintf("api key %d", id))
+}
@@ -0,0 +1,90 @@
+package stores
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/life-unlimited/podcastination-server/podcasts"
+)
+
+const chapterSelect = "select c.id, c.episode_id, c.start_time, c.title from chapters as c"
+
+type ChapterStore struct {
+	DB *sql.DB
+}
+
+// All retrieves all chapters from the store.
+func (s *ChapterStore) All() ([]podcasts.Chapter, error) {
+	rows, err := s.DB.Query(fmt.Sprintf("%s order by c.episode_id, c.start_time;", chapterSelect))
+	if err != nil {
+		return nil, fmt.Errorf("could not query db for chapters: %v", err)
+	}
+	defer CloseRows(rows)
+
+	chapters, err := parseRowsAsChapters(rows)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse chapter rows: %v", err)
+	}
+	return chapters, nil
+}
+
+// ByEpisode retrieves all chapters from the store that belong to the given episode ordered by start time.
+func (s *ChapterStore) ByEpisode(episodeId int) ([]podcasts.Chapter, error) {
+	rows, err := s.DB.Query(fmt.Sprintf("%s where c.episode_id = $1 order by c.start_time;", chapterSelect), episodeId)
+	if err != nil {
+		return nil, fmt.Errorf("could not query db for chapters by episode: %v", err)
+	}
+	defer CloseRows(rows)
+
+	chapters, err := parseRowsAsChapters(rows)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse chapter rows: %v", err)
+	}
+	return chapters, nil
+}
+
+// ByPodcast retrieves all chapters from the store that belong to episodes of the given podcast.
+func (s *ChapterStore) ByPodcast(podcastId int) ([]podcasts.Chapter, error) {
+	rows, err := s.DB.Query(fmt.Sprintf("%s join episodes on c.episode_id = episodes.id join seasons on "+
+		"episodes.season_id = seasons.id where seasons.podcast_id = $1 order by c.episode_id, c.start_time;",
+		chapterSelect), podcastId)
+	if err != nil {
+		return nil, fmt.Errorf("could not query db for chapters by podcast: %v", err)
+	}
+	defer CloseRows(rows)
+
+	chapters, err := parseRowsAsChapters(rows)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse chapter rows: %v", err)
+	}
+	return chapters, nil
+}
+
+// parseRowsAsChapters parses rows retrieved from db as chapters.
+func parseRowsAsChapters(rows *sql.Rows) ([]podcasts.Chapter, error) {
+	chapters := make([]podcasts.Chapter, 0)
+	for rows.Next() {
+		var chapter podcasts.Chapter
+		err := rows.Scan(&chapter.Id, &chapter.EpisodeId, &chapter.StartTime, &chapter.Title)
+		if err != nil {
+			return nil, err
+		}
+		chapters = append(chapters, chapter)
+	}
+	return chapters, nil
+}
+
+const chapterInsert = `INSERT INTO chapters (episode_id, start_time, title)
+VALUES ($1, $2, $3)
+RETURNING id`
+
+// CreateInTx inserts a new chapter into db in the given transaction and returns the chapter with the assigned id.
+func (s *ChapterStore) CreateInTx(tx *sql.Tx, c podcasts.Chapter) (podcasts.Chapter, error) {
+	var id int
+	err := tx.QueryRow(chapterInsert, c.EpisodeId, c.StartTime, c.Title).Scan(&id)
+	if err != nil {
+		return podcasts.Chapter{}, fmt.Errorf("could not insert chapter into db: %v", err)
+	}
+	res := c
+	res.Id = id
+	return res, nil
+}
@@ -3,12 +3,16 @@ package stores
 import (
 	"database/sql"
 	"fmt"
+	"github.com/google/uuid"
 	"github.com/life-unlimited/podcastination-server/podcasts"
 	"time"
 )
 
 const episodeSelect = `select e.id, e.title, e.subtitle, e.date, e.author, e.description, e.mp3_location, e.season_id, 
-       e.num, e.image_location, e.yt_url, e.mp3_length, e.is_available, e.pdf_location from episodes as e`
+       e.num, e.image_location, e.yt_url, e.mp3_length, e.is_available, e.pdf_location, e.mime_type, e.file_size,
+       e.persons, e.alternate_enclosures, e.transcripts, e.explicit, e.episode_type, e.publish_at,
+       e.guid, e.legacy_guid, e.season_num, e.season_title
+from episodes as e`
 
 type EpisodeStore struct {
 	DB *sql.DB
@@ -89,21 +93,34 @@ func parseRowsAsEpisodes(rows *sql.Rows) ([]podcasts.Episode, error) {
 		mp3Location   sql.NullString
 		mp3Length     int
 		seasonId      int
-		num           int
+		num           sql.NullInt64
 		imageLocation sql.NullString
 		ytURL         sql.NullString
 		isAvailable   bool
 		pdfLocation   sql.NullString
+		mimeType      string
+		fileSize      int64
+		persons       []byte
+		enclosures    []byte
+		transcripts   []byte
+		explicit      bool
+		episodeType   string
+		publishAt     sql.NullTime
+		guid          string
+		legacyGUID    sql.NullString
+		seasonNum     sql.NullInt64
+		seasonTitle   sql.NullString
 	)
 
 	episodes := make([]podcasts.Episode, 0)
 	for rows.Next() {
 		err := rows.Scan(&id, &title, &subtitle, &date, &author, &description, &mp3Location, &seasonId, &num,
-			&imageLocation, &ytURL, &mp3Length, &isAvailable, &pdfLocation)
+			&imageLocation, &ytURL, &mp3Length, &isAvailable, &pdfLocation, &mimeType, &fileSize, &persons, &enclosures, &transcripts,
+			&explicit, &episodeType, &publishAt, &guid, &legacyGUID, &seasonNum, &seasonTitle)
 		if err != nil {
 			return nil, err
 		}
-		episodes = append(episodes, podcasts.Episode{
+		episode := podcasts.Episode{
 			Id:            id,
 			Title:         title,
 			Subtitle:      subtitle.String,
@@ -115,43 +132,111 @@ func parseRowsAsEpisodes(rows *sql.Rows) ([]podcasts.Episode, error) {
 			MP3Location:   mp3Location.String,
 			YouTubeURL:    ytURL.String,
 			SeasonId:      seasonId,
-			Num:           num,
+			Num:           int(num.Int64),
 			MP3Length:     mp3Length,
 			IsAvailable:   isAvailable,
-		})
+			MIMEType:      mimeType,
+			FileSize:      fileSize,
+			Explicit:      explicit,
+			Type:          podcasts.EpisodeType(episodeType),
+			SeasonNum:     int(seasonNum.Int64),
+			SeasonTitle:   seasonTitle.String,
+			GUID:          guid,
+			LegacyGUID:    legacyGUID.String,
+		}
+		if publishAt.Valid {
+			// Copy as publishAt is reused for the next row.
+			t := publishAt.Time
+			episode.PublishAt = &t
+		}
+		if err = unmarshalJSONColumn(persons, &episode.Persons); err != nil {
+			return nil, err
+		}
+		if err = unmarshalJSONColumn(enclosures, &episode.AlternateEnclosures); err != nil {
+			return nil, err
+		}
+		if err = unmarshalJSONColumn(transcripts, &episode.Transcripts); err != nil {
+			return nil, err
+		}
+		episodes = append(episodes, episode)
 	}
 	return episodes, nil
 }
 
 const episodeInsert = `INSERT INTO episodes (title, subtitle, date, author, description, mp3_location, season_id, num,
-                      image_location, yt_url, mp3_length, is_available, pdf_location)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+                      image_location, yt_url, mp3_length, is_available, pdf_location, mime_type, file_size, persons, alternate_enclosures, transcripts, explicit, episode_type,
+                      publish_at, guid, legacy_guid, season_num, season_title)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24,
+        $25)
 RETURNING id`
 
-// Create inserts a new episode into db and returns the episode with the assigned id.
+// Create inserts a new episode into db and returns the episode with the assigned id. If the episode has no GUID, a
+// new one is generated.
 func (s *EpisodeStore) Create(e podcasts.Episode) (podcasts.Episode, error) {
+	return createEpisode(s.DB, e)
+}
+
+// CreateInTx is like Create but performs the insert in the given transaction.
+func (s *EpisodeStore) CreateInTx(tx *sql.Tx, e podcasts.Episode) (podcasts.Episode, error) {
+	return createEpisode(tx, e)
+}
+
+func createEpisode(db queryRower, e podcasts.Episode) (podcasts.Episode, error) {
+	persons, enclosures, transcripts, err := marshalEpisodeJSONColumns(e)
+	if err != nil {
+		return podcasts.Episode{}, err
+	}
+	guid := e.GUID
+	if guid == "" {
+		guid = uuid.New().String()
+	}
+	var legacyGUID sql.NullString
+	if e.LegacyGUID != "" {
+		legacyGUID = sql.NullString{String: e.LegacyGUID, Valid: true}
+	}
 	var id int
-	err := s.DB.QueryRow(episodeInsert, e.Title, e.Subtitle, e.Date, e.Author, e.Description, e.MP3Location, e.SeasonId,
-		e.Num, e.ImageLocation, e.YouTubeURL, e.MP3Length, e.IsAvailable, e.PDFLocation).Scan(&id)
+	err = db.QueryRow(episodeInsert, e.Title, e.Subtitle, e.Date, e.Author, e.Description, e.MP3Location, e.SeasonId,
+		nullableNum(e), e.ImageLocation, e.YouTubeURL, e.MP3Length, e.IsAvailable, e.PDFLocation, e.MIMEType,
+		e.FileSize, persons, enclosures, transcripts, e.Explicit, e.TypeOrDefault(),
+		e.PublishAt, guid, legacyGUID, nullableSeasonNum(e), nullableSeasonTitle(e)).Scan(&id)
 	if err != nil {
 		return podcasts.Episode{}, fmt.Errorf("could not insert episode into db: %v", err)
 	}
 	res := e
 	res.Id = id
+	res.GUID = guid
+	res.Type = e.TypeOrDefault()
 	return res, nil
 }
 
 const episodeUpdate = `UPDATE episodes
 SET title=$1, subtitle=$2, date=$3, author=$4, description=$5, mp3_location=$6, season_id=$7, num=$8,
-    image_location=$9, yt_url=$10, mp3_length=$11, is_available=$12, pdf_location=$13
-WHERE id=$14
+    image_location=$9, yt_url=$10, mp3_length=$11, is_available=$12, pdf_location=$13, mime_type=$14,
+    file_size=$15, persons=$16, alternate_enclosures=$17,
+    transcripts=$18, explicit=$19, episode_type=$20, publish_at=$21, season_num=$22, season_title=$23
+WHERE id=$24
 RETURNING id`
 
-// Update updates an episode in the db based on its id.
+// Update updates an episode in the db based on its id. The guids are not changed as they need to stay the same.
 func (s *EpisodeStore) Update(e podcasts.Episode) error {
+	return updateEpisode(s.DB, e)
+}
+
+// UpdateInTx is like Update but performs the update in the given transaction.
+func (s *EpisodeStore) UpdateInTx(tx *sql.Tx, e podcasts.Episode) error {
+	return updateEpisode(tx, e)
+}
+
+func updateEpisode(db queryRower, e podcasts.Episode) error {
+	persons, enclosures, transcripts, err := marshalEpisodeJSONColumns(e)
+	if err != nil {
+		return err
+	}
 	id := -1
-	err := s.DB.QueryRow(episodeUpdate, e.Title, e.Subtitle, e.Date, e.Author, e.Description, e.MP3Location, e.SeasonId,
-		e.Num, e.ImageLocation, e.YouTubeURL, e.MP3Length, e.IsAvailable, e.PDFLocation, e.Id).Scan(&id)
+	err = db.QueryRow(episodeUpdate, e.Title, e.Subtitle, e.Date, e.Author, e.Description, e.MP3Location, e.SeasonId,
+		nullableNum(e), e.ImageLocation, e.YouTubeURL, e.MP3Length, e.IsAvailable, e.PDFLocation, e.MIMEType,
+		e.FileSize, persons, enclosures, transcripts, e.Explicit, e.TypeOrDefault(),
+		e.PublishAt, nullableSeasonNum(e), nullableSeasonTitle(e), e.Id).Scan(&id)
 	if err != nil {
 		return fmt.Errorf("could not update episode in db: %v", err)
 	}
@@ -160,3 +245,118 @@ func (s *EpisodeStore) Update(e podcasts.Episode) error {
 	}
 	return nil
 }
+
+// nullableNum returns the number of the given episode or nil if it is unnumbered. Unnumbered episodes are stored
+// with null, so that the number stays unique within a season.
+func nullableNum(e podcasts.Episode) interface{} {
+	if e.Num == 0 {
+		return nil
+	}
+	return e.Num
+}
+
+// nullableSeasonNum returns the season number override of the given episode or nil if it has none.
+func nullableSeasonNum(e podcasts.Episode) interface{} {
+	if e.SeasonNum == 0 {
+		return nil
+	}
+	return e.SeasonNum
+}
+
+// nullableSeasonTitle returns the season title override of the given episode or nil if it has none.
+func nullableSeasonTitle(e podcasts.Episode) interface{} {
+	if e.SeasonTitle == "" {
+		return nil
+	}
+	return e.SeasonTitle
+}
+
+// marshalEpisodeJSONColumns marshals the persons, alternate enclosures and transcripts of the given episode.
+func marshalEpisodeJSONColumns(e podcasts.Episode) (string, string, string, error) {
+	persons, err := marshalJSONColumn(e.Persons)
+	if err != nil {
+		return "", "", "", err
+	}
+	enclosures, err := marshalJSONColumn(e.AlternateEnclosures)
+	if err != nil {
+		return "", "", "", err
+	}
+	transcripts, err := marshalJSONColumn(e.Transcripts)
+	if err != nil {
+		return "", "", "", err
+	}
+	return persons, enclosures, transcripts, nil
+}
+
+// NextPublishAt retrieves the earliest publish date of episodes that is after the given time. If there is none, false
+// is returned.
+func (s *EpisodeStore) NextPublishAt(after time.Time) (time.Time, bool, error) {
+	var next sql.NullTime
+	err := s.DB.QueryRow("SELECT min(publish_at) FROM episodes WHERE publish_at > $1", after).Scan(&next)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("could not query db for next publish date: %v", err)
+	}
+	return next.Time, next.Valid, nil
+}
+
+// PodcastsPublishedBetween retrieves the ids of all podcasts with episodes that have a publish date after from and
+// not after to.
+func (s *EpisodeStore) PodcastsPublishedBetween(from, to time.Time) ([]int, error) {
+	rows, err := s.DB.Query(`select distinct seasons.podcast_id from episodes as e
+join seasons on e.season_id = seasons.id
+where e.publish_at > $1 and e.publish_at <= $2;`, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("could not query db for podcasts with published episodes: %v", err)
+	}
+	defer CloseRows(rows)
+
+	podcastIds := make([]int, 0)
+	for rows.Next() {
+		var podcastId int
+		if err = rows.Scan(&podcastId); err != nil {
+			return nil, fmt.Errorf("could not parse podcast id row: %v", err)
+		}
+		podcastIds = append(podcastIds, podcastId)
+	}
+	return podcastIds, nil
+}
+
+// Delete deletes the episode with the given id from the db.
+func (s *EpisodeStore) Delete(id int) error {
+	result, err := s.DB.Exec("DELETE FROM episodes WHERE id=$1", id)
+	if err != nil {
+		return fmt.Errorf("could not delete episode from db: %v", err)
+	}
+	return assureAffected(result, fmt.Sprintf("episode %d", id))
+}
+
+// Exists checks if an episode with the given id exists in the db.
+func (s *EpisodeStore) Exists(id int) (bool, error) {
+	var exists bool
+	err := s.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM episodes WHERE id=$1)", id).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("could not query db for episode existence: %v", err)
+	}
+	return exists, nil
+}
+
+// IsPublic checks if the episode with the given id is available and published at the given time. Unknown episodes are
+// not public.
+func (s *EpisodeStore) IsPublic(id int, now time.Time) (bool, error) {
+	var public bool
+	err := s.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM episodes WHERE id=$1 AND is_available AND
+(publish_at IS NULL OR publish_at <= $2))`, id, now).Scan(&public)
+	if err != nil {
+		return false, fmt.Errorf("could not query db for episode visibility: %v", err)
+	}
+	return public, nil
+}
+
+// Begin begins a new transaction which can be used with CreateInTx and UpdateInTx.
+func (s *EpisodeStore) Begin() (*sql.Tx, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("could not begin tx: %v", err)
+	}
+	return tx, nil
+}
@@ -69,3 +69,46 @@ func parseRowsAsOwners(rows *sql.Rows) ([]podcasts.Owner, error) {
 	}
 	return owners, nil
 }
+
+const ownerInsert = `INSERT INTO owners (name, email, copyright)
+VALUES ($1, $2, $3)
+RETURNING id`
+
+// Create inserts a new owner into db and returns the owner with the assigned id.
+func (s *OwnerStore) Create(o podcasts.Owner) (podcasts.Owner, error) {
+	var id int
+	err := s.DB.QueryRow(ownerInsert, o.Name, o.Email, o.Copyright).Scan(&id)
+	if err != nil {
+		return podcasts.Owner{}, fmt.Errorf("could not insert owner into db: %v", err)
+	}
+	res := o
+	res.Id = id
+	return res, nil
+}
+
+const ownerUpdate = `UPDATE owners
+SET name=$1, email=$2, copyright=$3
+WHERE id=$4
+RETURNING id`
+
+// Update updates an owner in the db based on its id.
+func (s *OwnerStore) Update(o podcasts.Owner) error {
+	id := -1
+	err := s.DB.QueryRow(ownerUpdate, o.Name, o.Email, o.Copyright, o.Id).Scan(&id)
+	if err != nil {
+		return fmt.Errorf("could not update owner in db: %v", err)
+	}
+	if id == -1 {
+		return fmt.Errorf("could not update owner in db: owner %d not found", o.Id)
+	}
+	return nil
+}
+
+// Delete deletes the owner with the given id from the db.
+func (s *OwnerStore) Delete(id int) error {
+	result, err := s.DB.Exec("DELETE FROM owners WHERE id=$1", id)
+	if err != nil {
+		return fmt.Errorf("could not delete owner from db: %v", err)
+	}
+	return assureAffected(result, fmt.Sprintf("owner %d", id))
+}
@@ -11,7 +11,7 @@ type PodcastStore struct {
 	DB *sql.DB
 }
 
-const podcastSelect = "select id, title, subtitle, language, owner_id, description, keywords, link, image_location, type, key, feed_link from podcasts"
+const podcastSelect = "select id, title, subtitle, language, owner_id, description, keywords, link, image_location, type, key, feed_link, guid, locked, funding, persons, categories, explicit from podcasts"
 
 // All retrieves all podcasts from the store.
 func (s *PodcastStore) All() ([]podcasts.Podcast, error) {
@@ -79,16 +79,23 @@ func parseRowsAsPodcasts(rows *sql.Rows) ([]podcasts.Podcast, error) {
 		podcastType   sql.NullString
 		key           sql.NullString
 		feedLink      string
+		guid          sql.NullString
+		locked        bool
+		funding       []byte
+		persons       []byte
+		categories    []byte
+		explicit      bool
 	)
 
 	pcs := make([]podcasts.Podcast, 0)
 	for rows.Next() {
 		err := rows.Scan(&id, &title, &subtitle, &language, &ownerId, &description, &keywords, &link, &imageLocation,
-			&podcastType, &key, &feedLink)
+			&podcastType, &key, &feedLink, &guid, &locked, &funding, &persons, &categories,
+			&explicit)
 		if err != nil {
 			return nil, err
 		}
-		pcs = append(pcs, podcasts.Podcast{
+		podcast := podcasts.Podcast{
 			Id:            id,
 			Title:         title,
 			Subtitle:      subtitle.String,
@@ -101,7 +108,110 @@ func parseRowsAsPodcasts(rows *sql.Rows) ([]podcasts.Podcast, error) {
 			PodcastType:   podcasts.PodcastType(podcastType.String),
 			Key:           key.String,
 			FeedLink:      feedLink,
-		})
+			GUID:          guid.String,
+			Locked:        locked,
+			Explicit:      explicit,
+		}
+		if err = unmarshalJSONColumn(funding, &podcast.Funding); err != nil {
+			return nil, err
+		}
+		if err = unmarshalJSONColumn(persons, &podcast.Persons); err != nil {
+			return nil, err
+		}
+		if err = unmarshalJSONColumn(categories, &podcast.Categories); err != nil {
+			return nil, err
+		}
+		pcs = append(pcs, podcast)
+	}
+	return pcs, nil
+}
+
+const podcastInsert = `INSERT INTO podcasts (title, subtitle, language, owner_id, description, keywords, link, image_location,
+                      type, key, feed_link, guid, locked, funding, persons, categories, explicit)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+RETURNING id`
+
+// Create inserts a new podcast into db and returns the podcast with the assigned id.
+func (s *PodcastStore) Create(p podcasts.Podcast) (podcasts.Podcast, error) {
+	funding, persons, categories, err := marshalPodcastJSONColumns(p)
+	if err != nil {
+		return podcasts.Podcast{}, err
+	}
+	var id int
+	err = s.DB.QueryRow(podcastInsert, p.Title, p.Subtitle, p.Language, p.OwnerId, p.Description,
+		strings.Join(p.Keywords, ","), p.Link, p.ImageLocation, p.PodcastType, p.Key, p.FeedLink, p.GUID, p.Locked,
+		funding, persons, categories, p.Explicit).Scan(&id)
+	if err != nil {
+		return podcasts.Podcast{}, fmt.Errorf("could not insert podcast into db: %v", err)
+	}
+	res := p
+	res.Id = id
+	return res, nil
+}
+
+const podcastUpdate = `UPDATE podcasts
+SET title=$1, subtitle=$2, language=$3, owner_id=$4, description=$5, keywords=$6, link=$7, image_location=$8,
+    type=$9, key=$10, feed_link=$11, guid=$12, locked=$13, funding=$14, persons=$15,
+    categories=$16, explicit=$17
+WHERE id=$18
+RETURNING id`
+
+// Update updates a podcast in the db based on its id.
+func (s *PodcastStore) Update(p podcasts.Podcast) error {
+	funding, persons, categories, err := marshalPodcastJSONColumns(p)
+	if err != nil {
+		return err
+	}
+	id := -1
+	err = s.DB.QueryRow(podcastUpdate, p.Title, p.Subtitle, p.Language, p.OwnerId, p.Description,
+		strings.Join(p.Keywords, ","), p.Link, p.ImageLocation, p.PodcastType, p.Key, p.FeedLink, p.GUID, p.Locked,
+		funding, persons, categories, p.Explicit, p.Id).Scan(&id)
+	if err != nil {
+		return fmt.Errorf("could not update podcast in db: %v", err)
+	}
+	if id == -1 {
+		return fmt.Errorf("could not update podcast in db: podcast %d not found", p.Id)
+	}
+	return nil
+}
+
+// marshalPodcastJSONColumns marshals the funding, persons and categories of the given podcast.
+func marshalPodcastJSONColumns(p podcasts.Podcast) (string, string, string, error) {
+	funding, err := marshalJSONColumn(p.Funding)
+	if err != nil {
+		return "", "", "", err
+	}
+	persons, err := marshalJSONColumn(p.Persons)
+	if err != nil {
+		return "", "", "", err
+	}
+	categories, err := marshalJSONColumn(p.Categories)
+	if err != nil {
+		return "", "", "", err
+	}
+	return funding, persons, categories, nil
+}
+
+// Delete deletes the podcast with the given id from the db.
+func (s *PodcastStore) Delete(id int) error {
+	result, err := s.DB.Exec("DELETE FROM podcasts WHERE id=$1", id)
+	if err != nil {
+		return fmt.Errorf("could not delete podcast from db: %v", err)
+	}
+	return assureAffected(result, fmt.Sprintf("podcast %d", id))
+}
+
+// ByOwner retrieves all podcasts from the store that belong to the given owner.
+func (s *PodcastStore) ByOwner(ownerId int) ([]podcasts.Podcast, error) {
+	rows, err := s.DB.Query(fmt.Sprintf("%s where owner_id = $1", podcastSelect), ownerId)
+	if err != nil {
+		return nil, fmt.Errorf("could not query db for podcasts by owner %d: %v", ownerId, err)
+	}
+	defer CloseRows(rows)
+
+	pcs, err := parseRowsAsPodcasts(rows)
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing podcast rows: %v", err)
 	}
 	return pcs, nil
 }
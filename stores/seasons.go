@@ -111,3 +111,48 @@ func parseRowsAsSeasons(rows *sql.Rows) ([]podcasts.Season, error) {
 	}
 	return seasons, nil
 }
+
+const seasonInsert = `INSERT INTO seasons (title, subtitle, description, image_location, podcast_id, num, key)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id`
+
+// Create inserts a new season into db and returns the season with the assigned id.
+func (s *SeasonStore) Create(season podcasts.Season) (podcasts.Season, error) {
+	var id int
+	err := s.DB.QueryRow(seasonInsert, season.Title, season.Subtitle, season.Description, season.ImageLocation,
+		season.PodcastId, season.Num, season.Key).Scan(&id)
+	if err != nil {
+		return podcasts.Season{}, fmt.Errorf("could not insert season into db: %v", err)
+	}
+	res := season
+	res.Id = id
+	return res, nil
+}
+
+const seasonUpdate = `UPDATE seasons
+SET title=$1, subtitle=$2, description=$3, image_location=$4, podcast_id=$5, num=$6, key=$7
+WHERE id=$8
+RETURNING id`
+
+// Update updates a season in the db based on its id.
+func (s *SeasonStore) Update(season podcasts.Season) error {
+	id := -1
+	err := s.DB.QueryRow(seasonUpdate, season.Title, season.Subtitle, season.Description, season.ImageLocation,
+		season.PodcastId, season.Num, season.Key, season.Id).Scan(&id)
+	if err != nil {
+		return fmt.Errorf("could not update season in db: %v", err)
+	}
+	if id == -1 {
+		return fmt.Errorf("could not update season in db: season %d not found", season.Id)
+	}
+	return nil
+}
+
+// Delete deletes the season with the given id from the db.
+func (s *SeasonStore) Delete(id int) error {
+	result, err := s.DB.Exec("DELETE FROM seasons WHERE id=$1", id)
+	if err != nil {
+		return fmt.Errorf("could not delete season from db: %v", err)
+	}
+	return assureAffected(result, fmt.Sprintf("season %d", id))
+}
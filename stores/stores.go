@@ -2,7 +2,10 @@ package stores
 
 import (
 	"database/sql"
+	"encoding/json"
+	"fmt"
 	"log"
+	"reflect"
 )
 
 type Stores struct {
@@ -10,6 +13,13 @@ type Stores struct {
 	Owners   OwnerStore
 	Seasons  SeasonStore
 	Episodes EpisodeStore
+	Chapters ChapterStore
+	APIKeys  APIKeyStore
+}
+
+// queryRower is implemented by sql.DB as well as sql.Tx and allows performing queries in transactions.
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
 }
 
 func CloseRows(rows *sql.Rows) {
@@ -17,3 +27,39 @@ func CloseRows(rows *sql.Rows) {
 		log.Fatalf("could not close rows: %v", err)
 	}
 }
+
+// assureAffected assures that the given sql.Result affected at least one row. Otherwise, an error is returned that
+// states the given entity to not have been found.
+func assureAffected(result sql.Result, entity string) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not retrieve affected rows: %v", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s not found", entity)
+	}
+	return nil
+}
+
+// marshalJSONColumn marshals the given value for storing it in a json column. Nil slices are stored as empty arrays.
+func marshalJSONColumn(v interface{}) (string, error) {
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice && rv.IsNil() {
+		return "[]", nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal json column: %v", err)
+	}
+	return string(raw), nil
+}
+
+// unmarshalJSONColumn unmarshals the given content of a json column into v. Empty content is ignored.
+func unmarshalJSONColumn(raw []byte, v interface{}) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("could not unmarshal json column: %v", err)
+	}
+	return nil
+}
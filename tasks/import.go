@@ -1,20 +1,25 @@
 package tasks
 
 import (
+	"database/sql"
 	"encoding/json"
-	"encoding/xml"
 	"fmt"
-	"github.com/hajimehoshi/go-mp3"
+	"github.com/life-unlimited/podcastination-server/audio"
+	"github.com/life-unlimited/podcastination-server/feedgen"
+	"github.com/life-unlimited/podcastination-server/id3"
 	"github.com/life-unlimited/podcastination-server/podcast_xml"
 	"github.com/life-unlimited/podcastination-server/podcasts"
 	"github.com/life-unlimited/podcastination-server/stores"
+	"github.com/life-unlimited/podcastination-server/transcripts"
 	"github.com/life-unlimited/podcastination-server/transfer"
+	"github.com/life-unlimited/podcastination-server/websub"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -22,13 +27,30 @@ import (
 const ImportTaskDetailsFileName = "task.json"
 const PodcastXMLDetailsFileName = "podcast.xml"
 
+// finishedTaskDirSuffix is the suffix of task directories that are being removed after successful import.
+const finishedTaskDirSuffix = ".done"
+
 // ImportJob is the task that is scheduled.
 type ImportJob struct {
 	StaticContentURL string
 	PullDir          string
 	PodcastDir       string
 	ImportInterval   time.Duration
-	Store            ImportJobStores
+	// FailedDir is the directory where tasks are moved to after failing MaxAttempts times.
+	FailedDir string
+	// MaxAttempts is the number of attempts after which a failing task is moved to the FailedDir.
+	MaxAttempts int
+	// RetryBackoff is the minimum duration to wait before retrying a failed task. It is doubled with each attempt.
+	RetryBackoff time.Duration
+	Store        ImportJobStores
+	// Publishing is notified about new publish dates of imported episodes if set.
+	Publishing *PublishingJob
+	// Mirror is used for syncing the podcast folder to the storage after refreshing the podcast xml.
+	Mirror *transfer.Mirror
+	// WebSub announces hubs in feeds and notifies them after feeds changed.
+	WebSub *websub.Publisher
+	// importMutex assures that import tasks are not performed concurrently by scheduled runs and ImportTaskNow.
+	importMutex sync.Mutex
 }
 
 type ImportJobStores struct {
@@ -36,6 +58,7 @@ type ImportJobStores struct {
 	Owners   stores.OwnerStore
 	Seasons  stores.SeasonStore
 	Episodes stores.EpisodeStore
+	Chapters stores.ChapterStore
 }
 
 type ImportTask struct {
@@ -43,33 +66,73 @@ type ImportTask struct {
 	Details ImportTaskDetails
 }
 
+// ImportReport is the result of a performed ImportTask.
+type ImportReport struct {
+	Episode podcasts.Episode `json:"episode"`
+	// Warnings are issues that did not prevent the import, for example values in the task details that differ from
+	// the ID3 tag of the audio file.
+	Warnings []string `json:"warnings"`
+}
+
 // ImportTaskDetails is a json structure created by the user who wants to import a podcast.
 type ImportTaskDetails struct {
 	// PodcastKey references the target podcast.
 	PodcastKey string `json:"podcast_key"`
 	// SeasonKey references the target season.
 	SeasonKey string `json:"season_key"`
-	// Title is the title of the episode.
+	// Title is the title of the episode. If none provided, the one from the ID3 tag of the audio file is used.
 	Title string `json:"title"`
 	// Subtitle is the subtitle for the episode.
 	Subtitle string `json:"subtitle"`
 	// Date is the creation date for the episode. This is also used in order to sort tasks for applying the right episode order.
+	// If none provided, the recording time from the ID3 tag of the audio file is used.
 	Date time.Time `json:"date"`
-	// Author is the author of episode. If none provided, 'Unknown' will be used.
+	// Author is the author of episode. If none provided, the artist from the ID3 tag of the audio file is used.
 	Author string `json:"author"`
-	// Description is an optional description for the episode.
+	// Description is an optional description for the episode. If none provided, the comment from the ID3 tag of the
+	// audio file is used.
 	Description string `json:"description"`
-	// MP3FileName is the file name of the mp3 file that is going to be added.
+	// MP3FileName is the file name of the audio file that is going to be added. Besides mp3, all formats supported by
+	// the audio package are allowed.
 	MP3FileName string `json:"mp3_file"`
-	// ImageFileName is the file name of an optional episode image.
+	// ImageFileName is the file name of an optional episode image. If none provided, the cover from the ID3 tag of the
+	// audio file is used.
 	ImageFileName string `json:"image_file"`
 	// PDFFileName is the file name of an optional pdf file.
 	PDFFileName string `json:"pdf_file"`
 	// YouTubeURL is the optional url to an youtube video.
 	YouTubeURL string `json:"yt_url"`
+	// TranscriptFileNames are the file names of optional transcripts in SRT, WebVTT or plain text format. Each format
+	// can only be provided once.
+	TranscriptFileNames []string `json:"transcript_files"`
+	// TranscriptLanguages optionally maps transcript file names to their language, for example en-us. Transcripts
+	// without language get the one of the podcast.
+	TranscriptLanguages map[string]string `json:"transcript_languages"`
+	// Chapters are optional chapters of the episode. If none are provided, they are read from the ID3 tag of mp3 files.
+	Chapters []ImportTaskChapter `json:"chapters"`
+	// Explicit marks the episode as containing explicit content.
+	Explicit bool `json:"explicit"`
+	// EpisodeType is the optional type of the episode. If none provided, podcasts.EpisodeTypeFull is used.
+	EpisodeType podcasts.EpisodeType `json:"episode_type"`
+	// Unnumbered leaves trailers and bonus episodes out of the episode numbering of the season.
+	Unnumbered bool `json:"unnumbered"`
+	// SeasonNum optionally overrides the number of the season in feeds and tags.
+	SeasonNum int `json:"season_num"`
+	// SeasonTitle optionally overrides the title of the season in feeds and tags.
+	SeasonTitle string `json:"season_title"`
+	// PublishAt is the optional time until which the episode is hidden from feeds and listings.
+	PublishAt *time.Time `json:"publish_at"`
+}
+
+// ImportTaskChapter is a chapter in ImportTaskDetails.
+type ImportTaskChapter struct {
+	// StartTime is the start of the chapter in seconds.
+	StartTime float64 `json:"start_time"`
+	Title     string  `json:"title"`
 }
 
-// IsValid checks if the ImportTaskDetails has all needed properties in order to perform the import.
+// IsValid checks if the ImportTaskDetails has all needed properties in order to perform the import. Title and date are
+// not required as they might be provided by the ID3 tag of the audio file which is checked on import.
 func (task *ImportTaskDetails) IsValid() (bool, error) {
 	if len(task.PodcastKey) == 0 {
 		return false, fmt.Errorf("no podcast key provided")
@@ -77,15 +140,21 @@ func (task *ImportTaskDetails) IsValid() (bool, error) {
 	if len(task.SeasonKey) == 0 {
 		return false, fmt.Errorf("no season key provided")
 	}
-	if len(task.Title) == 0 {
-		return false, fmt.Errorf("no title provided")
-	}
-	if task.Date.IsZero() {
-		return false, fmt.Errorf("no date provided")
-	}
 	if len(task.MP3FileName) == 0 {
 		return false, fmt.Errorf("no mp3 file name provided")
 	}
+	if !audio.IsSupportedFileName(task.MP3FileName) {
+		return false, fmt.Errorf("unsupported audio file format %s", filepath.Ext(task.MP3FileName))
+	}
+	if _, err := task.EpisodeType.IsValid(); err != nil {
+		return false, err
+	}
+	if task.Unnumbered && !task.EpisodeType.MayBeUnnumbered() {
+		return false, fmt.Errorf("only trailers and bonus episodes can be unnumbered")
+	}
+	if task.SeasonNum < 0 {
+		return false, fmt.Errorf("season number override must not be negative")
+	}
 	// Assure that the image file is png.
 	img := task.ImageFileName
 	if img != "" && !strings.HasSuffix(img, ".png") {
@@ -96,6 +165,36 @@ func (task *ImportTaskDetails) IsValid() (bool, error) {
 	if pdf != "" && !strings.HasSuffix(pdf, ".pdf") {
 		return false, fmt.Errorf("pdf file format must be .pdf")
 	}
+	// Assure supported and distinct transcript formats.
+	transcriptTypes := make(map[string]struct{})
+	transcriptNames := make(map[string]struct{})
+	for _, transcript := range task.TranscriptFileNames {
+		transcriptNames[transcript] = struct{}{}
+		mimeType, ok := transcripts.MIMETypeForFileName(transcript)
+		if !ok {
+			return false, fmt.Errorf("transcript file format must be one of %s",
+				strings.Join(transcripts.Extensions(), ", "))
+		}
+		if _, ok := transcriptTypes[mimeType]; ok {
+			return false, fmt.Errorf("multiple transcripts with format %s provided", filepath.Ext(transcript))
+		}
+		transcriptTypes[mimeType] = struct{}{}
+	}
+	for transcript, language := range task.TranscriptLanguages {
+		if _, ok := transcriptNames[transcript]; !ok {
+			return false, fmt.Errorf("language provided for unknown transcript %s", transcript)
+		}
+		if language == "" {
+			return false, fmt.Errorf("empty language provided for transcript %s", transcript)
+		}
+	}
+	// Assure valid chapters. Their start times are checked against the episode length on import.
+	for _, chapter := range task.Chapters {
+		c := podcasts.Chapter{StartTime: chapter.StartTime, Title: chapter.Title}
+		if _, err := c.IsValid(); err != nil {
+			return false, err
+		}
+	}
 	return true, nil
 }
 
@@ -109,11 +208,45 @@ func (job *ImportJob) name() string {
 
 // run runs the import tasks (yay).
 func (job *ImportJob) run() error {
+	return job.runTasks(nil)
+}
+
+// runTasks runs the import tasks. If taskDirs is not nil, only the tasks in the given directories are performed.
+func (job *ImportJob) runTasks(taskDirs map[string]struct{}) error {
+	job.importMutex.Lock()
+	defer job.importMutex.Unlock()
+	// Remove leftovers from previous runs.
+	if err := removeFinishedTaskDirs(job.PullDir); err != nil {
+		log.Printf("could not remove finished task folders: %v", err)
+	}
 	// Retrieve import tasks.
-	tasks, err := getImportTasks(job.PullDir)
+	allTasks, invalidTasks, err := getImportTasks(job.PullDir)
 	if err != nil {
 		return fmt.Errorf("error while retrieving import tasks: %v", err)
 	}
+	now := time.Now()
+	for _, invalidTask := range invalidTasks {
+		if !includesTaskDir(taskDirs, invalidTask.BaseDir) {
+			continue
+		}
+		if due, _ := job.isDue(invalidTask.BaseDir, now); due {
+			job.handleFailedAttempt(invalidTask.BaseDir, invalidTask.Err)
+		}
+	}
+	// Skip tasks that failed before and need to wait for their next attempt.
+	tasks := make([]ImportTask, 0, len(allTasks))
+	for _, task := range allTasks {
+		if !includesTaskDir(taskDirs, task.BaseDir) {
+			continue
+		}
+		due, err := job.isDue(task.BaseDir, now)
+		if err != nil {
+			log.Printf("could not check if task %s is due: %v", task.BaseDir, err)
+		}
+		if due {
+			tasks = append(tasks, task)
+		}
+	}
 	if len(tasks) == 0 {
 		return nil
 	}
@@ -121,11 +254,27 @@ func (job *ImportJob) run() error {
 	importSuccess := 0
 	changedPodcasts := make(map[int]struct{})
 	for _, task := range tasks {
-		affectedPodcast, err := job.performImportTask(task)
+		var report ImportReport
+		affectedPodcast, episode, err := job.performImportTask(task, &report)
 		if err != nil {
-			log.Printf("could not perform import task for %s: %v", task.Details.Title, err)
+			name := task.Details.Title
+			if name == "" {
+				// The title might be read from the ID3 tag.
+				name = filepath.Base(task.BaseDir)
+			}
+			log.Printf("could not perform import task for %s: %v", name, err)
+			job.handleFailedAttempt(task.BaseDir, err)
 			continue
 		}
+		report.Episode = episode
+		for _, warning := range report.Warnings {
+			log.Printf("warning while importing %s: %s", task.BaseDir, warning)
+		}
+		if len(report.Warnings) > 0 {
+			if err = job.recordImportWarnings(task.BaseDir, report, time.Now()); err != nil {
+				log.Printf("could not record import warnings of %s: %v", task.BaseDir, err)
+			}
+		}
 		importSuccess++
 		changedPodcasts[affectedPodcast.Id] = struct{}{}
 	}
@@ -162,28 +311,35 @@ func (job *ImportJob) run() error {
 	return nil
 }
 
-// refreshPodcastXML refreshes the podcast xml file for the given podcast.
+// includesTaskDir checks if the given task directory is included in the given ones. If taskDirs is nil, all are.
+func includesTaskDir(taskDirs map[string]struct{}, taskDir string) bool {
+	if taskDirs == nil {
+		return true
+	}
+	_, ok := taskDirs[taskDir]
+	return ok
+}
+
+// refreshPodcastXML refreshes the podcast xml file and the feeds in further formats for the given podcast. WebSub hubs
+// are notified about changed feeds. As the feeds are already written then, failing notifications are only logged.
 func (job *ImportJob) refreshPodcastXML(podcastId int) error {
 	// Get whole podcast content.
 	creationDetails, err := job.getPodcastAsCreationDetails(podcastId)
 	if err != nil {
 		return fmt.Errorf("could not get creation details: %v", err)
 	}
-	// Generate podcast xml.
-	podcastXML, err := podcast_xml.GeneratePodcastXML(creationDetails)
+	creationDetails.Hubs = job.WebSub.HubURLs()
+	// Write podcast xml and feeds in further formats.
+	changed, err := feedgen.WriteFeeds(creationDetails, job.PodcastDir, PodcastXMLDetailsFileName)
 	if err != nil {
-		return fmt.Errorf("could not generate podcast xml: %v", err)
+		return fmt.Errorf("could not write feeds: %v", err)
 	}
-	output, err := xml.MarshalIndent(podcastXML, "", "  ")
-	if err != nil {
-		return fmt.Errorf("could not marshal podcast xml: %v", err)
+	if err = job.Mirror.SyncPodcast(podcastId); err != nil {
+		return fmt.Errorf("could not sync podcast folder to storage: %v", err)
 	}
-	// Write podcast xml.
-	podcastXMLFilePath := filepath.Join(job.PodcastDir, transfer.GetPodcastFolderName(podcastId),
-		PodcastXMLDetailsFileName)
-	err = ioutil.WriteFile(podcastXMLFilePath, output, 0633)
-	if err != nil {
-		return fmt.Errorf("could not write podcast xml: %v", err)
+	// Hubs are notified after syncing, so that they fetch the new feeds.
+	if err = job.WebSub.Publish(changed...); err != nil {
+		log.Printf("could not notify websub hubs about podcast %d: %v", podcastId, err)
 	}
 	return nil
 }
@@ -209,28 +365,47 @@ func (job *ImportJob) getPodcastAsCreationDetails(podcastId int) (podcast_xml.Cr
 		return podcast_xml.CreationDetails{}, fmt.Errorf("could not get episodes for podcast %d from db: %v",
 			podcastId, err)
 	}
+	chapters, err := job.Store.Chapters.ByPodcast(podcastId)
+	if err != nil {
+		return podcast_xml.CreationDetails{}, fmt.Errorf("could not get chapters for podcast %d from db: %v",
+			podcastId, err)
+	}
 	return podcast_xml.CreationDetails{
 		StaticContentURL: job.StaticContentURL,
 		Owner:            owner,
 		Podcast:          podcast,
 		Seasons:          seasons,
 		Episodes:         episodes,
+		Chapters:         chapters,
 	}, nil
 }
 
-func getImportTasks(dir string) ([]ImportTask, error) {
+// invalidImportTask is a task directory whose details could not be read.
+type invalidImportTask struct {
+	BaseDir string
+	Err     error
+}
+
+// getImportTasks retrieves all import tasks from the given directory. Directories whose task details could not be read
+// are returned as invalid ones.
+func getImportTasks(dir string) ([]ImportTask, []invalidImportTask, error) {
 	// Read directories in pull folder.
 	fileInfo, err := ioutil.ReadDir(dir)
 	if err != nil {
-		return nil, fmt.Errorf("could not read directories from %s: %v", dir, err)
+		return nil, nil, fmt.Errorf("could not read directories from %s: %v", dir, err)
 	}
 	var importTasks []ImportTask
+	var invalidTasks []invalidImportTask
 	for _, file := range fileInfo {
-		if file.IsDir() {
+		// Hidden directories are ignored as they are still being staged or failed.
+		if file.IsDir() && !strings.HasPrefix(file.Name(), ".") {
 			baseDir := filepath.Join(dir, file.Name())
 			details, err := getImportTaskDetailsFromDir(baseDir)
 			if err != nil {
-				log.Printf("could not get import task details from directory %s: %v", file.Name(), err)
+				invalidTasks = append(invalidTasks, invalidImportTask{
+					BaseDir: baseDir,
+					Err:     fmt.Errorf("could not get import task details: %v", err),
+				})
 				continue
 			}
 			importTasks = append(importTasks, ImportTask{
@@ -243,7 +418,7 @@ func getImportTasks(dir string) ([]ImportTask, error) {
 	sort.Slice(importTasks, func(i, j int) bool {
 		return importTasks[i].Details.Date.Before(importTasks[j].Details.Date)
 	})
-	return importTasks, nil
+	return importTasks, invalidTasks, nil
 }
 
 func getImportTaskDetailsFromDir(dir string) (ImportTaskDetails, error) {
@@ -265,6 +440,10 @@ func getImportTaskDetailsFromDir(dir string) (ImportTaskDetails, error) {
 		_ = taskDetailsFile.Close()
 		return ImportTaskDetails{}, fmt.Errorf("could not parse task details file: %v", err)
 	}
+	// Close details file.
+	if err = taskDetailsFile.Close(); err != nil {
+		return ImportTaskDetails{}, fmt.Errorf("could not close task details file: %v", err)
+	}
 	// Check if task details are valid.
 	if _, err := details.IsValid(); err != nil {
 		return ImportTaskDetails{}, fmt.Errorf("invalid task details file: %v", err)
@@ -272,39 +451,101 @@ func getImportTaskDetailsFromDir(dir string) (ImportTaskDetails, error) {
 	return details, nil
 }
 
+// ImportTaskNow performs the given task immediately instead of waiting for the next run and refreshes the podcast xml
+// of the affected podcast. The ImportReport with the created episode is returned.
+func (job *ImportJob) ImportTaskNow(task ImportTask) (ImportReport, error) {
+	job.importMutex.Lock()
+	defer job.importMutex.Unlock()
+	report := ImportReport{Warnings: make([]string, 0)}
+	podcast, episode, err := job.performImportTask(task, &report)
+	if err != nil {
+		return ImportReport{}, err
+	}
+	report.Episode = episode
+	if err := job.refreshPodcastXML(podcast.Id); err != nil {
+		log.Printf("could not refresh podcast xml for podcast %d: %v", podcast.Id, err)
+	}
+	return report, nil
+}
+
 // performImportTask finally performs the given task which means that the episode is inserted into the database and
-// moved to its final location. However this does not perform the podcast xml file refresh.
-func (job *ImportJob) performImportTask(task ImportTask) (podcasts.Podcast, error) {
-	// Check the mp3 file.
-	audioLength, err := validateMP3(filepath.Join(task.BaseDir, task.Details.MP3FileName))
+// copied to its final location. However this does not perform the podcast xml file refresh. Warnings are added to the
+// given ImportReport.
+//
+// The import is performed in a transaction and recorded in an importJournal. If anything fails, the transaction is
+// rolled back and copied files are removed, so that the task can be performed again. If an import was interrupted,
+// for example because of a crash, it is detected via the journal and either finished or rolled back.
+func (job *ImportJob) performImportTask(task ImportTask, report *ImportReport) (podcasts.Podcast, podcasts.Episode,
+	error) {
+	// Check for an interrupted import.
+	podcast, episode, done, err := job.resumeImportTask(task)
+	if err != nil {
+		return podcasts.Podcast{}, podcasts.Episode{}, fmt.Errorf("could not resume interrupted import: %v", err)
+	}
+	if done {
+		return podcast, episode, nil
+	}
+	// Check the audio file.
+	audioFile := filepath.Join(task.BaseDir, task.Details.MP3FileName)
+	audioInfo, err := audio.Probe(audioFile)
+	if err != nil {
+		return podcasts.Podcast{}, podcasts.Episode{}, fmt.Errorf("error while validating audio file: %v", err)
+	}
+	audioStat, err := os.Stat(audioFile)
 	if err != nil {
-		return podcasts.Podcast{}, fmt.Errorf("error while validating mp3 file: %v", err)
+		return podcasts.Podcast{}, podcasts.Episode{}, fmt.Errorf("could not stat audio file: %v", err)
+	}
+	// Fill missing details from the id3 tag.
+	tag, err := id3.ReadFile(audioFile)
+	if err != nil && err != id3.ErrNoTag {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("could not read id3 tag: %v", err))
+	}
+	if tag != nil {
+		report.Warnings = append(report.Warnings, applyID3Tag(&task, tag)...)
+	}
+	if len(task.Details.Title) == 0 {
+		return podcasts.Podcast{}, podcasts.Episode{}, fmt.Errorf("no title provided in task details or id3 tag")
+	}
+	if task.Details.Date.IsZero() {
+		return podcasts.Podcast{}, podcasts.Episode{}, fmt.Errorf("no date provided in task details or id3 tag")
 	}
 	// Check image.
 	if len(task.Details.ImageFileName) != 0 {
 		image, err := os.Open(filepath.Join(task.BaseDir, task.Details.ImageFileName))
 		if err != nil {
-			return podcasts.Podcast{}, fmt.Errorf("could not open image file: %v", err)
+			return podcasts.Podcast{}, podcasts.Episode{}, fmt.Errorf("could not open image file: %v", err)
 		}
 		if err = image.Close(); err != nil {
-			return podcasts.Podcast{}, fmt.Errorf("could not close image file: %v", err)
+			return podcasts.Podcast{}, podcasts.Episode{}, fmt.Errorf("could not close image file: %v", err)
 		}
 	}
+	// Check transcripts.
+	for _, transcript := range task.Details.TranscriptFileNames {
+		if _, err := transcripts.Validate(filepath.Join(task.BaseDir, transcript)); err != nil {
+			return podcasts.Podcast{}, podcasts.Episode{}, fmt.Errorf("error while validating transcript %s: %v",
+				transcript, err)
+		}
+	}
+	// Check chapters.
+	chapters, err := taskChapters(task, tag, audioInfo)
+	if err != nil {
+		return podcasts.Podcast{}, podcasts.Episode{}, fmt.Errorf("invalid chapters: %v", err)
+	}
 	// Now we can check the database.
 	// Get the podcast.
-	podcast, err := job.Store.Podcasts.ByKey(task.Details.PodcastKey)
+	podcast, err = job.Store.Podcasts.ByKey(task.Details.PodcastKey)
 	if err != nil {
-		return podcasts.Podcast{}, fmt.Errorf("could not get podcast (%s): %v", task.Details.PodcastKey, err)
+		return podcasts.Podcast{}, podcasts.Episode{}, fmt.Errorf("could not get podcast (%s): %v", task.Details.PodcastKey, err)
 	}
 	// Get the season.
 	season, err := job.Store.Seasons.ByKey(task.Details.SeasonKey, podcast.Id)
 	if err != nil {
-		return podcast, fmt.Errorf("could not get season %s in podcast %d: %v", task.Details.SeasonKey, podcast.Id, err)
+		return podcast, podcasts.Episode{}, fmt.Errorf("could not get season %s in podcast %d: %v", task.Details.SeasonKey, podcast.Id, err)
 	}
 	// Get current episodes in season in order to get the latest episode number.
 	episodesInSeason, err := job.Store.Episodes.BySeason(season.Id)
 	if err != nil {
-		return podcast, fmt.Errorf("could not get episodes in season %d: %v", season.Id, err)
+		return podcast, podcasts.Episode{}, fmt.Errorf("could not get episodes in season %d: %v", season.Id, err)
 	}
 	episodeNum := 0
 	for _, episodeInSeason := range episodesInSeason {
@@ -313,26 +554,85 @@ func (job *ImportJob) performImportTask(task ImportTask) (podcasts.Podcast, erro
 		}
 	}
 	episodeNum++
-	// Create new episode entry and insert into db as we need the assigned id.
-	episode := podcasts.Episode{
+	if task.Details.Unnumbered {
+		episodeNum = 0
+	}
+	// Create new episode entry.
+	episode = podcasts.Episode{
 		Title:       task.Details.Title,
 		Subtitle:    task.Details.Subtitle,
 		Date:        task.Details.Date,
 		Author:      task.Details.Author,
 		Description: task.Details.Description,
-		MP3Length:   audioLength,
+		MP3Length:   audioInfo.Seconds(),
+		MIMEType:    audioInfo.MIMEType,
+		FileSize:    audioStat.Size(),
 		SeasonId:    season.Id,
 		Num:         episodeNum,
 		YouTubeURL:  task.Details.YouTubeURL,
+		Type:        task.Details.EpisodeType,
+		SeasonNum:   task.Details.SeasonNum,
+		SeasonTitle: task.Details.SeasonTitle,
+		PublishAt:   task.Details.PublishAt,
+		Explicit:    task.Details.Explicit,
 		IsAvailable: false, // This will be updated to true when all files are transferred.
 	}
+	// Begin the import. From now on, everything is recorded in the journal and rolled back on failure.
+	journal := newImportJournal(task.BaseDir)
+	if err = journal.save(); err != nil {
+		return podcast, podcasts.Episode{}, fmt.Errorf("could not save import journal: %v", err)
+	}
+	tx, err := job.Store.Episodes.Begin()
+	if err != nil {
+		job.rollbackImport(nil, journal)
+		return podcast, podcasts.Episode{}, fmt.Errorf("could not begin tx: %v", err)
+	}
+	episode, err = job.importEpisodeInTx(tx, journal, task, podcast, *season, episode, chapters)
+	if err != nil {
+		job.rollbackImport(tx, journal)
+		return podcast, podcasts.Episode{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		job.rollbackImport(nil, journal)
+		return podcast, podcasts.Episode{}, fmt.Errorf("could not commit tx: %v", err)
+	}
+	// The import is done. If saving the state fails, the committed import is still detected via the episode id.
+	journal.State = importStateCommitted
+	if err = journal.save(); err != nil {
+		log.Printf("could not save committed state in import journal for %s: %v", task.BaseDir, err)
+	}
+	if err = removeTaskDir(task.BaseDir); err != nil {
+		log.Printf("could not delete task folder %s: %v", task.BaseDir, err)
+	}
+	// Embargoed episodes are published by the PublishingJob.
+	if episode.PublishAt != nil && job.Publishing != nil {
+		job.Publishing.Reschedule()
+	}
+	// Podcast xml generation is done after all import tasks have been performed.
+	return podcast, episode, nil
+}
+
+// importEpisodeInTx inserts the given episode and its chapters in the given transaction, transfers its files and marks
+// it as available. All copied files are recorded in the given importJournal.
+func (job *ImportJob) importEpisodeInTx(tx *sql.Tx, journal *importJournal, task ImportTask, podcast podcasts.Podcast,
+	season podcasts.Season, episode podcasts.Episode, chapters []podcasts.Chapter) (podcasts.Episode, error) {
 	// Insert into db and get the inserted episode with its assigned id.
-	episode, err = job.Store.Episodes.Create(episode)
+	episode, err := job.Store.Episodes.CreateInTx(tx, episode)
 	if err != nil {
-		return podcast, fmt.Errorf("could not insert episode into db: %v", err)
+		return podcasts.Episode{}, fmt.Errorf("could not insert episode into db: %v", err)
+	}
+	for i := range chapters {
+		chapters[i].EpisodeId = episode.Id
+		if chapters[i], err = job.Store.Chapters.CreateInTx(tx, chapters[i]); err != nil {
+			return podcasts.Episode{}, fmt.Errorf("could not insert chapter into db: %v", err)
+		}
+	}
+	journal.EpisodeId = episode.Id
+	if err = journal.save(); err != nil {
+		return podcasts.Episode{}, fmt.Errorf("could not save import journal: %v", err)
 	}
 	// Get new file locations.
-	fileLocations := transfer.GetEpisodeFileLocations(episode, podcast.Id)
+	fileLocations := transfer.GetEpisodeFileLocations(episode, podcast.Id, filepath.Ext(task.Details.MP3FileName))
 	episode.MP3Location = fileLocations.MP3FullPath()
 	if task.Details.ImageFileName != "" {
 		episode.ImageLocation = fileLocations.ImageFullPath()
@@ -340,87 +640,231 @@ func (job *ImportJob) performImportTask(task ImportTask) (podcasts.Podcast, erro
 	if task.Details.PDFFileName != "" {
 		episode.PDFLocation = fileLocations.PDFFullPath()
 	}
+	episode.Transcripts = make([]podcasts.Transcript, 0, len(task.Details.TranscriptFileNames))
+	for _, transcript := range task.Details.TranscriptFileNames {
+		mimeType, _ := transcripts.MIMETypeForFileName(transcript)
+		language, ok := task.Details.TranscriptLanguages[transcript]
+		if !ok {
+			language = string(podcast.Language)
+		}
+		episode.Transcripts = append(episode.Transcripts, podcasts.Transcript{
+			Location: fileLocations.TranscriptFullPath(filepath.Ext(transcript)),
+			Type:     mimeType,
+			Language: language,
+		})
+	}
 	// Transfer the files.
-	err = job.performFileTransfer(episode, task, fileLocations)
+	err = job.performFileTransfer(&episode, season, chapters, task, fileLocations, journal)
 	if err != nil {
-		return podcast, fmt.Errorf("could not perform file transfer: %v", err)
+		return podcasts.Episode{}, fmt.Errorf("could not perform file transfer: %v", err)
+	}
+	if len(chapters) > 0 {
+		err = writeChaptersFile(journal, filepath.Join(job.PodcastDir, fileLocations.ChaptersFullPath()), chapters)
+		if err != nil {
+			return podcasts.Episode{}, fmt.Errorf("could not write chapters file: %v", err)
+		}
 	}
 	// Set active to true in db for episode.
 	episode.IsAvailable = true
-	err = job.Store.Episodes.Update(episode)
+	err = job.Store.Episodes.UpdateInTx(tx, episode)
 	if err != nil {
-		return podcast, fmt.Errorf("could not update episode data in db: %v", err)
+		return podcasts.Episode{}, fmt.Errorf("could not update episode data in db: %v", err)
+	}
+	return episode, nil
+}
+
+// rollbackImport rolls back the given transaction if not nil as well as all files recorded in the importJournal.
+// Errors are only logged as the original reason for the rollback is more important.
+func (job *ImportJob) rollbackImport(tx *sql.Tx, journal *importJournal) {
+	if tx != nil {
+		if err := tx.Rollback(); err != nil {
+			log.Printf("could not rollback import tx: %v", err)
+		}
+	}
+	if err := journal.rollbackFiles(); err != nil {
+		log.Printf("could not rollback import files: %v", err)
 	}
-	// Podcast xml generation is done after all import tasks have been performed.
-	return podcast, nil
 }
 
-// validateMP3 validates an mp3 file and returns the audio length in seconds.
-func validateMP3(file string) (int, error) {
-	f, err := os.Open(file)
+// resumeImportTask checks if the import of the given task was interrupted. If it was committed, the task directory is
+// removed and true is returned along with the imported episode. Otherwise, copied files are rolled back, so that the
+// task can be performed again.
+func (job *ImportJob) resumeImportTask(task ImportTask) (podcasts.Podcast, podcasts.Episode, bool, error) {
+	journal, found, err := readImportJournal(task.BaseDir)
 	if err != nil {
-		return -1, fmt.Errorf("could not open mp3 file: %v", err)
+		return podcasts.Podcast{}, podcasts.Episode{}, false, err
+	}
+	if !found {
+		return podcasts.Podcast{}, podcasts.Episode{}, false, nil
 	}
-	// Decode mp3.
-	d, err := mp3.NewDecoder(f)
+	committed := journal.State == importStateCommitted
+	if !committed && journal.EpisodeId != 0 {
+		committed, err = job.Store.Episodes.Exists(journal.EpisodeId)
+		if err != nil {
+			return podcasts.Podcast{}, podcasts.Episode{}, false, fmt.Errorf("could not check if episode exists: %v", err)
+		}
+	}
+	if !committed {
+		log.Printf("rolling back interrupted import of %s", task.BaseDir)
+		if err = journal.rollbackFiles(); err != nil {
+			return podcasts.Podcast{}, podcasts.Episode{}, false, fmt.Errorf("could not rollback files: %v", err)
+		}
+		return podcasts.Podcast{}, podcasts.Episode{}, false, nil
+	}
+	// Finish the import.
+	log.Printf("finishing interrupted import of %s", task.BaseDir)
+	podcast, err := job.Store.Podcasts.ByKey(task.Details.PodcastKey)
 	if err != nil {
-		_ = f.Close()
-		return -1, fmt.Errorf("could not create mp3 decoder: %v", err)
+		return podcasts.Podcast{}, podcasts.Episode{}, false, fmt.Errorf("could not get podcast (%s): %v",
+			task.Details.PodcastKey, err)
 	}
-	const sampleSize = 4
-	samples := d.Length() / sampleSize
-	audioLength := int(samples) / d.SampleRate()
-	err = f.Close()
+	episode, err := job.Store.Episodes.ById(journal.EpisodeId)
 	if err != nil {
-		return -1, fmt.Errorf("could not close mp3 file: %v", err)
+		return podcasts.Podcast{}, podcasts.Episode{}, false, fmt.Errorf("could not get episode %d: %v",
+			journal.EpisodeId, err)
 	}
-	return audioLength, nil
+	if err = removeTaskDir(task.BaseDir); err != nil {
+		return podcasts.Podcast{}, podcasts.Episode{}, false, fmt.Errorf("could not delete task folder: %v", err)
+	}
+	return podcast, *episode, true, nil
 }
 
-// performFileTransfer transfers all episode related files to the given destination. This also deletes the task
-// file.
-func (job *ImportJob) performFileTransfer(episode podcasts.Episode, task ImportTask,
-	fileLocations transfer.EpisodeFileLocations) error {
+// performFileTransfer copies all episode related files to the given destination. Each copy is recorded in the given
+// importJournal before it is performed. The task files are kept until the import is committed. Finally, mp3 files are
+// retagged with the details of the episode, its season and chapters and the file size of the episode is updated.
+func (job *ImportJob) performFileTransfer(episode *podcasts.Episode, season podcasts.Season,
+	chapters []podcasts.Chapter, task ImportTask, fileLocations transfer.EpisodeFileLocations,
+	journal *importJournal) error {
 	// Create target directory.
 	err := os.MkdirAll(filepath.Join(job.PodcastDir, fileLocations.BaseDir), 0744) // Create with read-write read read.
 	if err != nil {
 		return fmt.Errorf("could not create episode directory: %v", err)
 	}
-	// Move the files.
-	// Move the mp3.
-	mp3Destination := filepath.Join(job.PodcastDir, episode.MP3Location)
-	err = transfer.MoveFile(filepath.Join(task.BaseDir, task.Details.MP3FileName), mp3Destination)
+	// Copy the files.
+	// Copy the audio file.
+	audioDestination := filepath.Join(job.PodcastDir, episode.MP3Location)
+	err = copyRecorded(journal, filepath.Join(task.BaseDir, task.Details.MP3FileName), audioDestination)
 	if err != nil {
-		return fmt.Errorf("could not move mp3 to final destination: %v", err)
+		return fmt.Errorf("could not copy audio file to final destination: %v", err)
 	}
-	// Move the image if existing.
+	// Copy the image if existing.
 	if task.Details.ImageFileName != "" {
 		imageSource := filepath.Join(task.BaseDir, task.Details.ImageFileName)
 		_, err = os.Stat(imageSource)
 		if err == nil {
 			imageDestination := filepath.Join(job.PodcastDir, episode.ImageLocation)
-			err = transfer.MoveFile(imageSource, imageDestination)
+			err = copyRecorded(journal, imageSource, imageDestination)
 			if err != nil {
-				return fmt.Errorf("could not move image to final destination: %v", err)
+				return fmt.Errorf("could not copy image to final destination: %v", err)
 			}
 		}
 	}
-	// Move the pdf if existing.
+	// Copy the pdf if existing.
 	if task.Details.PDFFileName != "" {
 		pdfSource := filepath.Join(task.BaseDir, task.Details.PDFFileName)
 		_, err = os.Stat(pdfSource)
 		if err == nil {
 			pdfDestination := filepath.Join(job.PodcastDir, episode.PDFLocation)
-			err = transfer.MoveFile(pdfSource, pdfDestination)
+			err = copyRecorded(journal, pdfSource, pdfDestination)
 			if err != nil {
-				return fmt.Errorf("could not move pdf to final destination: %v", err)
+				return fmt.Errorf("could not copy pdf to final destination: %v", err)
 			}
 		}
 	}
-	// Delete the source folder
-	err = os.RemoveAll(task.BaseDir)
+	// Copy the transcripts next to the audio file.
+	for i, transcript := range task.Details.TranscriptFileNames {
+		transcriptDestination := filepath.Join(job.PodcastDir, episode.Transcripts[i].Location)
+		err = copyRecorded(journal, filepath.Join(task.BaseDir, transcript), transcriptDestination)
+		if err != nil {
+			return fmt.Errorf("could not copy transcript %s to final destination: %v", transcript, err)
+		}
+	}
+	// Retag the audio file.
+	fileSize, err := transfer.RetagEpisodeAudio(job.PodcastDir, *episode, season, chapters)
+	if err != nil {
+		return fmt.Errorf("could not retag audio file: %v", err)
+	}
+	episode.FileSize = fileSize
+	return nil
+}
+
+// taskChapters returns the chapters of the given task and checks them against the length of the audio file. If the
+// task has none, the chapters are read from the ID3 tag of the audio file if not nil. Chapters from the tag that
+// exceed the length or start at the same time as a previous one are skipped.
+func taskChapters(task ImportTask, tag *id3.Tag, audioInfo audio.Info) ([]podcasts.Chapter, error) {
+	length := audioInfo.Duration.Seconds()
+	chapters := make([]podcasts.Chapter, 0, len(task.Details.Chapters))
+	for _, chapter := range task.Details.Chapters {
+		chapters = append(chapters, podcasts.Chapter{
+			StartTime: chapter.StartTime,
+			Title:     chapter.Title,
+		})
+	}
+	if len(chapters) != 0 || tag == nil {
+		_, err := podcasts.ValidateChapters(chapters, length)
+		return chapters, err
+	}
+	for i, tagChapter := range tag.Chapters() {
+		chapter := podcasts.Chapter{
+			StartTime: tagChapter.Start.Seconds(),
+			Title:     tagChapter.Title,
+		}
+		if chapter.Title == "" {
+			chapter.Title = fmt.Sprintf("Chapter %d", i+1)
+		}
+		if _, err := podcasts.ValidateChapters(append(chapters, chapter), length); err != nil {
+			log.Printf("skipping chapter %s from id3 tag of %s: %v", chapter.Title, task.Details.MP3FileName, err)
+			continue
+		}
+		chapters = append(chapters, chapter)
+	}
+	return chapters, nil
+}
+
+// writeChaptersFile records the destination in the given importJournal and then writes the given chapters as
+// podcast_xml.ChaptersJSON.
+func writeChaptersFile(journal *importJournal, destination string, chapters []podcasts.Chapter) error {
+	if err := journal.recordCopy(destination); err != nil {
+		return fmt.Errorf("could not record copy: %v", err)
+	}
+	raw, err := json.MarshalIndent(podcast_xml.GenerateChaptersJSON(chapters), "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not marshal chapters: %v", err)
+	}
+	return ioutil.WriteFile(destination, raw, 0644)
+}
+
+// copyRecorded records the destination in the given importJournal and then copies the file.
+func copyRecorded(journal *importJournal, source, destination string) error {
+	if err := journal.recordCopy(destination); err != nil {
+		return fmt.Errorf("could not record copy: %v", err)
+	}
+	return transfer.CopyFile(source, destination)
+}
+
+// removeTaskDir removes the given task directory. It is renamed to a hidden folder first, so that it is never picked
+// up again if removing is interrupted. Such leftovers are removed with removeFinishedTaskDirs.
+func removeTaskDir(dir string) error {
+	parent, name := filepath.Split(dir)
+	finishedDir := filepath.Join(parent, fmt.Sprintf(".%s%s", strings.TrimPrefix(name, "."), finishedTaskDirSuffix))
+	if err := os.Rename(dir, finishedDir); err != nil {
+		return fmt.Errorf("could not rename task folder: %v", err)
+	}
+	return os.RemoveAll(finishedDir)
+}
+
+// removeFinishedTaskDirs removes leftovers of removeTaskDir in the given pull dir.
+func removeFinishedTaskDirs(pullDir string) error {
+	fileInfo, err := ioutil.ReadDir(pullDir)
 	if err != nil {
-		return fmt.Errorf("could not delete task file: %v", err)
+		return fmt.Errorf("could not read directories from %s: %v", pullDir, err)
+	}
+	for _, file := range fileInfo {
+		if file.IsDir() && strings.HasPrefix(file.Name(), ".") && strings.HasSuffix(file.Name(), finishedTaskDirSuffix) {
+			if err := os.RemoveAll(filepath.Join(pullDir, file.Name())); err != nil {
+				return fmt.Errorf("could not remove finished task folder %s: %v", file.Name(), err)
+			}
+		}
 	}
 	return nil
 }
@@ -0,0 +1,100 @@
+package tasks
+
+import (
+	"bytes"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/life-unlimited/podcastination-server/id3"
+	"github.com/life-unlimited/podcastination-server/stores"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestImportTaskDetails_IsValidTranscriptLanguages(t *testing.T) {
+	details := ImportTaskDetails{
+		PodcastKey:          "sermons",
+		SeasonKey:           "series",
+		MP3FileName:         "sermon.mp3",
+		TranscriptFileNames: []string{"sermon.vtt", "sermon.txt"},
+		TranscriptLanguages: map[string]string{"sermon.vtt": "en-us"},
+	}
+	_, err := details.IsValid()
+	assert.Nil(t, err, "language for a transcript should pass")
+	details.TranscriptLanguages["sermon.srt"] = "en-us"
+	_, err = details.IsValid()
+	assert.NotNil(t, err, "language for an unknown transcript should fail")
+	details.TranscriptLanguages = map[string]string{"sermon.vtt": ""}
+	_, err = details.IsValid()
+	assert.NotNil(t, err, "empty language should fail")
+}
+
+// mp3Frame creates an MPEG 1 layer III frame with 128 kbit/s at 44100 Hz.
+func mp3Frame() []byte {
+	frame := make([]byte, 417)
+	copy(frame, []byte{0xFF, 0xFB, 0x90, 0x00})
+	return frame
+}
+
+func TestImportJob_runTasksRecordsWarnings(t *testing.T) {
+	pullDir, err := ioutil.TempDir("", "pull")
+	if !assert.Nil(t, err, "creating pull dir should not fail") {
+		return
+	}
+	defer func() { _ = os.RemoveAll(pullDir) }()
+	podcastDir, err := ioutil.TempDir("", "podcasts")
+	if !assert.Nil(t, err, "creating podcast dir should not fail") {
+		return
+	}
+	defer func() { _ = os.RemoveAll(podcastDir) }()
+	// Create a task whose author differs from the id3 tag.
+	taskDir := filepath.Join(pullDir, "sermon")
+	assert.Nil(t, os.Mkdir(taskDir, 0744), "creating task dir should not fail")
+	mp3File := filepath.Join(taskDir, "sermon.mp3")
+	assert.Nil(t, ioutil.WriteFile(mp3File, bytes.Repeat(mp3Frame(), 100), 0644), "writing mp3 should not fail")
+	tag := id3.NewTag()
+	tag.SetText("TPE1", "John Doe")
+	assert.Nil(t, id3.WriteFile(mp3File, tag), "writing id3 tag should not fail")
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(taskDir, ImportTaskDetailsFileName), []byte(`{"podcast_key": "sermons",
+"season_key": "series", "mp3_file": "sermon.mp3", "title": "Grace", "author": "Jane Doe",
+"date": "2021-01-03T09:30:00Z"}`), 0644), "writing task details should not fail")
+	// Expect the import.
+	db, mock, err := sqlmock.New()
+	if !assert.Nil(t, err, "creating mock database should not fail") {
+		return
+	}
+	mock.ExpectQuery("from podcasts where key").WithArgs("sermons").WillReturnRows(sqlmock.NewRows([]string{"id",
+		"title", "subtitle", "language", "owner_id", "description", "keywords", "link", "image_location", "type", "key",
+		"feed_link", "guid", "locked", "funding", "persons", "categories", "explicit"}).
+		AddRow(1, "Sermons", nil, "en-us", 1, nil, nil, nil, nil, nil, "sermons", "https://example.com/feed", nil,
+			false, nil, nil, nil, false))
+	mock.ExpectQuery("from seasons").WithArgs("series", 1).WillReturnRows(sqlmock.NewRows([]string{"id", "title",
+		"subtitle", "description", "image_location", "podcast_id", "num", "key"}).
+		AddRow(2, "Series", nil, nil, nil, 1, 1, "series"))
+	mock.ExpectQuery("from episodes").WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO episodes").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectQuery("UPDATE episodes").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectCommit()
+	job := &ImportJob{
+		PullDir:    pullDir,
+		PodcastDir: podcastDir,
+		FailedDir:  filepath.Join(pullDir, ".failed"),
+		Store: ImportJobStores{
+			Podcasts: stores.PodcastStore{DB: db},
+			Seasons:  stores.SeasonStore{DB: db},
+			Episodes: stores.EpisodeStore{DB: db},
+			Chapters: stores.ChapterStore{DB: db},
+		},
+	}
+
+	assert.Nil(t, job.runTasks(nil), "running tasks should not fail")
+	assert.Nil(t, mock.ExpectationsWereMet(), "episode should be imported")
+	recorded, err := RecordedImportWarnings(job.FailedDir)
+	if assert.Nil(t, err, "getting warnings should not fail") && assert.Len(t, recorded, 1, "warnings should be recorded") {
+		assert.Equal(t, "sermon", recorded[0].Name, "task name should match")
+		assert.Equal(t, 7, recorded[0].EpisodeId, "id of the imported episode should be recorded")
+		assert.Len(t, recorded[0].Warnings, 1, "author conflict should be recorded")
+	}
+}
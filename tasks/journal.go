@@ -0,0 +1,140 @@
+package tasks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// importJournalFileName is the file name of the importJournal in the task directory. It is hidden in order to not be
+// confused with the task files.
+const importJournalFileName = ".import-journal.json"
+
+// importState is the state of an import that is recorded in the importJournal.
+type importState string
+
+const (
+	// importStateStarted means that the import has started. The episode might have been inserted in the still
+	// uncommitted transaction and files might have been copied to the podcast dir.
+	importStateStarted importState = "started"
+	// importStateCommitted means that the transaction was committed and only the task directory needs to be removed.
+	importStateCommitted importState = "committed"
+)
+
+// importJournal records the progress of an import in the task directory. As the task directory is only removed after
+// the import was committed, the journal allows detecting interrupted imports in the next run and either finishing or
+// rolling them back.
+type importJournal struct {
+	// path is the path of the journal file.
+	path string
+	// State is the current importState.
+	State importState `json:"state"`
+	// EpisodeId is the id of the inserted episode. It is set before the transaction is committed, so that a committed
+	// import can be detected by checking if the episode exists.
+	EpisodeId int `json:"episode_id"`
+	// CopiedFiles are the destinations of all files that were (or are being) copied to the podcast dir.
+	CopiedFiles []string `json:"copied_files"`
+}
+
+// newImportJournal creates a new importJournal for the given task directory with importStateStarted.
+func newImportJournal(taskDir string) *importJournal {
+	return &importJournal{
+		path:        filepath.Join(taskDir, importJournalFileName),
+		State:       importStateStarted,
+		CopiedFiles: make([]string, 0),
+	}
+}
+
+// readImportJournal reads the importJournal from the given task directory. If none exists, false is returned.
+func readImportJournal(taskDir string) (*importJournal, bool, error) {
+	path := filepath.Join(taskDir, importJournalFileName)
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("could not read import journal: %v", err)
+	}
+	journal := &importJournal{path: path}
+	err = json.Unmarshal(raw, journal)
+	if err != nil {
+		return nil, false, fmt.Errorf("could not parse import journal: %v", err)
+	}
+	return journal, true, nil
+}
+
+// save writes the journal. A temporary file is renamed in order to never leave a partially written journal.
+func (journal *importJournal) save() error {
+	raw, err := json.Marshal(journal)
+	if err != nil {
+		return fmt.Errorf("could not marshal import journal: %v", err)
+	}
+	tmpPath := journal.path + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("could not create import journal: %v", err)
+	}
+	if _, err = f.Write(raw); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("could not write import journal: %v", err)
+	}
+	if err = os.Rename(tmpPath, journal.path); err != nil {
+		return fmt.Errorf("could not rename import journal: %v", err)
+	}
+	return nil
+}
+
+// recordCopy records the given destination as copied and saves the journal. This must be called before copying.
+func (journal *importJournal) recordCopy(destination string) error {
+	journal.CopiedFiles = append(journal.CopiedFiles, destination)
+	return journal.save()
+}
+
+// rollbackFiles removes all copied files as well as their folders if empty and then removes the journal itself.
+func (journal *importJournal) rollbackFiles() error {
+	for i := len(journal.CopiedFiles) - 1; i >= 0; i-- {
+		copied := journal.CopiedFiles[i]
+		if err := os.Remove(copied); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("could not remove copied file %s: %v", copied, err)
+		}
+		// Remove folder if empty. If it is not, this fails which is fine.
+		_ = os.Remove(filepath.Dir(copied))
+	}
+	if err := os.Remove(journal.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("could not remove import journal: %v", err)
+	}
+	return nil
+}
+
+// JournaledEpisodeIds retrieves the ids of the episodes recorded in the import journals of the tasks in the given pull
+// dir. These belong to imports that were interrupted and are resumed with the next run.
+func JournaledEpisodeIds(pullDir string) (map[int]struct{}, error) {
+	fileInfo, err := ioutil.ReadDir(pullDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[int]struct{}{}, nil
+		}
+		return nil, fmt.Errorf("could not read directories from %s: %v", pullDir, err)
+	}
+	episodeIds := make(map[int]struct{})
+	for _, file := range fileInfo {
+		if !file.IsDir() {
+			continue
+		}
+		journal, found, err := readImportJournal(filepath.Join(pullDir, file.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("could not read import journal of %s: %v", file.Name(), err)
+		}
+		if found && journal.EpisodeId != 0 {
+			episodeIds[journal.EpisodeId] = struct{}{}
+		}
+	}
+	return episodeIds, nil
+}
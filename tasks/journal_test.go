@@ -0,0 +1,127 @@
+package tasks
+
+import (
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type ImportJournalTestSuite struct {
+	suite.Suite
+	taskDir    string
+	podcastDir string
+}
+
+func (suite *ImportJournalTestSuite) SetupTest() {
+	var err error
+	suite.taskDir, err = ioutil.TempDir("", "task")
+	suite.Require().Nil(err, "creating task dir should not fail")
+	suite.podcastDir, err = ioutil.TempDir("", "podcasts")
+	suite.Require().Nil(err, "creating podcast dir should not fail")
+}
+
+func (suite *ImportJournalTestSuite) TearDownTest() {
+	_ = os.RemoveAll(suite.taskDir)
+	_ = os.RemoveAll(suite.podcastDir)
+}
+
+func (suite *ImportJournalTestSuite) TestNotFound() {
+	_, found, err := readImportJournal(suite.taskDir)
+	suite.Require().Nilf(err, "reading should not fail but got %s", err)
+	suite.Assert().False(found, "journal should not be found")
+}
+
+func (suite *ImportJournalTestSuite) TestSaveAndRead() {
+	journal := newImportJournal(suite.taskDir)
+	journal.EpisodeId = 42
+	suite.Require().Nil(journal.recordCopy("/some/file.mp3"), "recording copy should not fail")
+	read, found, err := readImportJournal(suite.taskDir)
+	suite.Require().Nilf(err, "reading should not fail but got %s", err)
+	suite.Require().True(found, "journal should be found")
+	suite.Assert().Equal(importStateStarted, read.State, "state should match")
+	suite.Assert().Equal(42, read.EpisodeId, "episode id should match")
+	suite.Assert().Equal([]string{"/some/file.mp3"}, read.CopiedFiles, "copied files should match")
+}
+
+func (suite *ImportJournalTestSuite) TestRollbackFiles() {
+	episodeDir := filepath.Join(suite.podcastDir, "1", "episode")
+	suite.Require().Nil(os.MkdirAll(episodeDir, 0744), "creating episode dir should not fail")
+	journal := newImportJournal(suite.taskDir)
+	copied := filepath.Join(episodeDir, "file.mp3")
+	suite.Require().Nil(journal.recordCopy(copied), "recording copy should not fail")
+	suite.Require().Nil(ioutil.WriteFile(copied, []byte("mp3"), 0644), "writing copied file should not fail")
+	// Recorded but never copied.
+	suite.Require().Nil(journal.recordCopy(filepath.Join(episodeDir, "thumb.png")), "recording copy should not fail")
+
+	err := journal.rollbackFiles()
+	suite.Require().Nilf(err, "rollback should not fail but got %s", err)
+	_, err = os.Stat(episodeDir)
+	suite.Assert().True(os.IsNotExist(err), "empty episode dir should be removed")
+	_, found, _ := readImportJournal(suite.taskDir)
+	suite.Assert().False(found, "journal should be removed")
+}
+
+func (suite *ImportJournalTestSuite) TestJournaledEpisodeIds() {
+	interruptedDir := filepath.Join(suite.taskDir, "interrupted")
+	suite.Require().Nil(os.Mkdir(interruptedDir, 0744), "creating task dir should not fail")
+	journal := newImportJournal(interruptedDir)
+	journal.EpisodeId = 42
+	suite.Require().Nil(journal.save(), "saving should not fail")
+	// Interrupted before inserting the episode.
+	startedDir := filepath.Join(suite.taskDir, "started")
+	suite.Require().Nil(os.Mkdir(startedDir, 0744), "creating task dir should not fail")
+	suite.Require().Nil(newImportJournal(startedDir).save(), "saving should not fail")
+	// Not yet imported.
+	suite.Require().Nil(os.Mkdir(filepath.Join(suite.taskDir, "pending"), 0744), "creating task dir should not fail")
+
+	episodeIds, err := JournaledEpisodeIds(suite.taskDir)
+	suite.Require().Nilf(err, "retrieving should not fail but got %s", err)
+	suite.Assert().Equal(map[int]struct{}{42: {}}, episodeIds, "episode ids should match")
+}
+
+func Test_importJournal(t *testing.T) {
+	suite.Run(t, new(ImportJournalTestSuite))
+}
+
+type RemoveTaskDirTestSuite struct {
+	suite.Suite
+	pullDir string
+}
+
+func (suite *RemoveTaskDirTestSuite) SetupTest() {
+	var err error
+	suite.pullDir, err = ioutil.TempDir("", "pull")
+	suite.Require().Nil(err, "creating pull dir should not fail")
+}
+
+func (suite *RemoveTaskDirTestSuite) TearDownTest() {
+	_ = os.RemoveAll(suite.pullDir)
+}
+
+func (suite *RemoveTaskDirTestSuite) TestRemove() {
+	taskDir := filepath.Join(suite.pullDir, "task")
+	suite.Require().Nil(os.Mkdir(taskDir, 0744), "creating task dir should not fail")
+	err := removeTaskDir(taskDir)
+	suite.Require().Nilf(err, "removing should not fail but got %s", err)
+	entries, _ := ioutil.ReadDir(suite.pullDir)
+	suite.Assert().Len(entries, 0, "pull dir should be empty")
+}
+
+func (suite *RemoveTaskDirTestSuite) TestRemoveLeftovers() {
+	leftover := filepath.Join(suite.pullDir, ".task"+finishedTaskDirSuffix)
+	suite.Require().Nil(os.Mkdir(leftover, 0744), "creating leftover should not fail")
+	staged := filepath.Join(suite.pullDir, ".upload_staged")
+	suite.Require().Nil(os.Mkdir(staged, 0744), "creating staged dir should not fail")
+	err := removeFinishedTaskDirs(suite.pullDir)
+	suite.Require().Nilf(err, "removing should not fail but got %s", err)
+	_, err = os.Stat(leftover)
+	suite.Assert().True(os.IsNotExist(err), "leftover should be removed")
+	_, err = os.Stat(staged)
+	suite.Assert().Nil(err, "staged dir should be kept")
+}
+
+func Test_removeTaskDir(t *testing.T) {
+	suite.Run(t, new(RemoveTaskDirTestSuite))
+}
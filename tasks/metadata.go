@@ -0,0 +1,84 @@
+package tasks
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/life-unlimited/podcastination-server/id3"
+	"image"
+	_ "image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// id3CoverFileName is the file name the cover from an ID3 tag is extracted to in the task directory. It is hidden, so
+// that it is not considered a change of the task by the ImportWatcher.
+const id3CoverFileName = ".id3_cover.png"
+
+// applyID3Tag fills empty title, author, date and description of the given task details with the values from the given
+// ID3 tag. If no image is provided, the cover from the tag is extracted to the task directory and used instead.
+// Values that are provided in the task details as well as in the tag but differ are returned as warnings.
+func applyID3Tag(task *ImportTask, tag *id3.Tag) []string {
+	warnings := make([]string, 0)
+	details := &task.Details
+	// Text values.
+	textValues := []struct {
+		name  string
+		value *string
+		tag   string
+	}{
+		{name: "title", value: &details.Title, tag: tag.Title()},
+		{name: "author", value: &details.Author, tag: tag.Artist()},
+		{name: "description", value: &details.Description, tag: tag.Comment()},
+	}
+	for _, v := range textValues {
+		if v.tag == "" {
+			continue
+		}
+		if *v.value == "" {
+			*v.value = v.tag
+			continue
+		}
+		if strings.TrimSpace(*v.value) != v.tag {
+			warnings = append(warnings, fmt.Sprintf("%s %q from task details differs from %q in id3 tag", v.name,
+				*v.value, v.tag))
+		}
+	}
+	// Date.
+	if recordingTime, layout, ok := tag.RecordingTime(); ok {
+		if details.Date.IsZero() {
+			details.Date = recordingTime
+		} else if details.Date.UTC().Format(layout) != recordingTime.Format(layout) {
+			warnings = append(warnings, fmt.Sprintf("date %s from task details differs from %s in id3 tag",
+				details.Date.UTC().Format(layout), recordingTime.Format(layout)))
+		}
+	}
+	// Cover.
+	if details.ImageFileName == "" {
+		if picture, ok := tag.Picture(); ok {
+			if err := writeCoverAsPNG(picture, filepath.Join(task.BaseDir, id3CoverFileName)); err != nil {
+				warnings = append(warnings, fmt.Sprintf("could not use cover from id3 tag: %v", err))
+			} else {
+				details.ImageFileName = id3CoverFileName
+			}
+		}
+	}
+	return warnings
+}
+
+// writeCoverAsPNG writes the given picture as png to the given file. Other formats are converted.
+func writeCoverAsPNG(picture id3.Picture, file string) error {
+	if picture.MIMEType == "image/png" {
+		return ioutil.WriteFile(file, picture.Data, 0644)
+	}
+	img, _, err := image.Decode(bytes.NewReader(picture.Data))
+	if err != nil {
+		return fmt.Errorf("could not decode %s: %v", picture.MIMEType, err)
+	}
+	var buf bytes.Buffer
+	if err = png.Encode(&buf, img); err != nil {
+		return fmt.Errorf("could not encode png: %v", err)
+	}
+	return ioutil.WriteFile(file, buf.Bytes(), 0644)
+}
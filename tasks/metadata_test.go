@@ -0,0 +1,95 @@
+package tasks
+
+import (
+	"bytes"
+	"github.com/life-unlimited/podcastination-server/id3"
+	"github.com/stretchr/testify/suite"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type MetadataTestSuite struct {
+	suite.Suite
+	taskDir string
+}
+
+func (suite *MetadataTestSuite) SetupTest() {
+	var err error
+	suite.taskDir, err = ioutil.TempDir("", "task")
+	suite.Require().Nil(err, "creating task dir should not fail")
+}
+
+func (suite *MetadataTestSuite) TearDownTest() {
+	_ = os.RemoveAll(suite.taskDir)
+}
+
+// textFrame creates an utf-8 text frame with the given id and value.
+func textFrame(id, value string) id3.Frame {
+	return id3.Frame{ID: id, Data: append([]byte{3}, value...)}
+}
+
+// pictureFrame creates an APIC frame for a front cover with the given MIME type and data.
+func pictureFrame(mimeType string, data []byte) id3.Frame {
+	content := append([]byte{0}, mimeType...)
+	content = append(content, 0, id3.PictureTypeFrontCover, 0)
+	return id3.Frame{ID: "APIC", Data: append(content, data...)}
+}
+
+func (suite *MetadataTestSuite) TestFillsEmptyDetails() {
+	var jpegCover bytes.Buffer
+	suite.Require().Nil(jpeg.Encode(&jpegCover, image.NewRGBA(image.Rect(0, 0, 2, 2)), nil), "encoding should not fail")
+	tag := &id3.Tag{Version: 4, Frames: []id3.Frame{
+		textFrame("TIT2", "Sunday Service"),
+		textFrame("TPE1", "Jane Doe"),
+		textFrame("TDRC", "2021-01-03T09:30"),
+		{ID: "COMM", Data: []byte("\x03engiTunNORM\x00 0000")},
+		{ID: "COMM", Data: []byte("\x03eng\x00About grace")},
+		pictureFrame("image/jpeg", jpegCover.Bytes()),
+	}}
+	task := ImportTask{BaseDir: suite.taskDir}
+	warnings := applyID3Tag(&task, tag)
+	suite.Assert().Empty(warnings, "there should be no warnings")
+	suite.Assert().Equal("Sunday Service", task.Details.Title, "title should match")
+	suite.Assert().Equal("Jane Doe", task.Details.Author, "author should match")
+	suite.Assert().Equal("About grace", task.Details.Description, "description should match")
+	suite.Assert().Equal(time.Date(2021, 1, 3, 9, 30, 0, 0, time.UTC), task.Details.Date, "date should match")
+	suite.Require().Equal(id3CoverFileName, task.Details.ImageFileName, "cover should be used as image")
+	cover, err := os.Open(filepath.Join(suite.taskDir, id3CoverFileName))
+	suite.Require().Nil(err, "opening cover should not fail")
+	defer func() { _ = cover.Close() }()
+	_, err = png.Decode(cover)
+	suite.Assert().Nil(err, "cover should be converted to png")
+}
+
+func (suite *MetadataTestSuite) TestConflicts() {
+	tag := &id3.Tag{Version: 3, Frames: []id3.Frame{
+		textFrame("TIT2", "Sunday Service"),
+		textFrame("TPE1", "Jane Doe"),
+		textFrame("TYER", "2021"),
+		textFrame("TDAT", "0301"),
+		pictureFrame("image/png", []byte("invalid")),
+	}}
+	task := ImportTask{
+		BaseDir: suite.taskDir,
+		Details: ImportTaskDetails{
+			Title:         "Sunday Service",
+			Author:        "John Doe",
+			Date:          time.Date(2021, 1, 10, 0, 0, 0, 0, time.UTC),
+			ImageFileName: "thumb.png",
+		},
+	}
+	warnings := applyID3Tag(&task, tag)
+	suite.Assert().Len(warnings, 2, "should warn about author and date")
+	suite.Assert().Equal("John Doe", task.Details.Author, "author from task details should be kept")
+	suite.Assert().Equal("thumb.png", task.Details.ImageFileName, "image from task details should be kept")
+}
+
+func Test_metadata(t *testing.T) {
+	suite.Run(t, new(MetadataTestSuite))
+}
@@ -0,0 +1,98 @@
+package tasks
+
+import (
+	"github.com/life-unlimited/podcastination-server/stores"
+	"log"
+	"sync"
+	"time"
+)
+
+// maxPublishingInterval is the maximum duration the PublishingJob waits before checking for lifted embargoes again.
+const maxPublishingInterval = time.Hour
+
+// PublishingJob regenerates the feeds of podcasts exactly when the embargo of one of their episodes lifts. After
+// changing the publish date of an episode, Reschedule must be called.
+type PublishingJob struct {
+	episodes stores.EpisodeStore
+	// refreshFeed regenerates the feed of the podcast with the given id.
+	refreshFeed func(podcastId int) error
+	// lastRun is the time up to which lifted embargoes have been handled.
+	lastRun  time.Time
+	runMutex sync.Mutex
+	// reschedule is signaled when the next publish date might have changed.
+	reschedule chan struct{}
+}
+
+// NewPublishingJob creates a new PublishingJob that handles embargoes lifting after the given time.
+func NewPublishingJob(episodes stores.EpisodeStore, refreshFeed func(podcastId int) error,
+	since time.Time) *PublishingJob {
+	return &PublishingJob{
+		episodes:    episodes,
+		refreshFeed: refreshFeed,
+		lastRun:     since,
+		reschedule:  make(chan struct{}, 1),
+	}
+}
+
+func (job *PublishingJob) name() string {
+	return "PublishingJob"
+}
+
+// interval returns the duration until the next embargo lifts.
+func (job *PublishingJob) interval() time.Duration {
+	job.runMutex.Lock()
+	lastRun := job.lastRun
+	job.runMutex.Unlock()
+	next, ok, err := job.episodes.NextPublishAt(lastRun)
+	if err != nil {
+		log.Printf("could not retrieve next publish date: %v", err)
+		return maxPublishingInterval
+	}
+	if !ok {
+		return maxPublishingInterval
+	}
+	wait := time.Until(next)
+	if wait < 0 {
+		return 0
+	}
+	if wait > maxPublishingInterval {
+		return maxPublishingInterval
+	}
+	return wait
+}
+
+func (job *PublishingJob) rescheduled() <-chan struct{} {
+	return job.reschedule
+}
+
+// Reschedule makes the PublishingJob wait for the next embargo to lift again. It must be called after publish dates
+// have been changed.
+func (job *PublishingJob) Reschedule() {
+	select {
+	case job.reschedule <- struct{}{}:
+	default:
+		// Already pending.
+	}
+}
+
+// run regenerates the feeds of all podcasts with episodes whose embargo lifted since the last run. If they cannot be
+// retrieved, they are handled in the next run.
+func (job *PublishingJob) run() error {
+	job.runMutex.Lock()
+	defer job.runMutex.Unlock()
+	now := time.Now()
+	podcastIds, err := job.episodes.PodcastsPublishedBetween(job.lastRun, now)
+	if err != nil {
+		log.Printf("could not retrieve podcasts with published episodes: %v", err)
+		return nil
+	}
+	job.lastRun = now
+	for _, podcastId := range podcastIds {
+		if err = job.refreshFeed(podcastId); err != nil {
+			log.Printf("could not refresh podcast xml for podcast %d: %v", podcastId, err)
+			continue
+		}
+		log.Printf("published embargoed episodes of podcast %d", podcastId)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package tasks
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/life-unlimited/podcastination-server/stores"
+	"github.com/stretchr/testify/suite"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	nextPublishAtQuery            = "SELECT min(publish_at) FROM episodes WHERE publish_at > $1"
+	podcastsPublishedBetweenQuery = "select distinct seasons.podcast_id from episodes"
+)
+
+type PublishingJobTestSuite struct {
+	suite.Suite
+	db        *sql.DB
+	mock      sqlmock.Sqlmock
+	since     time.Time
+	refreshed []int
+	job       *PublishingJob
+}
+
+func (suite *PublishingJobTestSuite) SetupTest() {
+	var err error
+	suite.db, suite.mock, err = sqlmock.New()
+	suite.Require().Nil(err, "creating mock database should not fail")
+	suite.since = time.Now().Add(-time.Minute)
+	suite.refreshed = nil
+	suite.job = NewPublishingJob(stores.EpisodeStore{DB: suite.db}, func(podcastId int) error {
+		suite.refreshed = append(suite.refreshed, podcastId)
+		if podcastId == 2 {
+			return fmt.Errorf("refresh failed")
+		}
+		return nil
+	}, suite.since)
+}
+
+func (suite *PublishingJobTestSuite) TearDownTest() {
+	suite.Assert().Nil(suite.mock.ExpectationsWereMet(), "all mock expectations should be met")
+	_ = suite.db.Close()
+}
+
+// expectNextPublishAt expects NextPublishAt to be queried for lifted embargoes after the given time.
+func (suite *PublishingJobTestSuite) expectNextPublishAt(after interface{}, next interface{}) {
+	suite.mock.ExpectQuery(regexp.QuoteMeta(nextPublishAtQuery)).WithArgs(after).
+		WillReturnRows(sqlmock.NewRows([]string{"min"}).AddRow(next))
+}
+
+func (suite *PublishingJobTestSuite) TestIntervalWithoutEmbargoes() {
+	suite.expectNextPublishAt(suite.since, nil)
+	suite.Assert().Equal(maxPublishingInterval, suite.job.interval(), "maximum interval should be used")
+}
+
+func (suite *PublishingJobTestSuite) TestIntervalUntilNextEmbargo() {
+	suite.expectNextPublishAt(suite.since, time.Now().Add(10*time.Minute))
+	suite.Assert().InDelta(float64(10*time.Minute), float64(suite.job.interval()), float64(time.Second),
+		"interval should last until the embargo lifts")
+}
+
+func (suite *PublishingJobTestSuite) TestIntervalIsCapped() {
+	suite.expectNextPublishAt(suite.since, time.Now().Add(3*time.Hour))
+	suite.Assert().Equal(maxPublishingInterval, suite.job.interval(), "interval should be capped")
+}
+
+func (suite *PublishingJobTestSuite) TestIntervalForPastEmbargo() {
+	suite.expectNextPublishAt(suite.since, time.Now().Add(-time.Second))
+	suite.Assert().Equal(time.Duration(0), suite.job.interval(), "lifted embargo should be handled immediately")
+}
+
+func (suite *PublishingJobTestSuite) TestIntervalOnError() {
+	suite.mock.ExpectQuery(regexp.QuoteMeta(nextPublishAtQuery)).WillReturnError(fmt.Errorf("db down"))
+	suite.Assert().Equal(maxPublishingInterval, suite.job.interval(), "maximum interval should be used on errors")
+}
+
+func (suite *PublishingJobTestSuite) TestReschedule() {
+	suite.job.Reschedule()
+	suite.job.Reschedule()
+	select {
+	case <-suite.job.rescheduled():
+	default:
+		suite.Fail("reschedule should be signaled")
+	}
+	select {
+	case <-suite.job.rescheduled():
+		suite.Fail("reschedule should only be signaled once")
+	default:
+	}
+}
+
+func (suite *PublishingJobTestSuite) TestRun() {
+	suite.mock.ExpectQuery(regexp.QuoteMeta(podcastsPublishedBetweenQuery)).WithArgs(suite.since, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"podcast_id"}).AddRow(1).AddRow(2).AddRow(3))
+	suite.Require().Nil(suite.job.run(), "run should not fail")
+	suite.Assert().Equal([]int{1, 2, 3}, suite.refreshed, "feeds of all podcasts should be refreshed despite errors")
+	suite.Assert().True(suite.job.lastRun.After(suite.since), "last run should be updated")
+	// The next run only handles embargoes lifting afterwards.
+	suite.expectNextPublishAt(suite.job.lastRun, nil)
+	suite.job.interval()
+}
+
+func (suite *PublishingJobTestSuite) TestRunOnError() {
+	suite.mock.ExpectQuery(regexp.QuoteMeta(podcastsPublishedBetweenQuery)).WillReturnError(fmt.Errorf("db down"))
+	suite.Require().Nil(suite.job.run(), "run should not fail")
+	suite.Assert().Empty(suite.refreshed, "no feeds should be refreshed")
+	suite.Assert().Equal(suite.since, suite.job.lastRun, "podcasts should be handled in the next run")
+}
+
+func TestPublishingJob(t *testing.T) {
+	suite.Run(t, new(PublishingJobTestSuite))
+}
+
+// reschedulingTestJob is a reschedulingJob whose interval can be changed.
+type reschedulingTestJob struct {
+	mutex      sync.Mutex
+	wait       time.Duration
+	calls      int
+	reschedule chan struct{}
+	runs       chan struct{}
+}
+
+func (job *reschedulingTestJob) name() string {
+	return "reschedulingTestJob"
+}
+
+func (job *reschedulingTestJob) run() error {
+	select {
+	case job.runs <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (job *reschedulingTestJob) interval() time.Duration {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	job.calls++
+	return job.wait
+}
+
+func (job *reschedulingTestJob) rescheduled() <-chan struct{} {
+	return job.reschedule
+}
+
+func TestSchedulerReschedules(t *testing.T) {
+	job := &reschedulingTestJob{
+		wait:       time.Hour,
+		reschedule: make(chan struct{}, 1),
+		runs:       make(chan struct{}, 1),
+	}
+	scheduler := NewScheduler(SchedulingConfig{}, nil)
+	scheduler.ScheduleJob(job, false)
+	defer scheduler.Stop()
+	// Wait until the scheduler waits with the initial interval, which is also retrieved for logging.
+	for waiting := false; !waiting; time.Sleep(time.Millisecond) {
+		job.mutex.Lock()
+		waiting = job.calls >= 2
+		job.mutex.Unlock()
+	}
+	job.mutex.Lock()
+	job.wait = 10 * time.Millisecond
+	job.mutex.Unlock()
+	job.reschedule <- struct{}{}
+	select {
+	case <-job.runs:
+	case <-time.After(time.Second):
+		t.Error("job should run with the new interval after rescheduling")
+	}
+}
@@ -0,0 +1,248 @@
+package tasks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ImportErrorFileName is the file name of the ImportError that is written to quarantined tasks.
+const ImportErrorFileName = "error.json"
+
+// importAttemptsFileName is the file name of the importAttempts in the task directory.
+const importAttemptsFileName = ".import-attempts.json"
+
+// ImportWarningsFileName is the file name of the recorded ImportWarnings of scheduled imports in the failed dir.
+const ImportWarningsFileName = "import-warnings.json"
+
+// maxImportWarnings is the number of latest ImportWarnings that are kept in the ImportWarningsFileName.
+const maxImportWarnings = 100
+
+// maxRetryBackoff caps the exponential backoff for retrying failed tasks.
+const maxRetryBackoff = 24 * time.Hour
+
+// importAttempts records failed attempts of performing a task in its directory.
+type importAttempts struct {
+	Attempts    int       `json:"attempts"`
+	LastAttempt time.Time `json:"last_attempt"`
+	LastError   string    `json:"last_error"`
+}
+
+// ImportError is written to the ImportErrorFileName of quarantined tasks.
+type ImportError struct {
+	// Reason is the error of the last attempt.
+	Reason string `json:"reason"`
+	// Timestamp is the time of the last attempt.
+	Timestamp time.Time `json:"timestamp"`
+	// Attempts is the number of failed attempts.
+	Attempts int `json:"attempts"`
+}
+
+// FailedImportTask is a task that was moved to the failed dir.
+type FailedImportTask struct {
+	Name  string      `json:"name"`
+	Error ImportError `json:"error"`
+}
+
+// ImportWarnings are the warnings of a scheduled import. As the task directory is removed after the import, they are
+// recorded in the failed dir, so that they can be reviewed along with failed tasks.
+type ImportWarnings struct {
+	// Name is the name of the task directory.
+	Name      string    `json:"name"`
+	EpisodeId int       `json:"episode_id"`
+	Timestamp time.Time `json:"timestamp"`
+	Warnings  []string  `json:"warnings"`
+}
+
+// readImportAttempts reads the importAttempts from the given task directory. If none exist, empty ones are returned.
+func readImportAttempts(taskDir string) (importAttempts, error) {
+	raw, err := ioutil.ReadFile(filepath.Join(taskDir, importAttemptsFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return importAttempts{}, nil
+		}
+		return importAttempts{}, fmt.Errorf("could not read import attempts: %v", err)
+	}
+	var attempts importAttempts
+	if err = json.Unmarshal(raw, &attempts); err != nil {
+		return importAttempts{}, fmt.Errorf("could not parse import attempts: %v", err)
+	}
+	return attempts, nil
+}
+
+// writeJSONFile marshals the given value and writes it to the given path.
+func writeJSONFile(path string, v interface{}) error {
+	raw, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not marshal: %v", err)
+	}
+	return ioutil.WriteFile(path, raw, 0644)
+}
+
+// isDue checks if the task in the given directory can be attempted at the given time. Tasks that failed before are
+// retried with exponential backoff. If the attempts could not be read, the task is due.
+func (job *ImportJob) isDue(taskDir string, now time.Time) (bool, error) {
+	attempts, err := readImportAttempts(taskDir)
+	if err != nil {
+		return true, err
+	}
+	if attempts.Attempts == 0 {
+		return true, nil
+	}
+	return !now.Before(attempts.LastAttempt.Add(job.retryBackoff(attempts.Attempts))), nil
+}
+
+// retryBackoff returns the duration to wait after the given number of failed attempts.
+func (job *ImportJob) retryBackoff(attempts int) time.Duration {
+	backoff := job.RetryBackoff
+	for i := 1; i < attempts && backoff < maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxRetryBackoff {
+		return maxRetryBackoff
+	}
+	return backoff
+}
+
+// handleFailedAttempt records a failed attempt for the task in the given directory. If the maximum number of attempts
+// is reached, the task is moved to the failed dir. Errors are only logged.
+func (job *ImportJob) handleFailedAttempt(taskDir string, reason error) {
+	attempts, err := readImportAttempts(taskDir)
+	if err != nil {
+		log.Printf("could not read import attempts of %s: %v", taskDir, err)
+	}
+	attempts.Attempts++
+	attempts.LastAttempt = time.Now()
+	attempts.LastError = reason.Error()
+	if job.MaxAttempts > 0 && attempts.Attempts >= job.MaxAttempts {
+		if err = job.quarantine(taskDir, attempts); err != nil {
+			log.Printf("could not move failed task %s to %s: %v", taskDir, job.FailedDir, err)
+		} else {
+			log.Printf("moved task %s to %s after %d failed attempts", filepath.Base(taskDir), job.FailedDir,
+				attempts.Attempts)
+			return
+		}
+	}
+	if err = writeJSONFile(filepath.Join(taskDir, importAttemptsFileName), attempts); err != nil {
+		log.Printf("could not write import attempts of %s: %v", taskDir, err)
+	}
+}
+
+// quarantine moves the task in the given directory to the failed dir and writes the ImportError.
+func (job *ImportJob) quarantine(taskDir string, attempts importAttempts) error {
+	if job.FailedDir == "" {
+		return fmt.Errorf("no failed dir configured")
+	}
+	if err := os.MkdirAll(job.FailedDir, 0744); err != nil {
+		return fmt.Errorf("could not create failed dir: %v", err)
+	}
+	destination := filepath.Join(job.FailedDir, filepath.Base(taskDir))
+	if _, err := os.Stat(destination); err == nil {
+		destination = fmt.Sprintf("%s_%s", destination, attempts.LastAttempt.Format("20060102_150405"))
+	}
+	err := writeJSONFile(filepath.Join(taskDir, ImportErrorFileName), ImportError{
+		Reason:    attempts.LastError,
+		Timestamp: attempts.LastAttempt,
+		Attempts:  attempts.Attempts,
+	})
+	if err != nil {
+		return fmt.Errorf("could not write import error: %v", err)
+	}
+	_ = os.Remove(filepath.Join(taskDir, importAttemptsFileName))
+	if err = os.Rename(taskDir, destination); err != nil {
+		return fmt.Errorf("could not move task: %v", err)
+	}
+	return nil
+}
+
+// FailedImportTasks retrieves all tasks in the given failed dir.
+func FailedImportTasks(failedDir string) ([]FailedImportTask, error) {
+	fileInfo, err := ioutil.ReadDir(failedDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []FailedImportTask{}, nil
+		}
+		return nil, fmt.Errorf("could not read directories from %s: %v", failedDir, err)
+	}
+	failedTasks := make([]FailedImportTask, 0)
+	for _, file := range fileInfo {
+		if !file.IsDir() {
+			continue
+		}
+		failedTask := FailedImportTask{Name: file.Name()}
+		raw, err := ioutil.ReadFile(filepath.Join(failedDir, file.Name(), ImportErrorFileName))
+		if err == nil {
+			_ = json.Unmarshal(raw, &failedTask.Error)
+		}
+		failedTasks = append(failedTasks, failedTask)
+	}
+	return failedTasks, nil
+}
+
+// RequeueFailedImportTask moves the task with the given name from the failed dir back to the pull dir, so that it is
+// attempted again in the next run.
+func RequeueFailedImportTask(failedDir, pullDir, name string) error {
+	if name != filepath.Base(name) || name == "." || name == ".." {
+		return fmt.Errorf("invalid task name %s", name)
+	}
+	source := filepath.Join(failedDir, name)
+	if _, err := os.Stat(source); err != nil {
+		return fmt.Errorf("could not find failed task: %v", err)
+	}
+	destination := filepath.Join(pullDir, name)
+	if _, err := os.Stat(destination); err == nil {
+		return fmt.Errorf("task %s already exists in pull dir", name)
+	}
+	if err := os.Remove(filepath.Join(source, ImportErrorFileName)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("could not remove import error: %v", err)
+	}
+	if err := os.Rename(source, destination); err != nil {
+		return fmt.Errorf("could not move task: %v", err)
+	}
+	return nil
+}
+
+// recordImportWarnings adds the warnings of the given ImportReport for the task in the given directory to the
+// ImportWarningsFileName in the failed dir. Only the latest maxImportWarnings are kept.
+func (job *ImportJob) recordImportWarnings(taskDir string, report ImportReport, now time.Time) error {
+	if job.FailedDir == "" {
+		return fmt.Errorf("no failed dir configured")
+	}
+	recorded, err := RecordedImportWarnings(job.FailedDir)
+	if err != nil {
+		return err
+	}
+	recorded = append(recorded, ImportWarnings{
+		Name:      filepath.Base(taskDir),
+		EpisodeId: report.Episode.Id,
+		Timestamp: now,
+		Warnings:  report.Warnings,
+	})
+	if len(recorded) > maxImportWarnings {
+		recorded = recorded[len(recorded)-maxImportWarnings:]
+	}
+	if err = os.MkdirAll(job.FailedDir, 0744); err != nil {
+		return fmt.Errorf("could not create failed dir: %v", err)
+	}
+	return writeJSONFile(filepath.Join(job.FailedDir, ImportWarningsFileName), recorded)
+}
+
+// RecordedImportWarnings retrieves the ImportWarnings of the latest scheduled imports from the given failed dir.
+func RecordedImportWarnings(failedDir string) ([]ImportWarnings, error) {
+	raw, err := ioutil.ReadFile(filepath.Join(failedDir, ImportWarningsFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []ImportWarnings{}, nil
+		}
+		return nil, fmt.Errorf("could not read import warnings: %v", err)
+	}
+	recorded := make([]ImportWarnings, 0)
+	if err = json.Unmarshal(raw, &recorded); err != nil {
+		return nil, fmt.Errorf("could not parse import warnings: %v", err)
+	}
+	return recorded, nil
+}
@@ -0,0 +1,101 @@
+package tasks
+
+import (
+	"fmt"
+	"github.com/life-unlimited/podcastination-server/podcasts"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type QuarantineTestSuite struct {
+	suite.Suite
+	pullDir string
+	job     *ImportJob
+}
+
+func (suite *QuarantineTestSuite) SetupTest() {
+	var err error
+	suite.pullDir, err = ioutil.TempDir("", "pull")
+	suite.Require().Nil(err, "creating pull dir should not fail")
+	suite.job = &ImportJob{
+		PullDir:      suite.pullDir,
+		FailedDir:    filepath.Join(suite.pullDir, ".failed"),
+		MaxAttempts:  2,
+		RetryBackoff: time.Minute,
+	}
+}
+
+func (suite *QuarantineTestSuite) TearDownTest() {
+	_ = os.RemoveAll(suite.pullDir)
+}
+
+func (suite *QuarantineTestSuite) TestRetryBackoff() {
+	suite.Assert().Equal(time.Minute, suite.job.retryBackoff(1), "first retry should wait backoff")
+	suite.Assert().Equal(4*time.Minute, suite.job.retryBackoff(3), "backoff should double with each attempt")
+	suite.Assert().Equal(maxRetryBackoff, suite.job.retryBackoff(100), "backoff should be capped")
+}
+
+func (suite *QuarantineTestSuite) TestQuarantineAndRequeue() {
+	taskDir := filepath.Join(suite.pullDir, "task")
+	suite.Require().Nil(os.Mkdir(taskDir, 0744), "creating task dir should not fail")
+
+	suite.job.handleFailedAttempt(taskDir, fmt.Errorf("first"))
+	due, err := suite.job.isDue(taskDir, time.Now())
+	suite.Require().Nilf(err, "checking due should not fail but got %s", err)
+	suite.Assert().False(due, "task should not be due directly after failing")
+	due, _ = suite.job.isDue(taskDir, time.Now().Add(2*time.Minute))
+	suite.Assert().True(due, "task should be due after backoff")
+
+	suite.job.handleFailedAttempt(taskDir, fmt.Errorf("second"))
+	_, err = os.Stat(taskDir)
+	suite.Require().True(os.IsNotExist(err), "task should be moved after max attempts")
+	failedTasks, err := FailedImportTasks(suite.job.FailedDir)
+	suite.Require().Nilf(err, "getting failed tasks should not fail but got %s", err)
+	suite.Require().Len(failedTasks, 1, "should have one failed task")
+	suite.Assert().Equal("task", failedTasks[0].Name, "name should match")
+	suite.Assert().Equal("second", failedTasks[0].Error.Reason, "reason should be last error")
+	suite.Assert().Equal(2, failedTasks[0].Error.Attempts, "attempts should match")
+
+	err = RequeueFailedImportTask(suite.job.FailedDir, suite.pullDir, "task")
+	suite.Require().Nilf(err, "requeue should not fail but got %s", err)
+	due, _ = suite.job.isDue(taskDir, time.Now())
+	suite.Assert().True(due, "requeued task should be due")
+	_, err = os.Stat(filepath.Join(taskDir, ImportErrorFileName))
+	suite.Assert().True(os.IsNotExist(err), "import error should be removed")
+}
+
+func (suite *QuarantineTestSuite) TestRequeueInvalidName() {
+	err := RequeueFailedImportTask(suite.job.FailedDir, suite.pullDir, "../task")
+	suite.Assert().NotNil(err, "requeue should fail for invalid name")
+}
+
+func (suite *QuarantineTestSuite) TestRecordImportWarnings() {
+	now := time.Date(2021, 1, 3, 12, 0, 0, 0, time.UTC)
+	for i := 0; i <= maxImportWarnings; i++ {
+		err := suite.job.recordImportWarnings(filepath.Join(suite.pullDir, fmt.Sprintf("task%d", i)), ImportReport{
+			Episode:  podcasts.Episode{Id: i},
+			Warnings: []string{"title differs from id3 tag"},
+		}, now)
+		suite.Require().Nilf(err, "recording warnings should not fail but got %s", err)
+	}
+	recorded, err := RecordedImportWarnings(suite.job.FailedDir)
+	suite.Require().Nilf(err, "getting warnings should not fail but got %s", err)
+	suite.Require().Len(recorded, maxImportWarnings, "only the latest warnings should be kept")
+	suite.Assert().Equal(ImportWarnings{
+		Name:      fmt.Sprintf("task%d", maxImportWarnings),
+		EpisodeId: maxImportWarnings,
+		Timestamp: now,
+		Warnings:  []string{"title differs from id3 tag"},
+	}, recorded[len(recorded)-1], "latest warnings should match")
+	failedTasks, err := FailedImportTasks(suite.job.FailedDir)
+	suite.Require().Nilf(err, "getting failed tasks should not fail but got %s", err)
+	suite.Assert().Empty(failedTasks, "warnings should not be listed as failed tasks")
+}
+
+func Test_quarantine(t *testing.T) {
+	suite.Run(t, new(QuarantineTestSuite))
+}
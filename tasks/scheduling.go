@@ -31,6 +31,13 @@ type SchedulingJob interface {
 	interval() time.Duration
 }
 
+// reschedulingJob is a SchedulingJob whose interval might change while waiting for the next run. After a signal from
+// rescheduled, the interval is retrieved again.
+type reschedulingJob interface {
+	SchedulingJob
+	rescheduled() <-chan struct{}
+}
+
 func NewScheduler(config SchedulingConfig, db *sql.DB) *Scheduler {
 	return &Scheduler{
 		config: config,
@@ -53,12 +60,19 @@ func (s *Scheduler) ScheduleJob(j SchedulingJob, initialRun bool) {
 				log.Printf("%s initial run failed: %v", jobLogPrefix(myJob), err)
 			}
 		}
+		var rescheduled <-chan struct{}
+		if r, ok := j.(reschedulingJob); ok {
+			rescheduled = r.rescheduled()
+		}
 		alive := true
 		for alive {
 			select {
 			case <-newJob.stop:
 				alive = false
 				break
+			case <-rescheduled:
+				// Wait with the new interval.
+				break
 			case <-time.After(j.interval()):
 				if err := j.run(); err != nil {
 					log.Fatalf("%s could not run job: %v", jobLogPrefix(myJob), err)
@@ -0,0 +1,92 @@
+package tasks
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// StageImportTask creates a new task directory in the given pull dir which contains the given files as well as the
+// task details file. The directory is hidden so that it is not picked up by the ImportJob until EnqueueImportTask is
+// called. Files are provided by their file name which must match the ones referenced in the details.
+func StageImportTask(pullDir string, details ImportTaskDetails, files map[string]io.Reader) (ImportTask, error) {
+	if _, err := details.IsValid(); err != nil {
+		return ImportTask{}, fmt.Errorf("invalid task details: %v", err)
+	}
+	// Create the hidden task directory.
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return ImportTask{}, fmt.Errorf("could not read random bytes for task directory name: %v", err)
+	}
+	dirName := fmt.Sprintf(".upload_%s_%s", time.Now().Format("20060102_150405"), hex.EncodeToString(suffix))
+	baseDir := filepath.Join(pullDir, dirName)
+	err := os.Mkdir(baseDir, 0744)
+	if err != nil {
+		return ImportTask{}, fmt.Errorf("could not create task directory: %v", err)
+	}
+	task := ImportTask{
+		BaseDir: baseDir,
+		Details: details,
+	}
+	// Write the files.
+	for fileName, content := range files {
+		if fileName != filepath.Base(fileName) {
+			_ = os.RemoveAll(baseDir)
+			return ImportTask{}, fmt.Errorf("invalid file name %s", fileName)
+		}
+		err = writeStagedFile(filepath.Join(baseDir, fileName), content)
+		if err != nil {
+			_ = os.RemoveAll(baseDir)
+			return ImportTask{}, fmt.Errorf("could not write file %s: %v", fileName, err)
+		}
+	}
+	// Write the task details.
+	detailsRaw, err := json.MarshalIndent(details, "", "  ")
+	if err != nil {
+		_ = os.RemoveAll(baseDir)
+		return ImportTask{}, fmt.Errorf("could not marshal task details: %v", err)
+	}
+	err = ioutil.WriteFile(filepath.Join(baseDir, ImportTaskDetailsFileName), detailsRaw, 0644)
+	if err != nil {
+		_ = os.RemoveAll(baseDir)
+		return ImportTask{}, fmt.Errorf("could not write task details: %v", err)
+	}
+	return task, nil
+}
+
+// EnqueueImportTask makes the given task staged with StageImportTask visible to the ImportJob so that it is performed
+// in the next run.
+func EnqueueImportTask(task ImportTask) (ImportTask, error) {
+	dir, name := filepath.Split(task.BaseDir)
+	if !strings.HasPrefix(name, ".") {
+		return task, nil
+	}
+	enqueued := task
+	enqueued.BaseDir = filepath.Join(dir, strings.TrimPrefix(name, "."))
+	err := os.Rename(task.BaseDir, enqueued.BaseDir)
+	if err != nil {
+		return ImportTask{}, fmt.Errorf("could not rename task directory: %v", err)
+	}
+	return enqueued, nil
+}
+
+// writeStagedFile writes the content to the given path.
+func writeStagedFile(path string, content io.Reader) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create file: %v", err)
+	}
+	_, err = io.Copy(f, content)
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("write file: %v", err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,219 @@
+package tasks
+
+import (
+	"fmt"
+	"github.com/fsnotify/fsnotify"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ReadyMarkerFileName is the file name of the marker that can be placed in a task directory in order to signal that
+// it is complete and can be imported right away.
+const ReadyMarkerFileName = ".ready"
+
+// DefaultWatchStabilityDelay is the duration the files of a task directory must not change before it is considered
+// complete if not configured otherwise.
+const DefaultWatchStabilityDelay = 10 * time.Second
+
+// watchCheckInterval is the interval in which pending task directories are checked for stability.
+const watchCheckInterval = time.Second
+
+// ImportWatcher watches the pull dir of an ImportJob and runs the import for task directories as soon as they are
+// complete. A task directory is complete if it contains a ReadyMarkerFileName or if its ImportTaskDetailsFileName is
+// present and the sizes of all files did not change for the stability delay. Scheduled runs of the ImportJob are
+// still needed as fallback, for example for events that were missed.
+type ImportWatcher struct {
+	job            *ImportJob
+	stabilityDelay time.Duration
+	watcher        *fsnotify.Watcher
+	// pending holds the task directories that changed and are waiting to become stable.
+	pending map[string]*taskDirSnapshot
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// taskDirSnapshot holds the file sizes of a task directory and since when they did not change.
+type taskDirSnapshot struct {
+	sizes map[string]int64
+	since time.Time
+}
+
+// NewImportWatcher creates a new ImportWatcher for the given ImportJob. If the stability delay is zero,
+// DefaultWatchStabilityDelay is used.
+func NewImportWatcher(job *ImportJob, stabilityDelay time.Duration) *ImportWatcher {
+	if stabilityDelay == 0 {
+		stabilityDelay = DefaultWatchStabilityDelay
+	}
+	return &ImportWatcher{
+		job:            job,
+		stabilityDelay: stabilityDelay,
+		pending:        make(map[string]*taskDirSnapshot),
+		stop:           make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+}
+
+// Start starts watching the pull dir. Already existing task directories are checked as well.
+func (w *ImportWatcher) Start() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("could not create watcher: %v", err)
+	}
+	if err = watcher.Add(w.job.PullDir); err != nil {
+		_ = watcher.Close()
+		return fmt.Errorf("could not watch %s: %v", w.job.PullDir, err)
+	}
+	w.watcher = watcher
+	fileInfo, err := ioutil.ReadDir(w.job.PullDir)
+	if err != nil {
+		_ = watcher.Close()
+		return fmt.Errorf("could not read directories from %s: %v", w.job.PullDir, err)
+	}
+	for _, file := range fileInfo {
+		if file.IsDir() && !strings.HasPrefix(file.Name(), ".") {
+			w.watchTaskDir(filepath.Join(w.job.PullDir, file.Name()))
+		}
+	}
+	go w.loop()
+	log.Printf("watching %s for import tasks (stability delay: %v)", w.job.PullDir, w.stabilityDelay)
+	return nil
+}
+
+// Stop stops watching and waits until a currently running import is finished.
+func (w *ImportWatcher) Stop() {
+	close(w.stop)
+	<-w.done
+}
+
+// loop handles watcher events and checks pending task directories until stopped.
+func (w *ImportWatcher) loop() {
+	defer close(w.done)
+	defer func() { _ = w.watcher.Close() }()
+	ticker := time.NewTicker(watchCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			w.handleEvent(event)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("error while watching %s: %v", w.job.PullDir, err)
+		case now := <-ticker.C:
+			w.checkPending(now)
+		}
+	}
+}
+
+// handleEvent marks the task directory affected by the given event as pending. Newly created task directories are
+// watched as well, so that changes to their files are noticed.
+func (w *ImportWatcher) handleEvent(event fsnotify.Event) {
+	var taskDir string
+	switch filepath.Dir(event.Name) {
+	case filepath.Clean(w.job.PullDir):
+		taskDir = event.Name
+		if event.Op&fsnotify.Create == fsnotify.Create && !strings.HasPrefix(filepath.Base(taskDir), ".") {
+			if info, err := os.Stat(taskDir); err == nil && info.IsDir() {
+				w.watchTaskDir(taskDir)
+			}
+			return
+		}
+	default:
+		taskDir = filepath.Dir(event.Name)
+		if filepath.Dir(taskDir) != filepath.Clean(w.job.PullDir) {
+			return
+		}
+	}
+	if _, ok := w.pending[taskDir]; ok || !strings.HasPrefix(filepath.Base(taskDir), ".") {
+		// Reset, so that stability is measured from now on.
+		w.pending[taskDir] = nil
+	}
+}
+
+// watchTaskDir adds a watch for the given task directory and marks it as pending.
+func (w *ImportWatcher) watchTaskDir(taskDir string) {
+	if err := w.watcher.Add(taskDir); err != nil {
+		log.Printf("could not watch task directory %s: %v", taskDir, err)
+	}
+	w.pending[taskDir] = nil
+}
+
+// checkPending checks all pending task directories for stability and runs the import for the stable ones.
+func (w *ImportWatcher) checkPending(now time.Time) {
+	stable := make(map[string]struct{})
+	for taskDir, snapshot := range w.pending {
+		info, err := os.Stat(taskDir)
+		if err != nil || !info.IsDir() {
+			// Removed, renamed or no task directory.
+			delete(w.pending, taskDir)
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(taskDir, ReadyMarkerFileName)); err == nil {
+			stable[taskDir] = struct{}{}
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(taskDir, ImportTaskDetailsFileName)); err != nil {
+			continue
+		}
+		sizes, err := taskDirFileSizes(taskDir)
+		if err != nil {
+			log.Printf("could not read task directory %s: %v", taskDir, err)
+			continue
+		}
+		if snapshot == nil || !equalFileSizes(snapshot.sizes, sizes) {
+			w.pending[taskDir] = &taskDirSnapshot{sizes: sizes, since: now}
+			continue
+		}
+		if now.Sub(snapshot.since) >= w.stabilityDelay {
+			stable[taskDir] = struct{}{}
+		}
+	}
+	if len(stable) == 0 {
+		return
+	}
+	for taskDir := range stable {
+		delete(w.pending, taskDir)
+	}
+	if err := w.job.runTasks(stable); err != nil {
+		log.Printf("could not run import for watched task directories: %v", err)
+	}
+}
+
+// taskDirFileSizes returns the sizes of all non-hidden files in the given task directory. Hidden files are ignored as
+// they are written by the import itself.
+func taskDirFileSizes(taskDir string) (map[string]int64, error) {
+	fileInfo, err := ioutil.ReadDir(taskDir)
+	if err != nil {
+		return nil, err
+	}
+	sizes := make(map[string]int64, len(fileInfo))
+	for _, file := range fileInfo {
+		if !strings.HasPrefix(file.Name(), ".") {
+			sizes[file.Name()] = file.Size()
+		}
+	}
+	return sizes, nil
+}
+
+// equalFileSizes checks if the given file sizes are the same.
+func equalFileSizes(a, b map[string]int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, size := range a {
+		if otherSize, ok := b[name]; !ok || otherSize != size {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+package tasks
+
+import (
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type WatcherTestSuite struct {
+	suite.Suite
+	pullDir string
+	watcher *ImportWatcher
+}
+
+func (suite *WatcherTestSuite) SetupTest() {
+	var err error
+	suite.pullDir, err = ioutil.TempDir("", "pull")
+	suite.Require().Nil(err, "creating pull dir should not fail")
+	suite.watcher = NewImportWatcher(&ImportJob{
+		PullDir:      suite.pullDir,
+		MaxAttempts:  5,
+		RetryBackoff: time.Minute,
+	}, time.Minute)
+}
+
+func (suite *WatcherTestSuite) TearDownTest() {
+	_ = os.RemoveAll(suite.pullDir)
+}
+
+func (suite *WatcherTestSuite) TestWaitsForStability() {
+	taskDir := filepath.Join(suite.pullDir, "task")
+	suite.Require().Nil(os.Mkdir(taskDir, 0744), "creating task dir should not fail")
+	suite.Require().Nil(ioutil.WriteFile(filepath.Join(taskDir, ImportTaskDetailsFileName), []byte("{"), 0644))
+	suite.watcher.pending[taskDir] = nil
+	now := time.Now()
+
+	suite.watcher.checkPending(now)
+	suite.Require().NotNil(suite.watcher.pending[taskDir], "snapshot should be taken")
+	suite.Require().Nil(ioutil.WriteFile(filepath.Join(taskDir, "a.mp3"), []byte("data"), 0644))
+	suite.watcher.checkPending(now.Add(2 * time.Minute))
+	suite.Require().Contains(suite.watcher.pending, taskDir, "changed task should still be pending")
+	suite.watcher.checkPending(now.Add(2*time.Minute + 30*time.Second))
+	suite.Require().Contains(suite.watcher.pending, taskDir, "task should be pending until stability delay passed")
+
+	suite.watcher.checkPending(now.Add(4 * time.Minute))
+	suite.Assert().NotContains(suite.watcher.pending, taskDir, "stable task should not be pending anymore")
+	attempts, err := readImportAttempts(taskDir)
+	suite.Require().Nilf(err, "reading attempts should not fail but got %s", err)
+	suite.Assert().Equal(1, attempts.Attempts, "invalid task should have been attempted")
+}
+
+func (suite *WatcherTestSuite) TestReadyMarker() {
+	taskDir := filepath.Join(suite.pullDir, "task")
+	suite.Require().Nil(os.Mkdir(taskDir, 0744), "creating task dir should not fail")
+	suite.watcher.pending[taskDir] = nil
+
+	suite.watcher.checkPending(time.Now())
+	suite.Require().Contains(suite.watcher.pending, taskDir, "task without details should be pending")
+	suite.Require().Nil(ioutil.WriteFile(filepath.Join(taskDir, ReadyMarkerFileName), nil, 0644))
+	suite.watcher.checkPending(time.Now())
+	suite.Assert().NotContains(suite.watcher.pending, taskDir, "ready task should not be pending anymore")
+}
+
+func (suite *WatcherTestSuite) TestRemovedTaskDir() {
+	taskDir := filepath.Join(suite.pullDir, "task")
+	suite.watcher.pending[taskDir] = nil
+	suite.watcher.checkPending(time.Now())
+	suite.Assert().NotContains(suite.watcher.pending, taskDir, "removed task should not be pending anymore")
+}
+
+func Test_watcher(t *testing.T) {
+	suite.Run(t, new(WatcherTestSuite))
+}
@@ -0,0 +1,144 @@
+// Package transcripts is used for validating transcript files of episodes.
+package transcripts
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// MIME types of the supported transcript formats.
+const (
+	MIMETypeSRT    = "application/x-subrip"
+	MIMETypeWebVTT = "text/vtt"
+	MIMETypeText   = "text/plain"
+)
+
+// mimeTypes holds the MIME type for each supported file extension.
+var mimeTypes = map[string]string{
+	".srt": MIMETypeSRT,
+	".vtt": MIMETypeWebVTT,
+	".txt": MIMETypeText,
+}
+
+// Extensions returns the file extensions of all supported transcript formats.
+func Extensions() []string {
+	return []string{".srt", ".vtt", ".txt"}
+}
+
+// MIMETypeForFileName returns the MIME type of the transcript with the given file name. If the format is not
+// supported, false is returned.
+func MIMETypeForFileName(fileName string) (string, bool) {
+	mimeType, ok := mimeTypes[strings.ToLower(filepath.Ext(fileName))]
+	return mimeType, ok
+}
+
+// IsCaptions checks if the given MIME type is a format with timing information which can be used as captions.
+func IsCaptions(mimeType string) bool {
+	return mimeType == MIMETypeSRT || mimeType == MIMETypeWebVTT
+}
+
+var (
+	srtTimingRegex    = regexp.MustCompile(`^\d{2,}:\d{2}:\d{2},\d{3} --> \d{2,}:\d{2}:\d{2},\d{3}`)
+	webVTTTimingRegex = regexp.MustCompile(`^(\d{2,}:)?\d{2}:\d{2}\.\d{3} --> (\d{2,}:)?\d{2}:\d{2}\.\d{3}`)
+)
+
+// Validate validates the given transcript file according to its format and returns its MIME type.
+func Validate(file string) (string, error) {
+	mimeType, ok := MIMETypeForFileName(file)
+	if !ok {
+		return "", fmt.Errorf("unsupported transcript format %s", filepath.Ext(file))
+	}
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("could not read transcript file: %v", err)
+	}
+	// Strip byte order mark.
+	content = bytes.TrimPrefix(content, []byte("\xEF\xBB\xBF"))
+	if !utf8.Valid(content) {
+		return "", fmt.Errorf("transcript is not valid utf-8")
+	}
+	if len(bytes.TrimSpace(content)) == 0 {
+		return "", fmt.Errorf("transcript is empty")
+	}
+	switch mimeType {
+	case MIMETypeSRT:
+		err = validateSRT(content)
+	case MIMETypeWebVTT:
+		err = validateWebVTT(content)
+	}
+	if err != nil {
+		return "", fmt.Errorf("invalid transcript: %v", err)
+	}
+	return mimeType, nil
+}
+
+// validateSRT checks that the given content consists of cues with a number, timing and text.
+func validateSRT(content []byte) error {
+	blocks := splitBlocks(content)
+	for i, block := range blocks {
+		if len(block) < 2 {
+			return fmt.Errorf("cue %d is incomplete", i+1)
+		}
+		if !srtTimingRegex.MatchString(block[1]) {
+			return fmt.Errorf("cue %d has invalid timing %q", i+1, block[1])
+		}
+	}
+	return nil
+}
+
+// validateWebVTT checks that the given content starts with the WEBVTT header and that all cues have valid timings.
+func validateWebVTT(content []byte) error {
+	blocks := splitBlocks(content)
+	if len(blocks) == 0 || !strings.HasPrefix(blocks[0][0], "WEBVTT") {
+		return fmt.Errorf("missing WEBVTT header")
+	}
+	cues := 0
+	for _, block := range blocks[1:] {
+		// Notes, styles and regions are no cues.
+		if strings.HasPrefix(block[0], "NOTE") || block[0] == "STYLE" || block[0] == "REGION" {
+			continue
+		}
+		// The cue identifier is optional.
+		timing := block[0]
+		if !strings.Contains(timing, "-->") && len(block) > 1 {
+			timing = block[1]
+		}
+		if !webVTTTimingRegex.MatchString(timing) {
+			return fmt.Errorf("cue %d has invalid timing %q", cues+1, timing)
+		}
+		cues++
+	}
+	if cues == 0 {
+		return fmt.Errorf("no cues found")
+	}
+	return nil
+}
+
+// splitBlocks splits the given content into blocks of non-empty lines separated by empty lines.
+func splitBlocks(content []byte) [][]string {
+	var blocks [][]string
+	var block []string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(content)+1)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			if len(block) > 0 {
+				blocks = append(blocks, block)
+				block = nil
+			}
+			continue
+		}
+		block = append(block, line)
+	}
+	if len(block) > 0 {
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
@@ -0,0 +1,65 @@
+package transcripts
+
+import (
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type TranscriptsTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func (suite *TranscriptsTestSuite) SetupTest() {
+	var err error
+	suite.dir, err = ioutil.TempDir("", "transcripts")
+	suite.Require().Nil(err, "creating temp dir should not fail")
+}
+
+func (suite *TranscriptsTestSuite) TearDownTest() {
+	_ = os.RemoveAll(suite.dir)
+}
+
+// validate writes the given content to a file with the given name and validates it.
+func (suite *TranscriptsTestSuite) validate(name, content string) (string, error) {
+	path := filepath.Join(suite.dir, name)
+	suite.Require().Nil(ioutil.WriteFile(path, []byte(content), 0644), "writing file should not fail")
+	return Validate(path)
+}
+
+func (suite *TranscriptsTestSuite) TestSRT() {
+	mimeType, err := suite.validate("a.srt", "1\r\n00:00:01,000 --> 00:00:04,000\r\nWelcome!\r\n\r\n"+
+		"2\r\n00:00:05,000 --> 00:00:08,500\r\nLet us pray.\r\n")
+	suite.Require().Nilf(err, "validating should not fail but got %v", err)
+	suite.Assert().Equal(MIMETypeSRT, mimeType, "mime type should match")
+	_, err = suite.validate("b.srt", "1\n00:00:01.000 --> 00:00:04.000\nWelcome!\n")
+	suite.Assert().NotNil(err, "validating should fail for invalid timing")
+}
+
+func (suite *TranscriptsTestSuite) TestWebVTT() {
+	mimeType, err := suite.validate("a.vtt", "\xEF\xBB\xBFWEBVTT\n\nNOTE first cue\n\nintro\n00:01.000 --> 00:04.000\n"+
+		"Welcome!\n\n01:00:05.000 --> 01:00:08.000 align:start\nAmen.\n")
+	suite.Require().Nilf(err, "validating should not fail but got %v", err)
+	suite.Assert().Equal(MIMETypeWebVTT, mimeType, "mime type should match")
+	_, err = suite.validate("b.vtt", "00:01.000 --> 00:04.000\nWelcome!\n")
+	suite.Assert().NotNil(err, "validating should fail for missing header")
+	_, err = suite.validate("c.vtt", "WEBVTT\n")
+	suite.Assert().NotNil(err, "validating should fail for missing cues")
+}
+
+func (suite *TranscriptsTestSuite) TestText() {
+	mimeType, err := suite.validate("a.txt", "Welcome to our service.")
+	suite.Require().Nilf(err, "validating should not fail but got %v", err)
+	suite.Assert().Equal(MIMETypeText, mimeType, "mime type should match")
+	_, err = suite.validate("b.txt", "\xff\xfe")
+	suite.Assert().NotNil(err, "validating should fail for invalid utf-8")
+	_, err = suite.validate("c.doc", "Welcome")
+	suite.Assert().NotNil(err, "validating should fail for unsupported format")
+}
+
+func Test_transcripts(t *testing.T) {
+	suite.Run(t, new(TranscriptsTestSuite))
+}
@@ -0,0 +1,138 @@
+package transfer
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStorage is a Storage in a directory of the local filesystem like the podcast directory.
+type LocalStorage struct {
+	// Dir is the root directory.
+	Dir string
+	// BaseURL is the url the root directory is publicly accessible with, for example the static content url.
+	BaseURL string
+}
+
+// path returns the filesystem path for the given key.
+func (s *LocalStorage) path(key string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.Dir, filepath.FromSlash(key)), nil
+}
+
+// Put writes the given content to a temporary file first which then replaces the object, so that readers never see
+// partially written objects.
+func (s *LocalStorage) Put(key string, content io.Reader) error {
+	file, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(file), 0744); err != nil {
+		return fmt.Errorf("could not create directory: %v", err)
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(file), fmt.Sprintf(".%s.*.tmp", filepath.Base(file)))
+	if err != nil {
+		return fmt.Errorf("could not create temporary file: %v", err)
+	}
+	// Removing fails after successful renaming which is fine.
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err = io.Copy(tmp, content); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("could not write content: %v", err)
+	}
+	if err = tmp.Chmod(0644); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("could not set file mode: %v", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("could not close temporary file: %v", err)
+	}
+	if err = os.Rename(tmp.Name(), file); err != nil {
+		return fmt.Errorf("could not replace file: %v", err)
+	}
+	return nil
+}
+
+func (s *LocalStorage) Get(key string) (io.ReadCloser, error) {
+	file, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return nil, ErrObjectNotFound
+	}
+	return f, err
+}
+
+func (s *LocalStorage) Stat(key string) (ObjectInfo, error) {
+	file, err := s.path(key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	info, err := os.Stat(file)
+	if os.IsNotExist(err) || (err == nil && info.IsDir()) {
+		return ObjectInfo{}, ErrObjectNotFound
+	}
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	return localObjectInfo(key, info), nil
+}
+
+func (s *LocalStorage) Delete(key string) error {
+	file, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(file); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStorage) List(prefix string) ([]ObjectInfo, error) {
+	objects := make([]ObjectInfo, 0)
+	err := filepath.Walk(s.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(s.Dir, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, localObjectInfo(key, info))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not walk directory: %v", err)
+	}
+	return objects, nil
+}
+
+func (s *LocalStorage) PublicURL(key string) string {
+	return fmt.Sprintf("%s/%s", strings.TrimRight(s.BaseURL, "/"), strings.TrimLeft(key, "/"))
+}
+
+// localObjectInfo creates the ObjectInfo for the file with the given key and os.FileInfo. The ETag is created from
+// modification time and size.
+func localObjectInfo(key string, info os.FileInfo) ObjectInfo {
+	return ObjectInfo{
+		Key:     key,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		ETag:    fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()),
+	}
+}
@@ -0,0 +1,93 @@
+package transfer
+
+import (
+	"fmt"
+	"github.com/life-unlimited/podcastination-server/id3"
+	"github.com/life-unlimited/podcastination-server/podcasts"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetagEpisodeAudio replaces the ID3 tag of the mp3 file of the given episode with one created from the episode, its
+// season and chapters. The cover is taken from the episode image or, if there is none, from the season image. The
+// audio frames are kept as they are. Files in other formats are not changed. The new file size is returned.
+func RetagEpisodeAudio(podcastDir string, episode podcasts.Episode, season podcasts.Season,
+	chapters []podcasts.Chapter) (int64, error) {
+	if episode.MP3Location == "" {
+		return 0, fmt.Errorf("episode %d has no audio file", episode.Id)
+	}
+	file := filepath.Join(podcastDir, episode.MP3Location)
+	if strings.ToLower(filepath.Ext(file)) == ".mp3" {
+		tag := episodeTag(podcastDir, episode, season, chapters)
+		if err := id3.WriteFile(file, tag); err != nil {
+			return 0, fmt.Errorf("could not write id3 tag: %v", err)
+		}
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return 0, fmt.Errorf("could not stat audio file: %v", err)
+	}
+	return info.Size(), nil
+}
+
+// episodeTag creates the ID3 tag for the given episode.
+func episodeTag(podcastDir string, episode podcasts.Episode, season podcasts.Season,
+	chapters []podcasts.Chapter) *id3.Tag {
+	tag := id3.NewTag()
+	tag.SetText("TIT2", episode.Title)
+	tag.SetText("TPE1", episode.Author)
+	tag.SetText("TALB", episode.FeedSeason(season).Title)
+	if episode.Num > 0 {
+		tag.SetText("TRCK", strconv.Itoa(episode.Num))
+	}
+	tag.SetText("TYER", strconv.Itoa(episode.Date.Year()))
+	// Cover.
+	coverLocation := episode.ImageLocation
+	if coverLocation == "" {
+		coverLocation = season.ImageLocation
+	}
+	if coverLocation != "" {
+		cover, err := ioutil.ReadFile(filepath.Join(podcastDir, coverLocation))
+		if err == nil {
+			tag.SetPicture(id3.Picture{
+				MIMEType: http.DetectContentType(cover),
+				Type:     id3.PictureTypeFrontCover,
+				Data:     cover,
+			})
+		} else {
+			// A missing cover is reported by the integrity check, so the tag is written without it.
+			log.Printf("could not read cover %s for episode %d: %v", coverLocation, episode.Id, err)
+		}
+	}
+	// Chapters end where the next one starts or with the episode.
+	sorted := append([]podcasts.Chapter{}, chapters...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].StartTime < sorted[j].StartTime
+	})
+	tagChapters := make([]id3.Chapter, 0, len(sorted))
+	for i, chapter := range sorted {
+		end := time.Duration(episode.MP3Length) * time.Second
+		if i+1 < len(sorted) {
+			end = secondsDuration(sorted[i+1].StartTime)
+		}
+		tagChapters = append(tagChapters, id3.Chapter{
+			Start: secondsDuration(chapter.StartTime),
+			End:   end,
+			Title: chapter.Title,
+		})
+	}
+	tag.SetChapters(tagChapters)
+	return tag
+}
+
+// secondsDuration converts the given seconds to a time.Duration.
+func secondsDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
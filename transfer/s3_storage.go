@@ -0,0 +1,352 @@
+package transfer
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// s3DateLayout is the layout of the x-amz-date header.
+	s3DateLayout = "20060102T150405Z"
+	// s3UnsignedPayload is used as payload hash for uploads, so that they do not need to be read twice.
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+	// s3EmptyPayloadHash is the SHA-256 hash of an empty payload.
+	s3EmptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// S3Storage is a Storage in a bucket of an S3-compatible object storage like AWS S3 or MinIO. Requests use path-style
+// addressing and are signed with AWS Signature Version 4.
+type S3Storage struct {
+	// Endpoint is the url of the object storage, for example http://127.0.0.1:9000.
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// Prefix is prepended to all keys, so that multiple instances can share a bucket.
+	Prefix string
+	// BaseURL is the url the bucket (including the prefix) is publicly accessible with, for example the one of a CDN.
+	// If empty, objects are accessed via the Endpoint.
+	BaseURL string
+	// Client performs the requests. If nil, http.DefaultClient is used.
+	Client *http.Client
+	// now returns the current time for signing and is replaced in tests.
+	now func() time.Time
+}
+
+// s3ListResult is the response of ListObjectsV2.
+type s3ListResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		LastModified time.Time `xml:"LastModified"`
+		ETag         string    `xml:"ETag"`
+		Size         int64     `xml:"Size"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// s3Error is the error response of the object storage.
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+func (s *S3Storage) Put(key string, content io.Reader) error {
+	// The content length is required, so the size must be known.
+	body, size, err := sizedReader(content)
+	if err != nil {
+		return fmt.Errorf("could not determine content size: %v", err)
+	}
+	if size == 0 {
+		body = http.NoBody
+	}
+	req, err := s.newRequest(http.MethodPut, key, nil, body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	res, err := s.do(req, s3UnsignedPayload)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+func (s *S3Storage) Get(key string) (io.ReadCloser, error) {
+	req, err := s.newRequest(http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.do(req, s3EmptyPayloadHash)
+	if err != nil {
+		return nil, err
+	}
+	return res.Body, nil
+}
+
+func (s *S3Storage) Stat(key string) (ObjectInfo, error) {
+	req, err := s.newRequest(http.MethodHead, key, nil, nil)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	res, err := s.do(req, s3EmptyPayloadHash)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	_ = res.Body.Close()
+	modTime, _ := http.ParseTime(res.Header.Get("Last-Modified"))
+	return ObjectInfo{
+		Key:     key,
+		Size:    res.ContentLength,
+		ModTime: modTime,
+		ETag:    res.Header.Get("ETag"),
+	}, nil
+}
+
+func (s *S3Storage) Delete(key string) error {
+	req, err := s.newRequest(http.MethodDelete, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	res, err := s.do(req, s3EmptyPayloadHash)
+	if err == ErrObjectNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+func (s *S3Storage) List(prefix string) ([]ObjectInfo, error) {
+	objects := make([]ObjectInfo, 0)
+	continuationToken := ""
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", s.Prefix+prefix)
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+		req, err := s.newRequest(http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		res, err := s.do(req, s3EmptyPayloadHash)
+		if err != nil {
+			return nil, err
+		}
+		var result s3ListResult
+		err = xml.NewDecoder(res.Body).Decode(&result)
+		_ = res.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("could not parse list result: %v", err)
+		}
+		for _, content := range result.Contents {
+			objects = append(objects, ObjectInfo{
+				Key:     strings.TrimPrefix(content.Key, s.Prefix),
+				Size:    content.Size,
+				ModTime: content.LastModified,
+				ETag:    content.ETag,
+			})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		continuationToken = result.NextContinuationToken
+	}
+}
+
+func (s *S3Storage) PublicURL(key string) string {
+	key = strings.TrimLeft(key, "/")
+	if s.BaseURL != "" {
+		return fmt.Sprintf("%s/%s", strings.TrimRight(s.BaseURL, "/"), key)
+	}
+	return fmt.Sprintf("%s/%s/%s%s", strings.TrimRight(s.Endpoint, "/"), s.Bucket, s.Prefix, key)
+}
+
+// newRequest creates a request for the object with the given key. If the key is empty, the request is for the bucket.
+func (s *S3Storage) newRequest(method, key string, query url.Values, body io.Reader) (*http.Request, error) {
+	path := "/" + s.Bucket
+	if key != "" {
+		cleaned, err := cleanKey(key)
+		if err != nil {
+			return nil, err
+		}
+		path = fmt.Sprintf("%s/%s%s", path, s.Prefix, cleaned)
+	}
+	endpoint, err := url.Parse(s.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint: %v", err)
+	}
+	u := &url.URL{
+		Scheme:   endpoint.Scheme,
+		Host:     endpoint.Host,
+		Path:     path,
+		RawPath:  s3EscapePath(path),
+		RawQuery: s3CanonicalQuery(query),
+	}
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("could not create request: %v", err)
+	}
+	return req, nil
+}
+
+// do signs and performs the given request. Responses with an error status are returned as error while
+// http.StatusNotFound results in ErrObjectNotFound. The caller must close the body of successful responses.
+func (s *S3Storage) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	s.sign(req, payloadHash)
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not perform %s request: %v", req.Method, err)
+	}
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return res, nil
+	}
+	defer func() { _ = res.Body.Close() }()
+	if res.StatusCode == http.StatusNotFound {
+		return nil, ErrObjectNotFound
+	}
+	var s3Err s3Error
+	if err = xml.NewDecoder(res.Body).Decode(&s3Err); err != nil || s3Err.Code == "" {
+		return nil, fmt.Errorf("%s request failed with status %d", req.Method, res.StatusCode)
+	}
+	return nil, fmt.Errorf("%s request failed with status %d: %s: %s", req.Method, res.StatusCode, s3Err.Code,
+		s3Err.Message)
+}
+
+// sign signs the given request with AWS Signature Version 4 using the given hash of the payload. The host, range,
+// content-type, content-md5 and x-amz-* headers are signed.
+func (s *S3Storage) sign(req *http.Request, payloadHash string) {
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	t := now().UTC()
+	date := t.Format(s3DateLayout)
+	req.Header.Set("X-Amz-Date", date)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	// Canonical headers.
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if name == "range" || name == "content-type" || name == "content-md5" || strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(fmt.Sprintf("%s:%s\n", name, headers[name]))
+	}
+	signedHeaders := strings.Join(names, ";")
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	// String to sign.
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", t.Format("20060102"), s.Region)
+	canonicalRequestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		date,
+		scope,
+		hex.EncodeToString(canonicalRequestHash[:]),
+	}, "\n")
+	// Signature.
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), t.Format("20060102"))
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKey, scope, signedHeaders, signature))
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of the given data with the given key.
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3Escape escapes the given string as required for signing. Only unreserved characters are kept.
+func s3Escape(s string, keepSlash bool) string {
+	var buf strings.Builder
+	for _, b := range []byte(s) {
+		if (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9') ||
+			b == '-' || b == '.' || b == '_' || b == '~' || (keepSlash && b == '/') {
+			buf.WriteByte(b)
+			continue
+		}
+		buf.WriteString(fmt.Sprintf("%%%02X", b))
+	}
+	return buf.String()
+}
+
+// s3EscapePath escapes the given path with each element being escaped.
+func s3EscapePath(path string) string {
+	return s3Escape(path, true)
+}
+
+// s3CanonicalQuery creates the canonical query string with escaped and sorted parameters.
+func s3CanonicalQuery(query url.Values) string {
+	params := make([]string, 0, len(query))
+	for name, values := range query {
+		for _, value := range values {
+			params = append(params, fmt.Sprintf("%s=%s", s3Escape(name, false), s3Escape(value, false)))
+		}
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+// sizedReader returns a reader for the given content along with its size. Files and other seekable readers are used
+// directly, others are read into memory.
+func sizedReader(content io.Reader) (io.Reader, int64, error) {
+	if seeker, ok := content.(io.ReadSeeker); ok {
+		start, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, 0, err
+		}
+		end, err := seeker.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, 0, err
+		}
+		if _, err = seeker.Seek(start, io.SeekStart); err != nil {
+			return nil, 0, err
+		}
+		// Wrapped, so that the http client does not close it.
+		return ioutil.NopCloser(seeker), end - start, nil
+	}
+	buf, err := ioutil.ReadAll(content)
+	if err != nil {
+		return nil, 0, err
+	}
+	return bytes.NewReader(buf), int64(len(buf)), nil
+}
@@ -0,0 +1,155 @@
+package transfer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ErrObjectNotFound is returned by a Storage if the requested object does not exist.
+var ErrObjectNotFound = errors.New("object not found")
+
+// ObjectInfo describes an object in a Storage.
+type ObjectInfo struct {
+	// Key is the slash separated path of the object relative to the root of the Storage.
+	Key     string
+	Size    int64
+	ModTime time.Time
+	// ETag identifies the content of the object. It might be empty if the Storage does not provide one.
+	ETag string
+}
+
+// Storage stores objects like media files and feeds by slash separated keys as used for locations of episodes, for
+// example 1/2/episode.mp3.
+type Storage interface {
+	// Put stores the given content with the given key. An existing object is replaced.
+	Put(key string, content io.Reader) error
+	// Get opens the object with the given key. The caller must close it.
+	Get(key string) (io.ReadCloser, error)
+	// Stat retrieves the ObjectInfo of the object with the given key.
+	Stat(key string) (ObjectInfo, error)
+	// Delete deletes the object with the given key. Deleting a non-existent object does not fail.
+	Delete(key string) error
+	// List retrieves all objects whose keys start with the given prefix.
+	List(prefix string) ([]ObjectInfo, error)
+	// PublicURL returns the url the object with the given key is publicly accessible with.
+	PublicURL(key string) string
+}
+
+// SyncStorage makes the objects with the given prefix in dst match the ones in src. Objects are copied if they are
+// missing in dst, differ in size or are newer in src. Objects that only exist in dst are only deleted if deleteExtra is
+// set. As an empty src with an empty prefix usually means a misconfiguration, nothing is deleted then. Hidden objects
+// like temporary files are ignored. The number of changed objects is returned.
+func SyncStorage(src, dst Storage, prefix string, deleteExtra bool) (int, error) {
+	srcObjects, err := src.List(prefix)
+	if err != nil {
+		return 0, fmt.Errorf("could not list source objects: %v", err)
+	}
+	dstObjects, err := dst.List(prefix)
+	if err != nil {
+		return 0, fmt.Errorf("could not list destination objects: %v", err)
+	}
+	existing := make(map[string]ObjectInfo, len(dstObjects))
+	for _, object := range dstObjects {
+		existing[object.Key] = object
+	}
+	changed := 0
+	for _, object := range srcObjects {
+		if isHiddenKey(object.Key) {
+			continue
+		}
+		dstObject, ok := existing[object.Key]
+		delete(existing, object.Key)
+		if ok && dstObject.Size == object.Size && !object.ModTime.After(dstObject.ModTime) {
+			continue
+		}
+		if err = copyObject(src, dst, object.Key); err != nil {
+			return changed, err
+		}
+		changed++
+	}
+	if !deleteExtra {
+		return changed, nil
+	}
+	if prefix == "" && len(srcObjects) == 0 {
+		return changed, fmt.Errorf("source is empty, refusing to delete destination objects")
+	}
+	for key := range existing {
+		if isHiddenKey(key) {
+			continue
+		}
+		if err = dst.Delete(key); err != nil {
+			return changed, fmt.Errorf("could not delete %s: %v", key, err)
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+// copyObject copies the object with the given key from src to dst.
+func copyObject(src, dst Storage, key string) error {
+	content, err := src.Get(key)
+	if err != nil {
+		return fmt.Errorf("could not get %s: %v", key, err)
+	}
+	defer func() { _ = content.Close() }()
+	if err = dst.Put(key, content); err != nil {
+		return fmt.Errorf("could not put %s: %v", key, err)
+	}
+	return nil
+}
+
+// isHiddenKey checks if any element of the given key starts with a dot.
+func isHiddenKey(key string) bool {
+	for _, element := range strings.Split(key, "/") {
+		if strings.HasPrefix(element, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanKey validates the given key and removes leading slashes. Keys must not contain empty, . or .. elements.
+func cleanKey(key string) (string, error) {
+	key = strings.TrimLeft(key, "/")
+	if key == "" {
+		return "", fmt.Errorf("empty key")
+	}
+	for _, element := range strings.Split(key, "/") {
+		if element == "" || element == "." || element == ".." {
+			return "", fmt.Errorf("invalid key %q", key)
+		}
+	}
+	return key, nil
+}
+
+// Mirror mirrors the podcast directory to a remote Storage like an object storage, so that media and feeds can be
+// served from there. The podcast directory stays the primary storage for imports and the web server. A nil Mirror
+// does nothing.
+type Mirror struct {
+	Local  *LocalStorage
+	Remote Storage
+	// Delete enables deleting remote objects that do not exist locally anymore. As this deletes all objects that are
+	// not in the podcast directory, the remote storage must not be shared with other data.
+	Delete bool
+}
+
+// SyncPodcast syncs the folder of the podcast with the given id to the remote Storage.
+func (m *Mirror) SyncPodcast(podcastId int) error {
+	if m == nil {
+		return nil
+	}
+	_, err := SyncStorage(m.Local, m.Remote, GetPodcastFolderName(podcastId)+"/", m.Delete)
+	return err
+}
+
+// SyncAll syncs the whole podcast directory to the remote Storage.
+func (m *Mirror) SyncAll() error {
+	if m == nil {
+		return nil
+	}
+	_, err := SyncStorage(m.Local, m.Remote, "", m.Delete)
+	return err
+}
@@ -3,13 +3,18 @@ package transfer
 import (
 	"fmt"
 	"github.com/life-unlimited/podcastination-server/podcasts"
+	"io/ioutil"
 	"log"
+	"os"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
 )
 
+// ChaptersFileExtension is the extension of chapters files in the JSON chapters format of the podcast namespace.
+const ChaptersFileExtension = ".chapters.json"
+
 type EpisodeFileLocations struct {
 	BaseDir       string
 	MP3FileName   string
@@ -17,13 +22,14 @@ type EpisodeFileLocations struct {
 	PDFFileName   string
 }
 
-// GetEpisodeFileLocations returns the file locations for the given episode and podcast.
-func GetEpisodeFileLocations(episode podcasts.Episode, podcastId int) EpisodeFileLocations {
+// GetEpisodeFileLocations returns the file locations for the given episode and podcast. The audio file gets the given
+// extension (for example .mp3) which should be the one of the original file.
+func GetEpisodeFileLocations(episode podcasts.Episode, podcastId int, audioExtension string) EpisodeFileLocations {
 	folderName := GetEpisodeFolderName(episode, podcastId)
 	cleanTitle := filepath.Clean(removeSpecialCharacters(replaceSpacesWithUnderscore(episode.Title)))
 	loc := EpisodeFileLocations{
 		BaseDir:       folderName,
-		MP3FileName:   fmt.Sprintf("%d_%s.mp3", episode.Id, cleanTitle),
+		MP3FileName:   fmt.Sprintf("%d_%s%s", episode.Id, cleanTitle, strings.ToLower(audioExtension)),
 		ImageFileName: fmt.Sprintf("thumb.png"),
 		PDFFileName:   fmt.Sprintf("%d_%s.pdf", episode.Id, cleanTitle),
 	}
@@ -56,6 +62,23 @@ func (loc EpisodeFileLocations) ImageFullPath() string {
 	return filepath.Join(loc.BaseDir, loc.ImageFileName)
 }
 
+// TranscriptFullPath returns the path of the transcript with the given extension which is placed next to the audio
+// file with the same name.
+func (loc EpisodeFileLocations) TranscriptFullPath(extension string) string {
+	base := strings.TrimSuffix(loc.MP3FileName, filepath.Ext(loc.MP3FileName))
+	return filepath.Join(loc.BaseDir, base+strings.ToLower(extension))
+}
+
+// ChaptersFullPath returns the path of the chapters file which is placed next to the audio file.
+func (loc EpisodeFileLocations) ChaptersFullPath() string {
+	return ChaptersLocation(loc.MP3FullPath())
+}
+
+// ChaptersLocation returns the location of the chapters file for the audio file at the given location.
+func ChaptersLocation(mp3Location string) string {
+	return strings.TrimSuffix(mp3Location, filepath.Ext(mp3Location)) + ChaptersFileExtension
+}
+
 func (loc EpisodeFileLocations) PDFFullPath() string {
 	if loc.PDFFileName == "" {
 		return ""
@@ -69,6 +92,30 @@ func GetEpisodeFolderName(episode podcasts.Episode, podcastId int) string {
 	return filepath.Join(GetPodcastFolderName(podcastId), fmt.Sprintf("%s_%d", timestamp, episode.Id))
 }
 
+// FindEpisodeFolder returns the folder of the given episode relative to the podcast dir. As the folder name contains
+// the time of the episode which is not stored, it is derived from the mp3 location or otherwise searched in the
+// podcast folder. If no folder was found, false is returned.
+func FindEpisodeFolder(podcastDir string, episode podcasts.Episode, podcastId int) (string, bool, error) {
+	if episode.MP3Location != "" {
+		return filepath.Dir(episode.MP3Location), true, nil
+	}
+	podcastFolder := GetPodcastFolderName(podcastId)
+	fileInfo, err := ioutil.ReadDir(filepath.Join(podcastDir, podcastFolder))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("could not read podcast folder: %v", err)
+	}
+	suffix := fmt.Sprintf("_%d", episode.Id)
+	for _, file := range fileInfo {
+		if file.IsDir() && strings.HasSuffix(file.Name(), suffix) {
+			return filepath.Join(podcastFolder, file.Name()), true, nil
+		}
+	}
+	return "", false, nil
+}
+
 func GetPodcastFolderName(podcastId int) string {
 	return strconv.Itoa(podcastId)
 }
@@ -9,6 +9,21 @@ import (
 // MoveFile moves a file from source path to destination path.
 // Taken from https://gist.github.com/var23rav/23ae5d0d4d830aff886c3c970b8f6c6b.
 func MoveFile(sourcePath, destPath string) error {
+	err := CopyFile(sourcePath, destPath)
+	if err != nil {
+		return err
+	}
+	// Delete source file.
+	err = os.Remove(sourcePath)
+	if err != nil {
+		return fmt.Errorf("remove source file: %s", err)
+	}
+	return nil
+}
+
+// CopyFile copies a file from source path to destination path. The destination file is synced to disk before
+// returning, so that it is complete once CopyFile succeeded.
+func CopyFile(sourcePath, destPath string) error {
 	inputFile, err := os.Open(sourcePath)
 	if err != nil {
 		return fmt.Errorf("open source file: %s", err)
@@ -24,10 +39,14 @@ func MoveFile(sourcePath, destPath string) error {
 		_ = outputFile.Close()
 		return fmt.Errorf("write dest file: %s", err)
 	}
-	// Delete source file.
-	err = os.Remove(sourcePath)
+	err = outputFile.Sync()
 	if err != nil {
-		return fmt.Errorf("remove source file: %s", err)
+		_ = outputFile.Close()
+		return fmt.Errorf("sync dest file: %s", err)
+	}
+	err = outputFile.Close()
+	if err != nil {
+		return fmt.Errorf("close dest file: %s", err)
 	}
 	return nil
 }
@@ -0,0 +1,79 @@
+package web_server
+
+import (
+	"context"
+	"github.com/life-unlimited/podcastination-server/auth"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// scopeContextKey is the context key for the auth.Scope of an authenticated request.
+type scopeContextKey struct{}
+
+// authMiddleware authenticates requests with the bearer token from the Authorization header. Write methods are
+// rejected if no token with auth.ScopeAdmin is provided. Read methods are allowed without a token but if one is
+// provided, it must be valid. The scope is then available via requestScope.
+func (s *WebServer) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		token, ok := bearerToken(r)
+		if !ok {
+			// Subscribers of the built-in hub are not authenticated.
+			if isWriteMethod(r.Method) && r.URL.Path != websubHubPath {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeString(w, http.StatusUnauthorized, "missing bearer token")
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		key, found, err := s.stores.APIKeys.ByHash(auth.HashKey(token))
+		if err != nil {
+			writeString(w, http.StatusInternalServerError, "could not check api key")
+			log.Printf("error while retrieving api key: %v", err)
+			return
+		}
+		if !found {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			writeString(w, http.StatusUnauthorized, "invalid bearer token")
+			return
+		}
+		if isWriteMethod(r.Method) && !key.Scope.Allows(auth.ScopeAdmin) {
+			writeString(w, http.StatusForbidden, "api key is not allowed to perform write operations")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), scopeContextKey{}, key.Scope)))
+	})
+}
+
+// requestScope returns the auth.Scope of the authenticated request. If the request was not authenticated, false is
+// returned.
+func requestScope(r *http.Request) (auth.Scope, bool) {
+	scope, ok := r.Context().Value(scopeContextKey{}).(auth.Scope)
+	return scope, ok
+}
+
+// bearerToken extracts the bearer token from the Authorization header of the given http.Request.
+func bearerToken(r *http.Request) (string, bool) {
+	const prefix = "bearer "
+	header := r.Header.Get("Authorization")
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", false
+	}
+	token := strings.TrimSpace(header[len(prefix):])
+	return token, token != ""
+}
+
+// isWriteMethod checks whether the given http method changes state.
+func isWriteMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,665 @@
+package web_server
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/life-unlimited/podcastination-server/audio"
+	"github.com/life-unlimited/podcastination-server/feedgen"
+	"github.com/life-unlimited/podcastination-server/podcasts"
+	"github.com/life-unlimited/podcastination-server/tasks"
+	"github.com/life-unlimited/podcastination-server/transfer"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// populateCRUDRoutes populates the given router with the routes needed for creating, updating and deleting podcasts,
+// seasons, episodes and owners.
+func (s *WebServer) populateCRUDRoutes(r *mux.Router) {
+	// Podcasts.
+	r.HandleFunc("/podcasts", s.createPodcastHandler).Methods(http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/podcasts/{id:[0-9]+}", s.updatePodcastHandler).Methods(http.MethodPut, http.MethodPatch, http.MethodOptions)
+	r.HandleFunc("/podcasts/{id:[0-9]+}", s.deletePodcastHandler).Methods(http.MethodDelete, http.MethodOptions)
+	// Seasons.
+	r.HandleFunc("/seasons", s.createSeasonHandler).Methods(http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/seasons/{id:[0-9]+}", s.updateSeasonHandler).Methods(http.MethodPut, http.MethodPatch, http.MethodOptions)
+	r.HandleFunc("/seasons/{id:[0-9]+}", s.deleteSeasonHandler).Methods(http.MethodDelete, http.MethodOptions)
+	// Episodes.
+	r.HandleFunc("/episodes/{id:[0-9]+}", s.getEpisodeByIdHandler).Methods(http.MethodGet, http.MethodOptions)
+	r.HandleFunc("/episodes", s.createEpisodeHandler).Methods(http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/episodes/{id:[0-9]+}", s.updateEpisodeHandler).Methods(http.MethodPut, http.MethodPatch, http.MethodOptions)
+	r.HandleFunc("/episodes/{id:[0-9]+}", s.deleteEpisodeHandler).Methods(http.MethodDelete, http.MethodOptions)
+	// Owners.
+	r.HandleFunc("/owners", s.getOwnersHandler).Methods(http.MethodGet, http.MethodOptions)
+	r.HandleFunc("/owners/{id:[0-9]+}", s.getOwnerByIdHandler).Methods(http.MethodGet, http.MethodOptions)
+	r.HandleFunc("/owners", s.createOwnerHandler).Methods(http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/owners/{id:[0-9]+}", s.updateOwnerHandler).Methods(http.MethodPut, http.MethodPatch, http.MethodOptions)
+	r.HandleFunc("/owners/{id:[0-9]+}", s.deleteOwnerHandler).Methods(http.MethodDelete, http.MethodOptions)
+}
+
+// createPodcastHandler creates a new podcast.
+func (s *WebServer) createPodcastHandler(w http.ResponseWriter, r *http.Request) {
+	var podcast podcasts.Podcast
+	if err := readJSON(r, &podcast); err != nil {
+		writeString(w, http.StatusBadRequest, fmt.Sprintf("invalid podcast: %v", err))
+		return
+	}
+	if _, err := podcast.IsValid(); err != nil {
+		writeString(w, http.StatusBadRequest, fmt.Sprintf("invalid podcast: %v", err))
+		return
+	}
+	if _, err := s.stores.Owners.ById(podcast.OwnerId); err != nil {
+		writeString(w, http.StatusBadRequest, "unknown owner")
+		return
+	}
+	// Persist the guid, so that it stays the same if the feed link changes.
+	podcast.GUID = podcast.GUIDOrDefault()
+	podcast, err := s.stores.Podcasts.Create(podcast)
+	if err != nil {
+		writeString(w, http.StatusInternalServerError, "could not create podcast")
+		log.Printf("error while creating podcast: %v", err)
+		return
+	}
+	s.refreshFeeds(podcast.Id)
+	writeJSONStatus(w, http.StatusCreated, podcast)
+}
+
+// updatePodcastHandler updates a podcast. When called with http.MethodPatch, only the provided fields are updated.
+func (s *WebServer) updatePodcastHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeString(w, http.StatusBadRequest, "invalid podcast id")
+		return
+	}
+	podcast, err := s.stores.Podcasts.ById(id)
+	if err != nil {
+		writeString(w, http.StatusNotFound, "could not retrieve podcast")
+		return
+	}
+	previousGUID := podcast.GUIDOrDefault()
+	if r.Method != http.MethodPatch {
+		podcast = podcasts.Podcast{}
+	}
+	if err := readJSON(r, &podcast); err != nil {
+		writeString(w, http.StatusBadRequest, fmt.Sprintf("invalid podcast: %v", err))
+		return
+	}
+	podcast.Id = id
+	if podcast.GUID == "" {
+		podcast.GUID = previousGUID
+	}
+	if _, err := podcast.IsValid(); err != nil {
+		writeString(w, http.StatusBadRequest, fmt.Sprintf("invalid podcast: %v", err))
+		return
+	}
+	if _, err := s.stores.Owners.ById(podcast.OwnerId); err != nil {
+		writeString(w, http.StatusBadRequest, "unknown owner")
+		return
+	}
+	if err := s.stores.Podcasts.Update(podcast); err != nil {
+		writeString(w, http.StatusInternalServerError, "could not update podcast")
+		log.Printf("error while updating podcast %d: %v", id, err)
+		return
+	}
+	s.refreshFeeds(podcast.Id)
+	writeJSON(w, podcast)
+}
+
+// deletePodcastHandler deletes a podcast. This is only possible if the podcast has no seasons.
+func (s *WebServer) deletePodcastHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeString(w, http.StatusBadRequest, "invalid podcast id")
+		return
+	}
+	if _, err := s.stores.Podcasts.ById(id); err != nil {
+		writeString(w, http.StatusNotFound, "could not retrieve podcast")
+		return
+	}
+	seasons, err := s.stores.Seasons.ByPodcast(id)
+	if err != nil {
+		writeString(w, http.StatusInternalServerError, "could not retrieve seasons for podcast")
+		log.Printf("error while retrieving seasons by podcast %d: %v", id, err)
+		return
+	}
+	if len(seasons) != 0 {
+		writeString(w, http.StatusConflict, "podcast still has seasons")
+		return
+	}
+	if err := s.stores.Podcasts.Delete(id); err != nil {
+		writeString(w, http.StatusInternalServerError, "could not delete podcast")
+		log.Printf("error while deleting podcast %d: %v", id, err)
+		return
+	}
+	// Remove the feed as it would be served otherwise.
+	feedFile := filepath.Join(s.config.StaticDir, transfer.GetPodcastFolderName(id), tasks.PodcastXMLDetailsFileName)
+	if err := os.Remove(feedFile); err != nil && !os.IsNotExist(err) {
+		log.Printf("could not remove podcast xml of deleted podcast %d: %v", id, err)
+	}
+	if err := s.config.Mirror.SyncPodcast(id); err != nil {
+		log.Printf("could not sync deleted podcast %d to storage: %v", id, err)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// createSeasonHandler creates a new season.
+func (s *WebServer) createSeasonHandler(w http.ResponseWriter, r *http.Request) {
+	var season podcasts.Season
+	if err := readJSON(r, &season); err != nil {
+		writeString(w, http.StatusBadRequest, fmt.Sprintf("invalid season: %v", err))
+		return
+	}
+	if _, err := season.IsValid(); err != nil {
+		writeString(w, http.StatusBadRequest, fmt.Sprintf("invalid season: %v", err))
+		return
+	}
+	if !isStaticLocation(season.ImageLocation) {
+		writeString(w, http.StatusBadRequest, "invalid season: image location outside of static dir")
+		return
+	}
+	if _, err := s.stores.Podcasts.ById(season.PodcastId); err != nil {
+		writeString(w, http.StatusBadRequest, "unknown podcast")
+		return
+	}
+	season, err := s.stores.Seasons.Create(season)
+	if err != nil {
+		writeString(w, http.StatusInternalServerError, "could not create season")
+		log.Printf("error while creating season: %v", err)
+		return
+	}
+	s.refreshFeeds(season.PodcastId)
+	writeJSONStatus(w, http.StatusCreated, season)
+}
+
+// updateSeasonHandler updates a season. When called with http.MethodPatch, only the provided fields are updated.
+func (s *WebServer) updateSeasonHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeString(w, http.StatusBadRequest, "invalid season id")
+		return
+	}
+	old, err := s.stores.Seasons.ById(id)
+	if err != nil {
+		writeString(w, http.StatusNotFound, "could not retrieve season")
+		return
+	}
+	season := *old
+	if r.Method != http.MethodPatch {
+		season = podcasts.Season{}
+	}
+	if err := readJSON(r, &season); err != nil {
+		writeString(w, http.StatusBadRequest, fmt.Sprintf("invalid season: %v", err))
+		return
+	}
+	season.Id = id
+	if _, err := season.IsValid(); err != nil {
+		writeString(w, http.StatusBadRequest, fmt.Sprintf("invalid season: %v", err))
+		return
+	}
+	if !isStaticLocation(season.ImageLocation) {
+		writeString(w, http.StatusBadRequest, "invalid season: image location outside of static dir")
+		return
+	}
+	if _, err := s.stores.Podcasts.ById(season.PodcastId); err != nil {
+		writeString(w, http.StatusBadRequest, "unknown podcast")
+		return
+	}
+	if err := s.stores.Seasons.Update(season); err != nil {
+		writeString(w, http.StatusInternalServerError, "could not update season")
+		log.Printf("error while updating season %d: %v", id, err)
+		return
+	}
+	s.refreshFeeds(old.PodcastId, season.PodcastId)
+	// The season title and image are part of the episode tags.
+	if season.Title != old.Title || season.ImageLocation != old.ImageLocation {
+		go s.retagSeason(id)
+	}
+	writeJSON(w, season)
+}
+
+// deleteSeasonHandler deletes a season. This is only possible if the season has no episodes.
+func (s *WebServer) deleteSeasonHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeString(w, http.StatusBadRequest, "invalid season id")
+		return
+	}
+	season, err := s.stores.Seasons.ById(id)
+	if err != nil {
+		writeString(w, http.StatusNotFound, "could not retrieve season")
+		return
+	}
+	episodes, err := s.stores.Episodes.BySeason(id)
+	if err != nil {
+		writeString(w, http.StatusInternalServerError, "could not retrieve episodes for season")
+		log.Printf("error while retrieving episodes by season %d: %v", id, err)
+		return
+	}
+	if len(episodes) != 0 {
+		writeString(w, http.StatusConflict, "season still has episodes")
+		return
+	}
+	if err := s.stores.Seasons.Delete(id); err != nil {
+		writeString(w, http.StatusInternalServerError, "could not delete season")
+		log.Printf("error while deleting season %d: %v", id, err)
+		return
+	}
+	s.refreshFeeds(season.PodcastId)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getEpisodeByIdHandler retrieves an episode by id. Admins retrieve it with its status.
+func (s *WebServer) getEpisodeByIdHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeString(w, http.StatusBadRequest, "invalid episode id")
+		return
+	}
+	episode, err := s.visibleEpisodeById(r, id)
+	if err != nil {
+		writeString(w, http.StatusNotFound, "could not retrieve episode")
+		return
+	}
+	if isAdminRequest(r) {
+		writeJSON(w, adminEpisode{Episode: *episode, Status: episode.Status(time.Now())})
+		return
+	}
+	writeJSON(w, episode)
+}
+
+// createEpisodeHandler creates a new episode. Files are not handled here, so the locations need to point to already
+// existing ones. The file size, mime type and length are taken from the audio file.
+func (s *WebServer) createEpisodeHandler(w http.ResponseWriter, r *http.Request) {
+	var episode podcasts.Episode
+	if err := readJSON(r, &episode); err != nil {
+		writeString(w, http.StatusBadRequest, fmt.Sprintf("invalid episode: %v", err))
+		return
+	}
+	if _, err := episode.IsValid(); err != nil {
+		writeString(w, http.StatusBadRequest, fmt.Sprintf("invalid episode: %v", err))
+		return
+	}
+	if err := validateEpisodeLocations(episode); err != nil {
+		writeString(w, http.StatusBadRequest, fmt.Sprintf("invalid episode: %v", err))
+		return
+	}
+	if err := s.probeEpisodeFiles(&episode); err != nil {
+		writeString(w, http.StatusBadRequest, fmt.Sprintf("invalid episode: %v", err))
+		return
+	}
+	season, err := s.stores.Seasons.ById(episode.SeasonId)
+	if err != nil {
+		writeString(w, http.StatusBadRequest, "unknown season")
+		return
+	}
+	// Guids are always generated.
+	episode.GUID = ""
+	episode.LegacyGUID = ""
+	episode, err = s.stores.Episodes.Create(episode)
+	if err != nil {
+		writeString(w, http.StatusInternalServerError, "could not create episode")
+		log.Printf("error while creating episode: %v", err)
+		return
+	}
+	s.refreshFeeds(season.PodcastId)
+	writeJSONStatus(w, http.StatusCreated, episode)
+}
+
+// updateEpisodeHandler updates an episode. When called with http.MethodPatch, only the provided fields are updated.
+func (s *WebServer) updateEpisodeHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeString(w, http.StatusBadRequest, "invalid episode id")
+		return
+	}
+	old, err := s.stores.Episodes.ById(id)
+	if err != nil {
+		writeString(w, http.StatusNotFound, "could not retrieve episode")
+		return
+	}
+	oldSeason, err := s.stores.Seasons.ById(old.SeasonId)
+	if err != nil {
+		writeString(w, http.StatusInternalServerError, "could not retrieve season of episode")
+		log.Printf("error while retrieving season %d: %v", old.SeasonId, err)
+		return
+	}
+	episode := *old
+	if r.Method != http.MethodPatch {
+		episode = podcasts.Episode{}
+	}
+	if err := readJSON(r, &episode); err != nil {
+		writeString(w, http.StatusBadRequest, fmt.Sprintf("invalid episode: %v", err))
+		return
+	}
+	episode.Id = id
+	// Guids must not change.
+	episode.GUID = old.GUID
+	episode.LegacyGUID = old.LegacyGUID
+	// Files and their properties are managed by imports.
+	episode.MP3Location = old.MP3Location
+	episode.MIMEType = old.MIMEType
+	episode.FileSize = old.FileSize
+	episode.MP3Length = old.MP3Length
+	episode.IsAvailable = old.IsAvailable
+	episode.Transcripts = old.Transcripts
+	if _, err := episode.IsValid(); err != nil {
+		writeString(w, http.StatusBadRequest, fmt.Sprintf("invalid episode: %v", err))
+		return
+	}
+	if err := validateEpisodeLocations(episode); err != nil {
+		writeString(w, http.StatusBadRequest, fmt.Sprintf("invalid episode: %v", err))
+		return
+	}
+	season, err := s.stores.Seasons.ById(episode.SeasonId)
+	if err != nil {
+		writeString(w, http.StatusBadRequest, "unknown season")
+		return
+	}
+	if err := s.stores.Episodes.Update(episode); err != nil {
+		writeString(w, http.StatusInternalServerError, "could not update episode")
+		log.Printf("error while updating episode %d: %v", id, err)
+		return
+	}
+	episode = s.retagEpisode(episode, *season)
+	s.refreshFeeds(oldSeason.PodcastId, season.PodcastId)
+	writeJSON(w, episode)
+}
+
+// deleteEpisodeHandler deletes an episode as well as its files.
+func (s *WebServer) deleteEpisodeHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeString(w, http.StatusBadRequest, "invalid episode id")
+		return
+	}
+	episode, err := s.stores.Episodes.ById(id)
+	if err != nil {
+		writeString(w, http.StatusNotFound, "could not retrieve episode")
+		return
+	}
+	season, err := s.stores.Seasons.ById(episode.SeasonId)
+	if err != nil {
+		writeString(w, http.StatusInternalServerError, "could not retrieve season of episode")
+		log.Printf("error while retrieving season %d: %v", episode.SeasonId, err)
+		return
+	}
+	if err := s.stores.Episodes.Delete(id); err != nil {
+		writeString(w, http.StatusInternalServerError, "could not delete episode")
+		log.Printf("error while deleting episode %d: %v", id, err)
+		return
+	}
+	// Remove episode files.
+	episodeDir, found, err := transfer.FindEpisodeFolder(s.config.StaticDir, *episode, season.PodcastId)
+	if err != nil {
+		log.Printf("could not find folder of deleted episode %d: %v", id, err)
+	} else if found {
+		if err := os.RemoveAll(filepath.Join(s.config.StaticDir, episodeDir)); err != nil {
+			log.Printf("could not remove files of deleted episode %d: %v", id, err)
+		}
+	}
+	s.refreshFeeds(season.PodcastId)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getOwnersHandler retrieves all owners.
+func (s *WebServer) getOwnersHandler(w http.ResponseWriter, _ *http.Request) {
+	owners, err := s.stores.Owners.All()
+	if err != nil {
+		writeString(w, http.StatusInternalServerError, "could not retrieve owners")
+		log.Printf("error while retrieving owners: %v", err)
+		return
+	}
+	writeJSON(w, owners)
+}
+
+// getOwnerByIdHandler retrieves an owner by id.
+func (s *WebServer) getOwnerByIdHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeString(w, http.StatusBadRequest, "invalid owner id")
+		return
+	}
+	owner, err := s.stores.Owners.ById(id)
+	if err != nil {
+		writeString(w, http.StatusNotFound, "could not retrieve owner")
+		return
+	}
+	writeJSON(w, owner)
+}
+
+// createOwnerHandler creates a new owner.
+func (s *WebServer) createOwnerHandler(w http.ResponseWriter, r *http.Request) {
+	var owner podcasts.Owner
+	if err := readJSON(r, &owner); err != nil {
+		writeString(w, http.StatusBadRequest, fmt.Sprintf("invalid owner: %v", err))
+		return
+	}
+	if _, err := owner.IsValid(); err != nil {
+		writeString(w, http.StatusBadRequest, fmt.Sprintf("invalid owner: %v", err))
+		return
+	}
+	owner, err := s.stores.Owners.Create(owner)
+	if err != nil {
+		writeString(w, http.StatusInternalServerError, "could not create owner")
+		log.Printf("error while creating owner: %v", err)
+		return
+	}
+	writeJSONStatus(w, http.StatusCreated, owner)
+}
+
+// updateOwnerHandler updates an owner. When called with http.MethodPatch, only the provided fields are updated.
+func (s *WebServer) updateOwnerHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeString(w, http.StatusBadRequest, "invalid owner id")
+		return
+	}
+	owner, err := s.stores.Owners.ById(id)
+	if err != nil {
+		writeString(w, http.StatusNotFound, "could not retrieve owner")
+		return
+	}
+	if r.Method != http.MethodPatch {
+		owner = podcasts.Owner{}
+	}
+	if err := readJSON(r, &owner); err != nil {
+		writeString(w, http.StatusBadRequest, fmt.Sprintf("invalid owner: %v", err))
+		return
+	}
+	owner.Id = id
+	if _, err := owner.IsValid(); err != nil {
+		writeString(w, http.StatusBadRequest, fmt.Sprintf("invalid owner: %v", err))
+		return
+	}
+	if err := s.stores.Owners.Update(owner); err != nil {
+		writeString(w, http.StatusInternalServerError, "could not update owner")
+		log.Printf("error while updating owner %d: %v", id, err)
+		return
+	}
+	// Refresh all podcasts of the owner as owner details are part of the feed.
+	ownedPodcasts, err := s.stores.Podcasts.ByOwner(id)
+	if err != nil {
+		log.Printf("could not retrieve podcasts of owner %d for feed refresh: %v", id, err)
+	}
+	for _, podcast := range ownedPodcasts {
+		s.refreshFeeds(podcast.Id)
+	}
+	writeJSON(w, owner)
+}
+
+// deleteOwnerHandler deletes an owner. This is only possible if the owner has no podcasts.
+func (s *WebServer) deleteOwnerHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeString(w, http.StatusBadRequest, "invalid owner id")
+		return
+	}
+	if _, err := s.stores.Owners.ById(id); err != nil {
+		writeString(w, http.StatusNotFound, "could not retrieve owner")
+		return
+	}
+	ownedPodcasts, err := s.stores.Podcasts.ByOwner(id)
+	if err != nil {
+		writeString(w, http.StatusInternalServerError, "could not retrieve podcasts for owner")
+		log.Printf("error while retrieving podcasts by owner %d: %v", id, err)
+		return
+	}
+	if len(ownedPodcasts) != 0 {
+		writeString(w, http.StatusConflict, "owner still has podcasts")
+		return
+	}
+	if err := s.stores.Owners.Delete(id); err != nil {
+		writeString(w, http.StatusInternalServerError, "could not delete owner")
+		log.Printf("error while deleting owner %d: %v", id, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// isStaticLocation checks if the given location is empty or a relative path that stays inside the static dir.
+func isStaticLocation(location string) bool {
+	if location == "" {
+		return true
+	}
+	cleaned := filepath.Clean(filepath.FromSlash(location))
+	return !filepath.IsAbs(cleaned) && cleaned != ".." && !strings.HasPrefix(cleaned, ".."+string(filepath.Separator))
+}
+
+// validateEpisodeLocations checks if all file locations of the given episode are inside the static dir.
+func validateEpisodeLocations(episode podcasts.Episode) error {
+	locations := []string{episode.ImageLocation, episode.PDFLocation, episode.MP3Location}
+	for _, transcript := range episode.Transcripts {
+		locations = append(locations, transcript.Location)
+	}
+	for _, location := range locations {
+		if !isStaticLocation(location) {
+			return fmt.Errorf("location %q outside of static dir", location)
+		}
+	}
+	return nil
+}
+
+// probeEpisodeFiles checks that all files of the given episode exist in the static dir and fills the file size, mime
+// type and length from its audio file if one is set.
+func (s *WebServer) probeEpisodeFiles(episode *podcasts.Episode) error {
+	locations := []string{episode.ImageLocation, episode.PDFLocation}
+	for _, transcript := range episode.Transcripts {
+		locations = append(locations, transcript.Location)
+	}
+	for _, location := range locations {
+		if location == "" {
+			continue
+		}
+		if info, err := os.Stat(filepath.Join(s.config.StaticDir, location)); err != nil || info.IsDir() {
+			return fmt.Errorf("file %q does not exist", location)
+		}
+	}
+	if episode.MP3Location == "" {
+		return nil
+	}
+	audioFile := filepath.Join(s.config.StaticDir, episode.MP3Location)
+	audioStat, err := os.Stat(audioFile)
+	if err != nil || audioStat.IsDir() {
+		return fmt.Errorf("audio file %q does not exist", episode.MP3Location)
+	}
+	audioInfo, err := audio.Probe(audioFile)
+	if err != nil {
+		return fmt.Errorf("invalid audio file %q: %v", episode.MP3Location, err)
+	}
+	episode.FileSize = audioStat.Size()
+	episode.MIMEType = audioInfo.MIMEType
+	episode.MP3Length = audioInfo.Seconds()
+	return nil
+}
+
+// retagSeason retags the audio files of all episodes of the season with the given id and refreshes the feed afterwards
+// as file sizes changed. As this might take a while, it is meant to be run in the background. Runs are serialized and
+// use the current season, so that the latest change wins if a season is updated again in the meantime.
+func (s *WebServer) retagSeason(seasonId int) {
+	s.seasonRetagMutex.Lock()
+	defer s.seasonRetagMutex.Unlock()
+	season, err := s.stores.Seasons.ById(seasonId)
+	if err != nil {
+		log.Printf("could not retrieve season %d for retagging: %v", seasonId, err)
+		return
+	}
+	episodes, err := s.stores.Episodes.BySeason(seasonId)
+	if err != nil {
+		log.Printf("could not retrieve episodes of season %d for retagging: %v", seasonId, err)
+		return
+	}
+	for _, episode := range episodes {
+		s.retagEpisode(episode, *season)
+	}
+	s.refreshFeeds(season.PodcastId)
+}
+
+// retagEpisode retags the audio file of the given episode after its metadata changed and stores the new file size. The
+// updated episode is returned. Errors are only logged as the metadata update itself succeeded.
+func (s *WebServer) retagEpisode(episode podcasts.Episode, season podcasts.Season) podcasts.Episode {
+	if !episode.IsAvailable || episode.MP3Location == "" {
+		return episode
+	}
+	// Episodes might be retagged concurrently by requests and background retagging of seasons.
+	s.retagMutex.Lock()
+	defer s.retagMutex.Unlock()
+	chapters, err := s.stores.Chapters.ByEpisode(episode.Id)
+	if err != nil {
+		log.Printf("could not retrieve chapters of episode %d for retagging: %v", episode.Id, err)
+		return episode
+	}
+	fileSize, err := transfer.RetagEpisodeAudio(s.config.StaticDir, episode, season, chapters)
+	if err != nil {
+		log.Printf("could not retag audio file of episode %d: %v", episode.Id, err)
+		return episode
+	}
+	if fileSize != episode.FileSize {
+		episode.FileSize = fileSize
+		if err = s.stores.Episodes.Update(episode); err != nil {
+			log.Printf("could not update file size of episode %d: %v", episode.Id, err)
+		}
+	}
+	return episode
+}
+
+// refreshFeeds regenerates the podcast xml for the podcasts with the given ids. Duplicates are only refreshed once.
+// Errors are only logged as the change itself was successful. As publish dates might have changed, the publishing job
+// is rescheduled as well. Cached feeds of the podcasts are invalidated and WebSub hubs are notified about changed feeds.
+func (s *WebServer) refreshFeeds(podcastIds ...int) {
+	if s.publishingJob != nil {
+		s.publishingJob.Reschedule()
+	}
+	s.feeds.invalidate(podcastIds...)
+	refreshed := make(map[int]struct{})
+	for _, podcastId := range podcastIds {
+		if _, ok := refreshed[podcastId]; ok {
+			continue
+		}
+		refreshed[podcastId] = struct{}{}
+		changed, err := feedgen.RefreshFeedForPodcast(*s.stores, s.config.StaticContentURL, s.config.StaticDir,
+			tasks.PodcastXMLDetailsFileName, s.config.WebSub.HubURLs(), podcastId)
+		if err != nil {
+			log.Printf("could not refresh podcast xml for podcast %d: %v", podcastId, err)
+			continue
+		}
+		if err = s.config.Mirror.SyncPodcast(podcastId); err != nil {
+			log.Printf("could not sync podcast %d to storage: %v", podcastId, err)
+			continue
+		}
+		if err = s.config.WebSub.Publish(changed...); err != nil {
+			log.Printf("could not notify websub hubs about podcast %d: %v", podcastId, err)
+		}
+	}
+}
+
+// readJSON decodes the body of the given http.Request into v. Unknown fields are not allowed.
+func readJSON(r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
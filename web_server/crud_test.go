@@ -0,0 +1,94 @@
+package web_server
+
+import (
+	"bytes"
+	"github.com/life-unlimited/podcastination-server/podcasts"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_isStaticLocation(t *testing.T) {
+	tests := map[string]bool{
+		"":                       true,
+		"2/3_4/episode.mp3":      true,
+		"2/../2/episode.mp3":     true,
+		"..":                     false,
+		"../etc/passwd":          false,
+		"2/../../etc/passwd":     false,
+		"/etc/passwd":            false,
+		"2/..foo/episode.mp3":    true,
+		"./2/3_4/transcript.vtt": true,
+	}
+	for location, expected := range tests {
+		assert.Equal(t, expected, isStaticLocation(location), "result should match for %q", location)
+	}
+}
+
+func Test_validateEpisodeLocations(t *testing.T) {
+	episode := podcasts.Episode{
+		ImageLocation: "2/3_4/image.jpg",
+		MP3Location:   "2/3_4/episode.mp3",
+		Transcripts:   []podcasts.Transcript{{Location: "2/3_4/transcript.vtt"}},
+	}
+	assert.Nil(t, validateEpisodeLocations(episode), "locations inside static dir should be valid")
+	episode.Transcripts[0].Location = "../transcript.vtt"
+	assert.NotNil(t, validateEpisodeLocations(episode), "transcript outside of static dir should be invalid")
+	episode.Transcripts = nil
+	episode.PDFLocation = "/tmp/notes.pdf"
+	assert.NotNil(t, validateEpisodeLocations(episode), "absolute location should be invalid")
+}
+
+func TestWebServer_probeEpisodeFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "static")
+	if !assert.Nil(t, err, "creating temp dir should not fail") {
+		return
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, "2", "3_4"), 0744), "creating dirs should not fail")
+	// 100 MPEG-1 Layer III frames with 128 kbit/s and 44.1 kHz.
+	frame := make([]byte, 417)
+	copy(frame, []byte{0xFF, 0xFB, 0x90, 0x00})
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "2", "3_4", "episode.mp3"), bytes.Repeat(frame, 100), 0644),
+		"writing episode should not fail")
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "2", "3_4", "image.jpg"), []byte("jpg"), 0644),
+		"writing image should not fail")
+	s := &WebServer{config: Config{StaticDir: dir}}
+
+	episode := podcasts.Episode{
+		ImageLocation: "2/3_4/image.jpg",
+		MP3Location:   "2/3_4/episode.mp3",
+	}
+	err = s.probeEpisodeFiles(&episode)
+	assert.Nilf(t, err, "probing should not fail but got %s", err)
+	assert.EqualValues(t, 41700, episode.FileSize, "file size should match")
+	assert.Equal(t, "audio/mpeg", episode.MIMEType, "mime type should match")
+	assert.Equal(t, 3, episode.MP3Length, "length should match")
+
+	episode.MP3Location = "2/3_4/missing.mp3"
+	assert.NotNil(t, s.probeEpisodeFiles(&episode), "missing audio file should be rejected")
+	episode.MP3Location = "2/3_4/image.jpg"
+	assert.NotNil(t, s.probeEpisodeFiles(&episode), "unsupported audio file should be rejected")
+	episode.MP3Location = ""
+	episode.PDFLocation = "2/3_4/notes.pdf"
+	assert.NotNil(t, s.probeEpisodeFiles(&episode), "missing pdf should be rejected")
+}
+
+func TestWebServer_createEpisodeHandlerMissingAudio(t *testing.T) {
+	dir, err := ioutil.TempDir("", "static")
+	if !assert.Nil(t, err, "creating temp dir should not fail") {
+		return
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	s := &WebServer{config: Config{StaticDir: dir}}
+	body := `{"title": "Episode", "date": "2021-01-01T12:00:00Z", "season_id": 2, "num": 1, "mp3_location": "2/3_4/episode.mp3"}`
+	rec := httptest.NewRecorder()
+	s.createEpisodeHandler(rec, httptest.NewRequest(http.MethodPost, "/episodes", strings.NewReader(body)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code, "status should match")
+	assert.Contains(t, rec.Body.String(), "does not exist", "missing file should be reported")
+}
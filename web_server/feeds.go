@@ -0,0 +1,255 @@
+package web_server
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/life-unlimited/podcastination-server/feedgen"
+	"github.com/life-unlimited/podcastination-server/podcasts"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// websubHubPath is the path the built-in WebSub hub is served at.
+const websubHubPath = "/websub"
+
+const (
+	// feedCacheTTL is the time rendered feeds are cached. Changes via the API invalidate the cache immediately, while
+	// imports and lifted embargoes become visible after this time.
+	feedCacheTTL = time.Minute
+	// maxFeedCacheEntries limits the number of cached feeds as each combination of filters results in one.
+	maxFeedCacheEntries = 256
+)
+
+// feedCacheEntry is a rendered feed in the feedCache.
+type feedCacheEntry struct {
+	podcastId int
+	feed      []byte
+	expires   time.Time
+}
+
+// feedCache caches rendered feeds by their canonical url which includes the format.
+type feedCache struct {
+	mutex   sync.Mutex
+	entries map[string]feedCacheEntry
+}
+
+// get retrieves the feed with the given key if it is not expired at the given time.
+func (c *feedCache) get(key string, now time.Time) ([]byte, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || !now.Before(entry.expires) {
+		return nil, false
+	}
+	return entry.feed, true
+}
+
+// put caches the given feed of the podcast with the given id. If the cache is full, expired entries are removed. If
+// it is still full, all entries are removed.
+func (c *feedCache) put(key string, podcastId int, feed []byte, now time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]feedCacheEntry)
+	}
+	if len(c.entries) >= maxFeedCacheEntries {
+		for k, entry := range c.entries {
+			if !now.Before(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxFeedCacheEntries {
+			c.entries = make(map[string]feedCacheEntry)
+		}
+	}
+	c.entries[key] = feedCacheEntry{
+		podcastId: podcastId,
+		feed:      feed,
+		expires:   now.Add(feedCacheTTL),
+	}
+}
+
+// invalidate removes all cached feeds of the podcasts with the given ids.
+func (c *feedCache) invalidate(podcastIds ...int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for key, entry := range c.entries {
+		for _, podcastId := range podcastIds {
+			if entry.podcastId == podcastId {
+				delete(c.entries, key)
+				break
+			}
+		}
+	}
+}
+
+// populateFeedRoutes populates the given router with the routes for rendering feeds.
+func (s *WebServer) populateFeedRoutes(r *mux.Router) {
+	r.HandleFunc("/podcasts/{key}/feed", s.getFeedOfPodcastHandler).Methods(http.MethodGet, http.MethodOptions)
+}
+
+// parseFeedFilter parses the feedgen.Filter from the query parameters season, author, from, to and limit of the given
+// request. Dates are either RFC 3339 timestamps or dates like 2021-01-31, where to includes the whole day.
+func parseFeedFilter(r *http.Request) (feedgen.Filter, error) {
+	query := r.URL.Query()
+	filter := feedgen.Filter{
+		SeasonKey: query.Get("season"),
+		Author:    query.Get("author"),
+	}
+	var err error
+	if from := query.Get("from"); from != "" {
+		if filter.From, _, err = parseFilterDate(from); err != nil {
+			return feedgen.Filter{}, fmt.Errorf("invalid from: %v", err)
+		}
+	}
+	if to := query.Get("to"); to != "" {
+		until, dateOnly, err := parseFilterDate(to)
+		if err != nil {
+			return feedgen.Filter{}, fmt.Errorf("invalid to: %v", err)
+		}
+		if dateOnly {
+			until = until.AddDate(0, 0, 1)
+		}
+		filter.Until = until
+	}
+	if limit := query.Get("limit"); limit != "" {
+		filter.Newest, err = strconv.Atoi(limit)
+		if err != nil || filter.Newest <= 0 {
+			return feedgen.Filter{}, fmt.Errorf("invalid limit: must be a positive number")
+		}
+	}
+	return filter, nil
+}
+
+// parseFilterDate parses the given RFC 3339 timestamp or date. If it is a date, true is returned.
+func parseFilterDate(value string) (time.Time, bool, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t, false, err
+}
+
+// feedFilterParameters are the query parameters of sub-feeds parsed by parseFeedFilter.
+var feedFilterParameters = []string{"season", "author", "from", "to", "limit"}
+
+// apiURL returns the public base url of the API.
+func (s *WebServer) apiURL() string {
+	if s.config.APIURL != "" {
+		return strings.TrimSuffix(s.config.APIURL, "/")
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(s.config.StaticContentURL, "/"), "/static")
+}
+
+// feedURL returns the canonical url of the feed of the podcast with the given key in the format with the given name.
+// It only contains the filter query parameters of the given request in a fixed order, so that it neither depends on
+// request headers nor on unknown or reordered parameters. If the format name is empty, it is omitted.
+func (s *WebServer) feedURL(key string, r *http.Request, formatName string) string {
+	query := r.URL.Query()
+	values := url.Values{}
+	for _, name := range feedFilterParameters {
+		if value := query.Get(name); value != "" {
+			values.Set(name, value)
+		}
+	}
+	if formatName != "" {
+		values.Set("format", formatName)
+	}
+	feedURL := fmt.Sprintf("%s/podcasts/%s/feed", s.apiURL(), url.PathEscape(key))
+	if len(values) == 0 {
+		return feedURL
+	}
+	return feedURL + "?" + values.Encode()
+}
+
+// negotiateFeedFormat selects the feedgen.Format for the given request. The format query parameter takes precedence
+// over the Accept header, so that formats can be selected in browsers as well.
+func negotiateFeedFormat(r *http.Request) (feedgen.Format, error) {
+	if name := r.URL.Query().Get("format"); name != "" {
+		format, ok := feedgen.FormatByName(name)
+		if !ok {
+			return feedgen.Format{}, fmt.Errorf("unknown format %q", name)
+		}
+		return format, nil
+	}
+	format, ok := feedgen.NegotiateFormat(r.Header.Get("Accept"))
+	if !ok {
+		names := make([]string, 0, len(feedgen.Formats))
+		for _, format := range feedgen.Formats {
+			names = append(names, format.ContentType)
+		}
+		return feedgen.Format{}, fmt.Errorf("supported content types are %s", strings.Join(names, ", "))
+	}
+	return format, nil
+}
+
+// getFeedOfPodcastHandler renders the feed of a podcast in the format selected via content negotiation. Sub-feeds are
+// requested via the filter query parameters. Rendered feeds are cached.
+func (s *WebServer) getFeedOfPodcastHandler(w http.ResponseWriter, r *http.Request) {
+	key := mux.Vars(r)["key"]
+	format, err := negotiateFeedFormat(r)
+	if err != nil {
+		writeString(w, http.StatusNotAcceptable, err.Error())
+		return
+	}
+	filter, err := parseFeedFilter(r)
+	if err != nil {
+		writeString(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	podcast, err := s.stores.Podcasts.ByKey(key)
+	if err != nil {
+		writeString(w, http.StatusNotFound, "could not retrieve podcast")
+		return
+	}
+	now := time.Now()
+	feedURL := s.feedURL(key, r, format.Name)
+	if feed, ok := s.feeds.get(feedURL, now); ok {
+		writeFeed(w, format, feed)
+		return
+	}
+	details, err := feedgen.CreationDetailsForPodcast(*s.stores, s.config.StaticContentURL, podcast.Id)
+	if err != nil {
+		writeString(w, http.StatusInternalServerError, "could not retrieve podcast details")
+		log.Printf("error while retrieving creation details for podcast %d: %v", podcast.Id, err)
+		return
+	}
+	if filter.IsEmpty() {
+		// Hubs are only notified about the feeds of whole podcasts.
+		details.Hubs = s.config.WebSub.HubURLs()
+	} else {
+		details, err = filter.Apply(details, now)
+		if err == feedgen.ErrUnknownSeason {
+			writeString(w, http.StatusNotFound, "could not retrieve season")
+			return
+		}
+		if err != nil {
+			writeString(w, http.StatusInternalServerError, "could not filter feed")
+			log.Printf("error while filtering feed for podcast %d: %v", podcast.Id, err)
+			return
+		}
+		// Sub-feeds are separate feeds with their own url and a guid which is the same for all formats.
+		details.FeedURL = feedURL
+		details.Podcast.GUID = podcasts.GUIDForFeedLink(s.feedURL(key, r, ""))
+	}
+	feed, err := format.Render(details)
+	if err != nil {
+		writeString(w, http.StatusInternalServerError, "could not render feed")
+		log.Printf("error while rendering %s feed for podcast %d: %v", format.Name, podcast.Id, err)
+		return
+	}
+	s.feeds.put(feedURL, podcast.Id, feed, now)
+	writeFeed(w, format, feed)
+}
+
+// writeFeed writes the given feed rendered in the given feedgen.Format.
+func writeFeed(w http.ResponseWriter, format feedgen.Format, feed []byte) {
+	w.Header().Set("Content-Type", format.ContentType+"; charset=utf-8")
+	w.Header().Add("Vary", "Accept")
+	write(w, http.StatusOK, feed)
+}
@@ -0,0 +1,56 @@
+package web_server
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_parseFeedFilter(t *testing.T) {
+	filter, err := parseFeedFilter(httptest.NewRequest("GET",
+		"/podcasts/sermons/feed?season=series&author=Jane&from=2021-01-01&to=2021-01-31&limit=10", nil))
+	if assert.Nil(t, err, "parsing should not fail") {
+		assert.Equal(t, "series", filter.SeasonKey, "season should match")
+		assert.Equal(t, "Jane", filter.Author, "author should match")
+		assert.Equal(t, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), filter.From, "from should match")
+		assert.Equal(t, time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC), filter.Until, "to should include the whole day")
+		assert.Equal(t, 10, filter.Newest, "limit should match")
+	}
+	_, err = parseFeedFilter(httptest.NewRequest("GET", "/podcasts/sermons/feed?limit=0", nil))
+	assert.NotNil(t, err, "invalid limit should fail")
+	_, err = parseFeedFilter(httptest.NewRequest("GET", "/podcasts/sermons/feed?from=yesterday", nil))
+	assert.NotNil(t, err, "invalid date should fail")
+}
+
+func Test_feedCache(t *testing.T) {
+	var cache feedCache
+	now := time.Now()
+	cache.put("rss a", 1, []byte("a"), now)
+	cache.put("rss b", 2, []byte("b"), now)
+	feed, ok := cache.get("rss a", now)
+	assert.True(t, ok, "cached feed should be found")
+	assert.Equal(t, "a", string(feed), "cached feed should match")
+	_, ok = cache.get("rss a", now.Add(feedCacheTTL))
+	assert.False(t, ok, "expired feed should not be found")
+	cache.invalidate(1)
+	_, ok = cache.get("rss a", now)
+	assert.False(t, ok, "invalidated feed should not be found")
+	_, ok = cache.get("rss b", now)
+	assert.True(t, ok, "feed of other podcast should be kept")
+}
+
+func TestWebServer_feedURL(t *testing.T) {
+	s := &WebServer{config: Config{StaticContentURL: "https://podcasts.example.com/static/"}}
+	r := httptest.NewRequest("GET", "/podcasts/sermons/feed?limit=5&utm=1&season=series&format=atom", nil)
+	r.Host = "attacker.example.com"
+	r.Header.Set("X-Forwarded-Proto", "gopher")
+	assert.Equal(t, "https://podcasts.example.com/podcasts/sermons/feed?format=atom&limit=5&season=series",
+		s.feedURL("sermons", r, "atom"), "url should be built from the static content url with sorted known parameters")
+	assert.Equal(t, "https://podcasts.example.com/podcasts/sermons/feed?limit=5&season=series",
+		s.feedURL("sermons", r, ""), "format should be omitted")
+	s.config.APIURL = "https://api.example.com/"
+	assert.Equal(t, "https://api.example.com/podcasts/sermons/feed",
+		s.feedURL("sermons", httptest.NewRequest("GET", "/podcasts/sermons/feed", nil), ""),
+		"api url should be used without query")
+}
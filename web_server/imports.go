@@ -0,0 +1,156 @@
+package web_server
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/life-unlimited/podcastination-server/tasks"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// maxUploadMemory is the maximum number of bytes of an upload that are held in memory. Everything else is stored in
+// temporary files.
+const maxUploadMemory = 32 << 20
+
+// Form field names for import uploads.
+const (
+	importFormDetails    = "details"
+	importFormMP3        = "mp3"
+	importFormImage      = "image"
+	importFormPDF        = "pdf"
+	importFormTranscript = "transcript"
+)
+
+// populateImportRoutes populates the given router with the routes needed for uploading import tasks.
+func (s *WebServer) populateImportRoutes(r *mux.Router) {
+	r.HandleFunc("/imports", s.createImportHandler).Methods(http.MethodPost, http.MethodOptions)
+}
+
+// createImportHandler accepts a multipart upload with the task details as json and the files for an import task. The
+// task is staged in the pull dir and then either enqueued for the next import run or, if the query parameter
+// immediate is set to true, performed right away. In the latter case, the tasks.ImportReport is returned.
+func (s *WebServer) createImportHandler(w http.ResponseWriter, r *http.Request) {
+	immediate, _ := strconv.ParseBool(r.URL.Query().Get("immediate"))
+	if s.config.MaxUploadSize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, s.config.MaxUploadSize)
+	}
+	if err := r.ParseMultipartForm(maxUploadMemory); err != nil {
+		writeString(w, http.StatusBadRequest, fmt.Sprintf("invalid multipart form: %v", err))
+		return
+	}
+	defer func() {
+		if err := r.MultipartForm.RemoveAll(); err != nil {
+			log.Printf("could not remove temporary upload files: %v", err)
+		}
+	}()
+	// Parse details.
+	var details tasks.ImportTaskDetails
+	if err := json.Unmarshal([]byte(r.FormValue(importFormDetails)), &details); err != nil {
+		writeString(w, http.StatusBadRequest, fmt.Sprintf("invalid task details: %v", err))
+		return
+	}
+	// Collect files and set file names in details.
+	files := make(map[string]io.Reader)
+	fileNames := map[string]*string{
+		importFormMP3:   &details.MP3FileName,
+		importFormImage: &details.ImageFileName,
+		importFormPDF:   &details.PDFFileName,
+	}
+	for field, fileName := range fileNames {
+		file, header, err := r.FormFile(field)
+		if err == http.ErrMissingFile {
+			*fileName = ""
+			continue
+		}
+		if err != nil {
+			writeString(w, http.StatusBadRequest, fmt.Sprintf("invalid %s file: %v", field, err))
+			return
+		}
+		defer closeUploadedFile(file)
+		*fileName = filepath.Base(header.Filename)
+		files[*fileName] = file
+	}
+	details.TranscriptFileNames = nil
+	for _, header := range r.MultipartForm.File[importFormTranscript] {
+		file, err := header.Open()
+		if err != nil {
+			writeString(w, http.StatusBadRequest, fmt.Sprintf("invalid %s file: %v", importFormTranscript, err))
+			return
+		}
+		defer closeUploadedFile(file)
+		fileName := filepath.Base(header.Filename)
+		details.TranscriptFileNames = append(details.TranscriptFileNames, fileName)
+		files[fileName] = file
+	}
+	if len(files) != countNonEmpty(details.MP3FileName, details.ImageFileName, details.PDFFileName)+
+		len(details.TranscriptFileNames) {
+		writeString(w, http.StatusBadRequest, "uploaded files must have distinct names")
+		return
+	}
+	if _, err := details.IsValid(); err != nil {
+		writeString(w, http.StatusBadRequest, fmt.Sprintf("invalid task details: %v", err))
+		return
+	}
+	// Assure that target exists.
+	podcast, err := s.stores.Podcasts.ByKey(details.PodcastKey)
+	if err != nil {
+		writeString(w, http.StatusBadRequest, "unknown podcast")
+		return
+	}
+	if _, err := s.stores.Seasons.ByKey(details.SeasonKey, podcast.Id); err != nil {
+		writeString(w, http.StatusBadRequest, "unknown season")
+		return
+	}
+	// Stage.
+	task, err := tasks.StageImportTask(s.config.PullDir, details, files)
+	if err != nil {
+		writeString(w, http.StatusInternalServerError, "could not stage import task")
+		log.Printf("error while staging import task: %v", err)
+		return
+	}
+	if !immediate {
+		task, err = tasks.EnqueueImportTask(task)
+		if err != nil {
+			writeString(w, http.StatusInternalServerError, "could not enqueue import task")
+			log.Printf("error while enqueueing import task: %v", err)
+			return
+		}
+		writeJSONStatus(w, http.StatusAccepted, map[string]string{"task": filepath.Base(task.BaseDir)})
+		return
+	}
+	// Perform now.
+	report, err := s.importJob.ImportTaskNow(task)
+	if err != nil {
+		if removeErr := os.RemoveAll(task.BaseDir); removeErr != nil {
+			log.Printf("could not remove failed import task %s: %v", task.BaseDir, removeErr)
+		}
+		writeString(w, http.StatusUnprocessableEntity, fmt.Sprintf("import failed: %v", err))
+		log.Printf("error while performing uploaded import task: %v", err)
+		return
+	}
+	writeJSONStatus(w, http.StatusCreated, report)
+}
+
+// closeUploadedFile closes the given multipart.File and logs a possible error.
+func closeUploadedFile(file multipart.File) {
+	if err := file.Close(); err != nil {
+		log.Printf("could not close uploaded file: %v", err)
+	}
+}
+
+// countNonEmpty counts the given strings that are not empty.
+func countNonEmpty(values ...string) int {
+	count := 0
+	for _, v := range values {
+		if v != "" {
+			count++
+		}
+	}
+	return count
+}
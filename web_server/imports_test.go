@@ -0,0 +1,118 @@
+package web_server
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/life-unlimited/podcastination-server/stores"
+	"github.com/life-unlimited/podcastination-server/tasks"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type CreateImportHandlerTestSuite struct {
+	suite.Suite
+	pullDir string
+	mock    sqlmock.Sqlmock
+	s       *WebServer
+}
+
+func (suite *CreateImportHandlerTestSuite) SetupTest() {
+	var err error
+	suite.pullDir, err = ioutil.TempDir("", "pull")
+	suite.Require().Nil(err, "creating pull dir should not fail")
+	db, mock, err := sqlmock.New()
+	suite.Require().Nil(err, "creating mock database should not fail")
+	suite.mock = mock
+	suite.s = &WebServer{
+		config: Config{PullDir: suite.pullDir},
+		stores: &stores.Stores{
+			Podcasts: stores.PodcastStore{DB: db},
+			Seasons:  stores.SeasonStore{DB: db},
+		},
+	}
+}
+
+func (suite *CreateImportHandlerTestSuite) TearDownTest() {
+	_ = os.RemoveAll(suite.pullDir)
+}
+
+// upload performs an import upload with the given details and mp3 file name.
+func (suite *CreateImportHandlerTestSuite) upload(details string, mp3FileName string) *httptest.ResponseRecorder {
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	suite.Require().Nil(form.WriteField(importFormDetails, details), "writing details should not fail")
+	mp3, err := form.CreateFormFile(importFormMP3, mp3FileName)
+	suite.Require().Nil(err, "creating mp3 field should not fail")
+	_, err = mp3.Write([]byte("audio"))
+	suite.Require().Nil(err, "writing mp3 should not fail")
+	suite.Require().Nil(form.Close(), "closing form should not fail")
+	req := httptest.NewRequest(http.MethodPost, "/imports", body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	rec := httptest.NewRecorder()
+	suite.s.createImportHandler(rec, req)
+	return rec
+}
+
+// taskDirs returns the names of all task directories in the pull dir.
+func (suite *CreateImportHandlerTestSuite) taskDirs() []string {
+	entries, err := ioutil.ReadDir(suite.pullDir)
+	suite.Require().Nil(err, "reading pull dir should not fail")
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func (suite *CreateImportHandlerTestSuite) TestInvalidDetails() {
+	rec := suite.upload(`{"podcast_key": "sermons"`, "episode.mp3")
+	suite.Assert().Equal(http.StatusBadRequest, rec.Code, "malformed details should be rejected")
+	rec = suite.upload(`{"podcast_key": "sermons"}`, "episode.mp3")
+	suite.Assert().Equal(http.StatusBadRequest, rec.Code, "details without season should be rejected")
+	suite.Assert().Contains(rec.Body.String(), "invalid task details", "error should match")
+	suite.Assert().Empty(suite.taskDirs(), "nothing should be staged")
+	suite.Assert().Nil(suite.mock.ExpectationsWereMet(), "stores should not be queried")
+}
+
+func (suite *CreateImportHandlerTestSuite) TestUnsupportedAudioFile() {
+	rec := suite.upload(`{"podcast_key": "sermons", "season_key": "series"}`, "episode.wma")
+	suite.Assert().Equal(http.StatusBadRequest, rec.Code, "unsupported audio file should be rejected")
+	suite.Assert().Contains(rec.Body.String(), "unsupported audio file format", "error should match")
+	suite.Assert().Empty(suite.taskDirs(), "nothing should be staged")
+	suite.Assert().Nil(suite.mock.ExpectationsWereMet(), "stores should not be queried")
+}
+
+func (suite *CreateImportHandlerTestSuite) TestEnqueue() {
+	suite.mock.ExpectQuery("from podcasts where key").WithArgs("sermons").WillReturnRows(sqlmock.NewRows([]string{"id",
+		"title", "subtitle", "language", "owner_id", "description", "keywords", "link", "image_location", "type", "key",
+		"feed_link", "guid", "locked", "funding", "persons", "categories", "explicit"}).
+		AddRow(1, "Sermons", nil, "en-us", 1, nil, nil, nil, nil, nil, "sermons", "https://example.com/feed", nil,
+			false, nil, nil, nil, false))
+	suite.mock.ExpectQuery("from seasons").WithArgs("series", 1).WillReturnRows(sqlmock.NewRows([]string{"id", "title",
+		"subtitle", "description", "image_location", "podcast_id", "num", "key"}).
+		AddRow(2, "Series", nil, nil, nil, 1, 1, "series"))
+	rec := suite.upload(`{"podcast_key": "sermons", "season_key": "series", "title": "Episode"}`, "episode.mp3")
+	suite.Require().Equal(http.StatusAccepted, rec.Code, "task should be enqueued")
+	suite.Assert().Nil(suite.mock.ExpectationsWereMet(), "all mock expectations should be met")
+	var res map[string]string
+	suite.Require().Nil(json.Unmarshal(rec.Body.Bytes(), &res), "response should be valid json")
+	suite.Assert().Equal([]string{res["task"]}, suite.taskDirs(), "only the task dir should exist")
+	suite.Assert().False(strings.HasPrefix(res["task"], "."), "task dir should not be hidden")
+	audio, err := ioutil.ReadFile(filepath.Join(suite.pullDir, res["task"], "episode.mp3"))
+	suite.Require().Nil(err, "reading staged mp3 should not fail")
+	suite.Assert().Equal("audio", string(audio), "staged mp3 should match")
+	_, err = os.Stat(filepath.Join(suite.pullDir, res["task"], tasks.ImportTaskDetailsFileName))
+	suite.Assert().Nil(err, "task details should be written")
+}
+
+func Test_createImportHandler(t *testing.T) {
+	suite.Run(t, new(CreateImportHandlerTestSuite))
+}
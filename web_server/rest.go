@@ -2,10 +2,16 @@ package web_server
 
 import (
 	"encoding/json"
+	"fmt"
 	"github.com/gorilla/mux"
+	"github.com/life-unlimited/podcastination-server/auth"
+	"github.com/life-unlimited/podcastination-server/podcasts"
 	"log"
 	"net/http"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // populateRESTRoutes populates the given router with the routes needed for REST.
@@ -18,6 +24,9 @@ func (s *WebServer) populateRESTRoutes(r *mux.Router) {
 	r.HandleFunc("/podcasts/{podcastId:[0-9]+}/seasons/last", s.getLastSeasonOfPodcastHandler).Methods(http.MethodGet, http.MethodOptions)
 	r.HandleFunc("/podcasts/{podcastId:[0-9]+}/seasons/{seasonNum:[0-9]+}", s.getLastSeasonOfPodcastHandler).Methods(http.MethodGet, http.MethodOptions)
 	r.HandleFunc("/podcasts/{podcastId:[0-9]+}/seasons", s.getSeasonsOfPodcastHandler).Methods(http.MethodGet, http.MethodOptions)
+	r.HandleFunc("/episodes/{id:[0-9]+}/transcripts", s.getTranscriptsOfEpisodeHandler).Methods(http.MethodGet, http.MethodOptions)
+	r.HandleFunc("/episodes/{id:[0-9]+}/transcript", s.getTranscriptOfEpisodeHandler).Methods(http.MethodGet, http.MethodOptions)
+	r.HandleFunc("/episodes/{id:[0-9]+}/chapters", s.getChaptersOfEpisodeHandler).Methods(http.MethodGet, http.MethodOptions)
 }
 
 // getSeasonByIdHandler retrieves a season by id.
@@ -143,7 +152,8 @@ func (s *WebServer) getSeasonOfPodcastHandler(w http.ResponseWriter, r *http.Req
 	writeString(w, http.StatusNotFound, "no season with given number found")
 }
 
-// getEpisodesOfSeason retrieves the episodes for the given season.
+// getEpisodesOfSeason retrieves the episodes for the given season. Admins retrieve all episodes with their status,
+// others only public ones.
 func (s *WebServer) getEpisodesOfSeason(w http.ResponseWriter, r *http.Request) {
 	seasonId, err := strconv.Atoi(mux.Vars(r)["seasonId"])
 	if err != nil {
@@ -156,7 +166,46 @@ func (s *WebServer) getEpisodesOfSeason(w http.ResponseWriter, r *http.Request)
 		log.Printf("error while retrieving episodes by season %d: %v", seasonId, err)
 		return
 	}
-	writeJSON(w, episodes)
+	writeJSON(w, visibleEpisodes(r, episodes))
+}
+
+// adminEpisode is an episode with its status as returned to admins.
+type adminEpisode struct {
+	podcasts.Episode
+	Status podcasts.EpisodeStatus `json:"status"`
+}
+
+// isAdminRequest checks if the given http.Request is authenticated with auth.ScopeAdmin.
+func isAdminRequest(r *http.Request) bool {
+	scope, ok := requestScope(r)
+	return ok && scope.Allows(auth.ScopeAdmin)
+}
+
+// visibleEpisodes returns the given episodes as visible for the given http.Request. Admins see all episodes with their
+// status while others only see public ones.
+func visibleEpisodes(r *http.Request, episodes []podcasts.Episode) interface{} {
+	now := time.Now()
+	if !isAdminRequest(r) {
+		return podcasts.PublicEpisodes(episodes, now)
+	}
+	withStatus := make([]adminEpisode, 0, len(episodes))
+	for _, episode := range episodes {
+		withStatus = append(withStatus, adminEpisode{Episode: episode, Status: episode.Status(now)})
+	}
+	return withStatus
+}
+
+// visibleEpisodeById retrieves the episode with the given id if it is visible for the given http.Request. Episodes
+// that are not public are only visible for admins.
+func (s *WebServer) visibleEpisodeById(r *http.Request, id int) (*podcasts.Episode, error) {
+	episode, err := s.stores.Episodes.ById(id)
+	if err != nil {
+		return nil, err
+	}
+	if !isAdminRequest(r) && !episode.IsPublic(time.Now()) {
+		return nil, fmt.Errorf("episode %d is not public", id)
+	}
+	return episode, nil
 }
 
 // getSeasonsOfPodcastHandler retrieves the seasons for the given podcast.
@@ -177,6 +226,11 @@ func (s *WebServer) getSeasonsOfPodcastHandler(w http.ResponseWriter, r *http.Re
 
 // writeJSON writes the given interface marshalled and with status code http.StatusOK.
 func writeJSON(w http.ResponseWriter, response interface{}) {
+	writeJSONStatus(w, http.StatusOK, response)
+}
+
+// writeJSONStatus writes the given interface marshalled and with the given status code.
+func writeJSONStatus(w http.ResponseWriter, statusCode int, response interface{}) {
 	s, err := json.Marshal(response)
 	if err != nil {
 		log.Printf("could not marshal %v: %v", response, err)
@@ -184,7 +238,7 @@ func writeJSON(w http.ResponseWriter, response interface{}) {
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
-	write(w, http.StatusOK, s)
+	write(w, statusCode, s)
 }
 
 // writeString writes the given string and converts it to bytes.
@@ -200,3 +254,72 @@ func write(w http.ResponseWriter, statusCode int, response []byte) {
 		log.Printf("could not write response: %v", err)
 	}
 }
+
+// getTranscriptsOfEpisodeHandler retrieves the transcripts of an episode.
+func (s *WebServer) getTranscriptsOfEpisodeHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeString(w, http.StatusBadRequest, "invalid episode id")
+		return
+	}
+	episode, err := s.visibleEpisodeById(r, id)
+	if err != nil {
+		writeString(w, http.StatusNotFound, "could not retrieve episode")
+		return
+	}
+	transcripts := episode.Transcripts
+	if transcripts == nil {
+		transcripts = make([]podcasts.Transcript, 0)
+	}
+	writeJSON(w, transcripts)
+}
+
+// getTranscriptOfEpisodeHandler serves the transcript file of an episode. If it has multiple ones, the format can be
+// chosen via the query parameter format (srt, vtt or txt). Otherwise, the first one is served.
+func (s *WebServer) getTranscriptOfEpisodeHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeString(w, http.StatusBadRequest, "invalid episode id")
+		return
+	}
+	episode, err := s.visibleEpisodeById(r, id)
+	if err != nil {
+		writeString(w, http.StatusNotFound, "could not retrieve episode")
+		return
+	}
+	format := r.URL.Query().Get("format")
+	for _, transcript := range episode.Transcripts {
+		if format != "" && !strings.EqualFold(filepath.Ext(transcript.Location), "."+format) {
+			continue
+		}
+		if !isStaticLocation(transcript.Location) {
+			writeString(w, http.StatusNotFound, "episode has no such transcript")
+			log.Printf("transcript location %q of episode %d is outside of static dir", transcript.Location, id)
+			return
+		}
+		w.Header().Set("Content-Type", fmt.Sprintf("%s; charset=utf-8", transcript.Type))
+		http.ServeFile(w, r, filepath.Join(s.config.StaticDir, transcript.Location))
+		return
+	}
+	writeString(w, http.StatusNotFound, "episode has no such transcript")
+}
+
+// getChaptersOfEpisodeHandler retrieves the chapters of an episode ordered by start time.
+func (s *WebServer) getChaptersOfEpisodeHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeString(w, http.StatusBadRequest, "invalid episode id")
+		return
+	}
+	if _, err := s.visibleEpisodeById(r, id); err != nil {
+		writeString(w, http.StatusNotFound, "could not retrieve episode")
+		return
+	}
+	chapters, err := s.stores.Chapters.ByEpisode(id)
+	if err != nil {
+		writeString(w, http.StatusInternalServerError, "could not retrieve chapters")
+		log.Printf("error while retrieving chapters of episode %d: %v", id, err)
+		return
+	}
+	writeJSON(w, chapters)
+}
@@ -5,29 +5,59 @@ import (
 	"fmt"
 	"github.com/gorilla/mux"
 	"github.com/life-unlimited/podcastination-server/stores"
+	"github.com/life-unlimited/podcastination-server/tasks"
+	"github.com/life-unlimited/podcastination-server/transfer"
+	"github.com/life-unlimited/podcastination-server/websub"
 	"log"
 	"net/http"
+	"sync"
 	"time"
 )
 
 type Config struct {
 	StaticDir string
 	Addr      string
+	// StaticContentURL is the base url for accessing static content which is needed for feed regeneration.
+	StaticContentURL string
+	// APIURL is the public base url of the API which is used for the urls of sub-feeds. If empty, the StaticContentURL
+	// without the /static suffix is used.
+	APIURL string
+	// AllowedOrigins are the origins allowed for cross-origin requests. If empty, all origins are allowed.
+	AllowedOrigins []string
+	// PullDir is the directory where uploaded import tasks are placed.
+	PullDir string
+	// MaxUploadSize is the maximum size in bytes of an uploaded import task. If zero, there is no limit.
+	MaxUploadSize int64
+	// Mirror is used for syncing podcast folders to the storage after changes. If nil, nothing is synced.
+	Mirror *transfer.Mirror
+	// WebSub announces hubs in feeds and notifies them after feeds changed. If it has a built-in hub, it is served at
+	// websubHubPath.
+	WebSub *websub.Publisher
 }
 
 type WebServer struct {
-	config  Config
-	stores  *stores.Stores
-	running bool
-	stop    chan struct{}
+	config        Config
+	stores        *stores.Stores
+	importJob     *tasks.ImportJob
+	publishingJob *tasks.PublishingJob
+	running       bool
+	stop          chan struct{}
+	// feeds caches rendered feeds.
+	feeds feedCache
+	// retagMutex serializes retagging of audio files and seasonRetagMutex background retagging of seasons.
+	retagMutex       sync.Mutex
+	seasonRetagMutex sync.Mutex
 }
 
-func NewServer(config Config, stores *stores.Stores) *WebServer {
+func NewServer(config Config, stores *stores.Stores, importJob *tasks.ImportJob,
+	publishingJob *tasks.PublishingJob) *WebServer {
 	return &WebServer{
-		config:  config,
-		stores:  stores,
-		running: false,
-		stop:    make(chan struct{}),
+		config:        config,
+		stores:        stores,
+		importJob:     importJob,
+		publishingJob: publishingJob,
+		running:       false,
+		stop:          make(chan struct{}),
 	}
 }
 
@@ -45,20 +75,31 @@ func (s *WebServer) Start() error {
 func (s *WebServer) run() {
 	r := mux.NewRouter()
 	// Enable CORS.
-	r.Use(middleware)
+	r.Use(s.middleware)
+	// Authenticate.
+	r.Use(s.authMiddleware)
 
 	// Static file handling.
-	r.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.Dir(s.config.StaticDir))))
+	r.PathPrefix("/static/").Handler(http.StripPrefix("/static/", s.staticHandler()))
 	// Not found handler with cors.
-	r.NotFoundHandler = middleware(http.NotFoundHandler())
+	r.NotFoundHandler = s.middleware(http.NotFoundHandler())
 
 	s.populateRESTRoutes(r)
+	s.populateCRUDRoutes(r)
+	s.populateImportRoutes(r)
+	s.populateFeedRoutes(r)
+	if s.config.WebSub != nil && s.config.WebSub.Hub != nil {
+		r.Handle(websubHubPath, s.config.WebSub.Hub).Methods(http.MethodPost, http.MethodOptions)
+	}
 
 	srv := &http.Server{
-		Handler:      r,
-		Addr:         s.config.Addr,
-		WriteTimeout: 15 * time.Second,
-		ReadTimeout:  15 * time.Second,
+		Handler:           r,
+		Addr:              s.config.Addr,
+		ReadHeaderTimeout: 15 * time.Second,
+		// Uploading import tasks might take a while. As the write timeout also includes reading the body, it needs to
+		// be long enough, too.
+		ReadTimeout:  10 * time.Minute,
+		WriteTimeout: 10 * time.Minute,
 	}
 
 	// Start web_server.
@@ -78,14 +119,17 @@ func (s *WebServer) run() {
 // middleware activates cross site stuff and avoids caching.
 //
 // Cors stuff taken from https://asanchez.dev/blog/cors-golang-options/.
-func middleware(next http.Handler) http.Handler {
+func (s *WebServer) middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Set headers.
-		w.Header().Set("Access-Control-Allow-Headers", "*")
-		w.Header().Set("Access-Control-Allow-Origin", "*")
+		// Set headers. The authorization header needs to be listed explicitly as it is not covered by the wildcard.
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization, *")
+		if origin, ok := s.allowedOrigin(r); ok {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+		}
 		w.Header().Set("Access-Control-Allow-Methods", "*")
-		// Avoid caching.
-		w.Header().Set("Cache-Control", "max-age=0, no-cache, must-revalidate, proxy-revalidate")
+		// Avoid caching. Static files override this with their own cache policy.
+		w.Header().Set("Cache-Control", cacheControlAPI)
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -98,6 +142,21 @@ func middleware(next http.Handler) http.Handler {
 	})
 }
 
+// allowedOrigin returns the value for the Access-Control-Allow-Origin header for the given http.Request. If no
+// allowed origins are configured, all are allowed.
+func (s *WebServer) allowedOrigin(r *http.Request) (string, bool) {
+	if len(s.config.AllowedOrigins) == 0 {
+		return "*", true
+	}
+	origin := r.Header.Get("Origin")
+	for _, allowed := range s.config.AllowedOrigins {
+		if origin == allowed {
+			return origin, true
+		}
+	}
+	return "", false
+}
+
 func (s *WebServer) Stop() error {
 	if !s.running {
 		return fmt.Errorf("web server already stopped")
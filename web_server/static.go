@@ -0,0 +1,94 @@
+package web_server
+
+import (
+	"fmt"
+	"github.com/life-unlimited/podcastination-server/tasks"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// cacheControlAPI avoids caching of API responses.
+	cacheControlAPI = "max-age=0, no-cache, must-revalidate, proxy-revalidate"
+	// cacheControlFeed allows caching feeds and other metadata files for a short time as they change with each update.
+	cacheControlFeed = "public, max-age=300"
+	// cacheControlMedia allows caching media files for an hour. As they are rewritten in place when retagged, they
+	// must be revalidated afterwards, which is cheap thanks to their ETag.
+	cacheControlMedia = "public, max-age=3600, must-revalidate"
+)
+
+// staticETag returns the strong ETag for a file with the given os.FileInfo. It is derived from size and modification
+// time, which change whenever files are replaced, so that the content does not need to be hashed.
+func staticETag(info os.FileInfo) string {
+	return fmt.Sprintf(`"%x-%x"`, info.Size(), info.ModTime().UnixNano())
+}
+
+// staticCacheControl returns the Cache-Control header value for the static file with the given name.
+func staticCacheControl(name string) string {
+	if name == tasks.PodcastXMLDetailsFileName {
+		return cacheControlFeed
+	}
+	switch filepath.Ext(name) {
+	case ".xml", ".json":
+		return cacheControlFeed
+	default:
+		return cacheControlMedia
+	}
+}
+
+// staticEpisodeFolder matches the names of episode folders in the static dir which end with the episode id.
+var staticEpisodeFolder = regexp.MustCompile(`^[^/]+_([0-9]+)$`)
+
+// episodeIdOfStaticFile returns the id of the episode the static file with the given slash separated name belongs to.
+// If it does not belong to an episode, false is returned.
+func episodeIdOfStaticFile(name string) (int, bool) {
+	elements := strings.Split(strings.TrimPrefix(name, "/"), "/")
+	if len(elements) < 3 {
+		return 0, false
+	}
+	match := staticEpisodeFolder.FindStringSubmatch(elements[1])
+	if match == nil {
+		return 0, false
+	}
+	id, err := strconv.Atoi(match[1])
+	return id, err == nil
+}
+
+// staticHandler serves the files in the static dir. Files get a strong ETag and a Cache-Control header depending on
+// their type. Byte ranges, Last-Modified and conditional requests resulting in http.StatusNotModified are handled by
+// http.FileServer. Files of episodes that are not public are only served to admins and directories are not listed, so
+// that embargoed episodes cannot be found.
+func (s *WebServer) staticHandler() http.Handler {
+	fileServer := http.FileServer(http.Dir(s.config.StaticDir))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := path.Clean("/" + r.URL.Path)
+		file := filepath.Join(s.config.StaticDir, filepath.FromSlash(name))
+		info, err := os.Stat(file)
+		if err != nil || !info.Mode().IsRegular() {
+			http.NotFound(w, r)
+			return
+		}
+		if episodeId, ok := episodeIdOfStaticFile(name); ok && !isAdminRequest(r) {
+			public, err := s.stores.Episodes.IsPublic(episodeId, time.Now())
+			if err != nil {
+				writeString(w, http.StatusInternalServerError, "could not check episode")
+				log.Printf("error while checking visibility of episode %d: %v", episodeId, err)
+				return
+			}
+			if !public {
+				http.NotFound(w, r)
+				return
+			}
+		}
+		w.Header().Set("ETag", staticETag(info))
+		w.Header().Set("Cache-Control", staticCacheControl(path.Base(name)))
+		fileServer.ServeHTTP(w, r)
+	})
+}
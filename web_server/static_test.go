@@ -0,0 +1,109 @@
+package web_server
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/life-unlimited/podcastination-server/stores"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_staticHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "static")
+	if !assert.Nil(t, err, "creating temp dir should not fail") {
+		return
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, "1", "2"), 0744), "creating dirs should not fail")
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "1", "2", "episode.mp3"), []byte("0123456789"), 0644),
+		"writing episode should not fail")
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "1", "podcast.xml"), []byte("<rss/>"), 0644),
+		"writing feed should not fail")
+	s := &WebServer{config: Config{StaticDir: dir}}
+	handler := s.staticHandler()
+	serve := func(path string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("/1/2/episode.mp3", nil)
+	assert.Equal(t, http.StatusOK, rec.Code, "status should match")
+	assert.Equal(t, cacheControlMedia, rec.Header().Get("Cache-Control"), "media cache policy should be used")
+	assert.NotEmpty(t, rec.Header().Get("Last-Modified"), "last modified should be set")
+	etag := rec.Header().Get("ETag")
+	assert.Regexp(t, `^"a-[0-9a-f]+"$`, etag, "strong etag should be set")
+
+	rec = serve("/1/2/episode.mp3", http.Header{"Range": {"bytes=2-5"}})
+	assert.Equal(t, http.StatusPartialContent, rec.Code, "range should be served")
+	assert.Equal(t, "2345", rec.Body.String(), "range content should match")
+	assert.Equal(t, "bytes 2-5/10", rec.Header().Get("Content-Range"), "content range should match")
+
+	rec = serve("/1/2/episode.mp3", http.Header{"If-None-Match": {etag}})
+	assert.Equal(t, http.StatusNotModified, rec.Code, "matching etag should result in not modified")
+
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "1", "2", "episode.mp3"), []byte("retagged 0123456789"), 0644),
+		"rewriting episode should not fail")
+	rec = serve("/1/2/episode.mp3", http.Header{"If-None-Match": {etag}})
+	assert.Equal(t, http.StatusOK, rec.Code, "rewritten file should be served again")
+	assert.NotEqual(t, etag, rec.Header().Get("ETag"), "etag should change")
+
+	rec = serve("/1/podcast.xml", nil)
+	assert.Equal(t, http.StatusOK, rec.Code, "status should match")
+	assert.Equal(t, cacheControlFeed, rec.Header().Get("Cache-Control"), "feed cache policy should be used")
+
+	rec = serve("/1/missing.mp3", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code, "missing file should not be found")
+	assert.Empty(t, rec.Header().Get("ETag"), "missing file should have no etag")
+}
+
+func Test_staticHandlerHidesEpisodes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "static")
+	if !assert.Nil(t, err, "creating temp dir should not fail") {
+		return
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, "1", "20210101120000_5"), 0744), "creating dirs should not fail")
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "1", "20210101120000_5", "episode.mp3"), []byte("audio"), 0644),
+		"writing episode should not fail")
+	db, mock, err := sqlmock.New()
+	if !assert.Nil(t, err, "creating mock database should not fail") {
+		return
+	}
+	s := &WebServer{config: Config{StaticDir: dir}, stores: &stores.Stores{Episodes: stores.EpisodeStore{DB: db}}}
+	handler := s.staticHandler()
+	serve := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	mock.ExpectQuery("SELECT EXISTS").WithArgs(5, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	assert.Equal(t, http.StatusNotFound, serve("/1/20210101120000_5/episode.mp3").Code,
+		"files of embargoed episodes should not be found")
+	mock.ExpectQuery("SELECT EXISTS").WithArgs(5, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	assert.Equal(t, http.StatusOK, serve("/1/20210101120000_5/episode.mp3").Code,
+		"files of public episodes should be served")
+	assert.Equal(t, http.StatusNotFound, serve("/1/").Code, "directories should not be listed")
+	assert.Nil(t, mock.ExpectationsWereMet(), "all mock expectations should be met")
+}
+
+func Test_episodeIdOfStaticFile(t *testing.T) {
+	id, ok := episodeIdOfStaticFile("/1/20210101120000_5/episode.mp3")
+	assert.True(t, ok, "episode file should be detected")
+	assert.Equal(t, 5, id, "episode id should match")
+	_, ok = episodeIdOfStaticFile("/1/podcast.xml")
+	assert.False(t, ok, "podcast file should not belong to an episode")
+	_, ok = episodeIdOfStaticFile("/1/2/episode.mp3")
+	assert.False(t, ok, "folder without episode id should not belong to an episode")
+}
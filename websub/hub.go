@@ -0,0 +1,457 @@
+package websub
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// defaultLease is the lease of subscriptions that do not request one or request a longer one than maxLease.
+	defaultLease = 10 * 24 * time.Hour
+	// maxLease is the maximum lease of subscriptions.
+	maxLease = 30 * 24 * time.Hour
+	// maxSecretLength is the maximum length of subscription secrets as defined by WebSub.
+	maxSecretLength = 200
+	// maxSubscriptionsPerTopic is the maximum number of subscriptions to a single topic.
+	maxSubscriptionsPerTopic = 100
+	// maxSubscriptions is the maximum number of subscriptions to all topics.
+	maxSubscriptions = 1000
+	// maxPendingVerifications is the maximum number of verifications of intent that are performed concurrently.
+	maxPendingVerifications = 16
+	// maxChallengeResponseSize is the maximum size of responses of subscribers to challenges.
+	maxChallengeResponseSize = 1 << 10
+	// maxTopicSize is the maximum size of the content of topics that is distributed.
+	maxTopicSize = 10 << 20
+	// maxQueuedTopics is the maximum number of notifications that wait for being distributed.
+	maxQueuedTopics = 64
+	// maxQueuedDeliveries is the maximum number of deliveries to subscribers that wait for a worker.
+	maxQueuedDeliveries = 256
+	// deliveryWorkers is the number of workers delivering content to subscribers concurrently.
+	deliveryWorkers = 4
+)
+
+// errDisallowedAddress is returned when connecting to a subscriber with a loopback, private or link-local address.
+var errDisallowedAddress = errors.New("address not allowed")
+
+// disallowedNetworks are the loopback, private, link-local and unspecified networks subscribers must not be in. This
+// prevents subscribers from making the hub send requests to internal services.
+var disallowedNetworks = parseNetworks(
+	"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16", "172.16.0.0/12", "192.168.0.0/16",
+	"::/128", "::1/128", "fc00::/7", "fe80::/10",
+)
+
+// subscription is an active subscription of a callback to a topic.
+type subscription struct {
+	callback string
+	secret   string
+	expires  time.Time
+}
+
+// delivery is the content of a topic that is to be delivered to a subscriber.
+type delivery struct {
+	subscription subscription
+	topic        string
+	contentType  string
+	content      []byte
+}
+
+// Hub is a minimal WebSub hub. It handles subscribing and unsubscribing with verification of intent and distributes
+// the content of topics to subscribers when notified. Subscriptions are only kept in memory. Hubs must be created
+// with NewHub.
+type Hub struct {
+	// URL is the public url of the hub.
+	URL string
+	// TopicPrefix restricts the topics that can be subscribed to, for example to the static content url.
+	TopicPrefix string
+	// AllowTopic optionally allows further topics outside of TopicPrefix, for example feed links on other hosts.
+	AllowTopic func(topic string) bool
+	// Client is used for fetching topics. If nil, a client with a default timeout is used. Subscribers are always
+	// contacted with a client that refuses loopback, private and link-local addresses unless AllowPrivateCallbacks is
+	// set.
+	Client *http.Client
+	// AllowPrivateCallbacks allows callbacks with loopback, private and link-local addresses, for example for testing
+	// with local subscribers.
+	AllowPrivateCallbacks bool
+	// subscriptions are the subscriptions by topic and callback.
+	subscriptions map[string]map[string]subscription
+	mutex         sync.Mutex
+	now           func() time.Time
+	// subscriberClient is used for all requests to subscribers.
+	subscriberClient *http.Client
+	// pending limits the number of concurrent verifications.
+	pending chan struct{}
+	// topics and deliveries are the queues for distributing content which are processed by workers.
+	topics     chan string
+	deliveries chan delivery
+	startOnce  sync.Once
+}
+
+// NewHub creates a Hub with the given public url that only allows topics with the given prefix.
+func NewHub(hubURL, topicPrefix string) *Hub {
+	h := &Hub{
+		URL:           hubURL,
+		TopicPrefix:   topicPrefix,
+		subscriptions: make(map[string]map[string]subscription),
+		now:           time.Now,
+		pending:       make(chan struct{}, maxPendingVerifications),
+		topics:        make(chan string, maxQueuedTopics),
+		deliveries:    make(chan delivery, maxQueuedDeliveries),
+	}
+	dialer := &net.Dialer{
+		Timeout: defaultTimeout,
+		// Addresses are checked when connecting, so that host names resolving to internal addresses are refused too.
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !h.isAllowedIP(ip) {
+				return errDisallowedAddress
+			}
+			return nil
+		},
+	}
+	h.subscriberClient = &http.Client{
+		Timeout: defaultTimeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, address)
+			},
+			TLSHandshakeTimeout: defaultTimeout,
+		},
+	}
+	return h
+}
+
+// ServeHTTP handles subscription requests. Verification of intent is performed asynchronously. Publishing is not
+// supported via HTTP as the Publisher notifies the Hub directly.
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeHubError(w, http.StatusBadRequest, fmt.Sprintf("invalid form: %v", err))
+		return
+	}
+	mode := r.PostForm.Get("hub.mode")
+	if mode != "subscribe" && mode != "unsubscribe" {
+		writeHubError(w, http.StatusBadRequest, "unsupported mode")
+		return
+	}
+	callback := r.PostForm.Get("hub.callback")
+	topic := r.PostForm.Get("hub.topic")
+	if err := h.validate(callback, topic); err != nil {
+		writeHubError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	secret := r.PostForm.Get("hub.secret")
+	if len(secret) > maxSecretLength {
+		writeHubError(w, http.StatusBadRequest, "secret too long")
+		return
+	}
+	if mode == "subscribe" && !h.canSubscribe(topic, callback) {
+		writeHubError(w, http.StatusTooManyRequests, "too many subscriptions")
+		return
+	}
+	lease := defaultLease
+	if seconds, err := strconv.Atoi(r.PostForm.Get("hub.lease_seconds")); err == nil && seconds > 0 &&
+		time.Duration(seconds)*time.Second <= maxLease {
+		lease = time.Duration(seconds) * time.Second
+	}
+	select {
+	case h.pending <- struct{}{}:
+	default:
+		writeHubError(w, http.StatusServiceUnavailable, "too many pending verifications")
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	go func() {
+		defer func() { <-h.pending }()
+		if err := h.verify(mode, callback, topic, secret, lease); err != nil {
+			log.Printf("could not verify %s of %s to %s: %v", mode, callback, topic, err)
+		}
+	}()
+}
+
+// writeHubError writes the given reason with the given status code.
+func writeHubError(w http.ResponseWriter, statusCode int, reason string) {
+	w.WriteHeader(statusCode)
+	_, _ = w.Write([]byte(reason))
+}
+
+// validate checks if the given callback is an absolute http url with an allowed host and the topic is allowed.
+func (h *Hub) validate(callback, topic string) error {
+	callbackURL, err := url.Parse(callback)
+	if err != nil || (callbackURL.Scheme != "http" && callbackURL.Scheme != "https") || callbackURL.Hostname() == "" {
+		return fmt.Errorf("invalid callback")
+	}
+	host := callbackURL.Hostname()
+	if ip := net.ParseIP(host); (ip != nil && !h.isAllowedIP(ip)) ||
+		(strings.EqualFold(host, "localhost") && !h.AllowPrivateCallbacks) {
+		return fmt.Errorf("callback not allowed")
+	}
+	if !h.isTopic(topic) {
+		return fmt.Errorf("unsupported topic")
+	}
+	return nil
+}
+
+// isTopic checks if the given topic has the TopicPrefix or is allowed via AllowTopic.
+func (h *Hub) isTopic(topic string) bool {
+	if topic == "" {
+		return false
+	}
+	return strings.HasPrefix(topic, h.TopicPrefix) || (h.AllowTopic != nil && h.AllowTopic(topic))
+}
+
+// canSubscribe checks if the given callback may subscribe to the given topic without exceeding the limits. Renewals
+// of existing subscriptions are always allowed.
+func (h *Hub) canSubscribe(topic, callback string) bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if _, ok := h.subscriptions[topic][callback]; ok {
+		return true
+	}
+	h.removeExpired()
+	if len(h.subscriptions[topic]) >= maxSubscriptionsPerTopic {
+		return false
+	}
+	total := 0
+	for _, subscriptions := range h.subscriptions {
+		total += len(subscriptions)
+	}
+	return total < maxSubscriptions
+}
+
+// removeExpired removes all expired subscriptions. The mutex must be held.
+func (h *Hub) removeExpired() {
+	now := h.now()
+	for topic, subscriptions := range h.subscriptions {
+		for callback, s := range subscriptions {
+			if !now.Before(s.expires) {
+				delete(subscriptions, callback)
+			}
+		}
+		if len(subscriptions) == 0 {
+			delete(h.subscriptions, topic)
+		}
+	}
+}
+
+// verify verifies the intent of the subscriber by sending a challenge to the callback which must be echoed. If
+// verified, the subscription is added or removed.
+func (h *Hub) verify(mode, callback, topic, secret string, lease time.Duration) error {
+	challenge, err := randomChallenge()
+	if err != nil {
+		return err
+	}
+	verifyURL, err := url.Parse(callback)
+	if err != nil {
+		return err
+	}
+	query := verifyURL.Query()
+	query.Set("hub.mode", mode)
+	query.Set("hub.topic", topic)
+	query.Set("hub.challenge", challenge)
+	if mode == "subscribe" {
+		query.Set("hub.lease_seconds", strconv.Itoa(int(lease.Seconds())))
+	}
+	verifyURL.RawQuery = query.Encode()
+	resp, err := h.subscriberClient.Get(verifyURL.String())
+	if err != nil {
+		return fmt.Errorf("could not send challenge: %v", err)
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxChallengeResponseSize))
+	_ = resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("could not read response: %v", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 || string(body) != challenge {
+		return fmt.Errorf("subscriber did not confirm")
+	}
+	if mode == "unsubscribe" {
+		h.mutex.Lock()
+		delete(h.subscriptions[topic], callback)
+		h.mutex.Unlock()
+		return nil
+	}
+	// Limits are checked again as other subscriptions might have been verified in the meantime.
+	if !h.canSubscribe(topic, callback) {
+		return fmt.Errorf("too many subscriptions")
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.subscriptions[topic] == nil {
+		h.subscriptions[topic] = make(map[string]subscription)
+	}
+	h.subscriptions[topic][callback] = subscription{
+		callback: callback,
+		secret:   secret,
+		expires:  h.now().Add(lease),
+	}
+	return nil
+}
+
+// activeSubscriptions returns the subscriptions to the given topic that are not expired and removes expired ones.
+func (h *Hub) activeSubscriptions(topic string) []subscription {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	now := h.now()
+	active := make([]subscription, 0, len(h.subscriptions[topic]))
+	for callback, s := range h.subscriptions[topic] {
+		if !now.Before(s.expires) {
+			delete(h.subscriptions[topic], callback)
+			continue
+		}
+		active = append(active, s)
+	}
+	return active
+}
+
+// Notify queues distributing the current content of the given topic to all of its subscribers. Distribution is
+// performed asynchronously by a fixed number of workers, so that slow subscribers do not block the caller. If the
+// queue is full, an error is returned.
+func (h *Hub) Notify(topic string) error {
+	h.startOnce.Do(h.startWorkers)
+	select {
+	case h.topics <- topic:
+		return nil
+	default:
+		return fmt.Errorf("could not notify subscribers of %s: queue full", topic)
+	}
+}
+
+// startWorkers starts the workers for fetching topics and delivering them to subscribers.
+func (h *Hub) startWorkers() {
+	go func() {
+		for topic := range h.topics {
+			if err := h.dispatch(topic); err != nil {
+				log.Printf("could not notify subscribers of %s: %v", topic, err)
+			}
+		}
+	}()
+	for i := 0; i < deliveryWorkers; i++ {
+		go func() {
+			for d := range h.deliveries {
+				if err := h.deliver(d); err != nil {
+					log.Printf("could not deliver %s to %s: %v", d.topic, d.subscription.callback, err)
+				}
+			}
+		}()
+	}
+}
+
+// dispatch fetches the current content of the given topic and queues its delivery to all active subscribers.
+func (h *Hub) dispatch(topic string) error {
+	subscriptions := h.activeSubscriptions(topic)
+	if len(subscriptions) == 0 {
+		return nil
+	}
+	resp, err := httpClient(h.Client).Get(topic)
+	if err != nil {
+		return fmt.Errorf("could not fetch topic: %v", err)
+	}
+	content, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxTopicSize+1))
+	_ = resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("could not read topic: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("could not fetch topic: unexpected status %d", resp.StatusCode)
+	}
+	if len(content) > maxTopicSize {
+		return fmt.Errorf("topic exceeds %d bytes", maxTopicSize)
+	}
+	for _, s := range subscriptions {
+		h.deliveries <- delivery{
+			subscription: s,
+			topic:        topic,
+			contentType:  resp.Header.Get("Content-Type"),
+			content:      content,
+		}
+	}
+	return nil
+}
+
+// deliver sends the content of the given delivery to the subscriber. If a secret was provided when subscribing, the
+// content is signed.
+func (h *Hub) deliver(d delivery) error {
+	req, err := http.NewRequest(http.MethodPost, d.subscription.callback, bytes.NewReader(d.content))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", d.contentType)
+	req.Header.Set("Link", fmt.Sprintf(`<%s>; rel="hub", <%s>; rel="self"`, h.URL, d.topic))
+	if d.subscription.secret != "" {
+		mac := hmac.New(sha256.New, []byte(d.subscription.secret))
+		mac.Write(d.content)
+		req.Header.Set("X-Hub-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := h.subscriberClient.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// randomChallenge generates a random challenge for verification of intent.
+func randomChallenge() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("could not generate challenge: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// isAllowedIP checks if subscribers with the given ip address may be contacted.
+func (h *Hub) isAllowedIP(ip net.IP) bool {
+	return h.AllowPrivateCallbacks || isPublicIP(ip)
+}
+
+// isPublicIP checks if the given ip address is neither in one of the disallowedNetworks nor a multicast address.
+func isPublicIP(ip net.IP) bool {
+	if ip.IsMulticast() {
+		return false
+	}
+	for _, network := range disallowedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseNetworks parses the given CIDR notations and panics if one is invalid.
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
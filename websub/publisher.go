@@ -0,0 +1,85 @@
+// Package websub is used for notifying WebSub hubs about changed feeds and provides a minimal built-in hub.
+package websub
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// defaultTimeout is the timeout for requests to hubs and subscribers if no http.Client is provided.
+const defaultTimeout = 10 * time.Second
+
+// Publisher announces hubs in feeds and notifies them about changed feeds, which are the topics in WebSub. A nil
+// Publisher does nothing.
+type Publisher struct {
+	// Hubs are the urls of external hubs.
+	Hubs []string
+	// Hub is the optional built-in Hub which is notified directly.
+	Hub *Hub
+	// Client is used for notifying external hubs. If nil, a client with a default timeout is used.
+	Client *http.Client
+}
+
+// HubURLs returns the urls of all hubs to announce in feeds.
+func (p *Publisher) HubURLs() []string {
+	if p == nil {
+		return nil
+	}
+	urls := append([]string{}, p.Hubs...)
+	if p.Hub != nil {
+		urls = append(urls, p.Hub.URL)
+	}
+	return urls
+}
+
+// Publish notifies all hubs that the given topics changed. All hubs are notified even if some fail.
+func (p *Publisher) Publish(topics ...string) error {
+	if p == nil || len(topics) == 0 {
+		return nil
+	}
+	failed := make([]string, 0)
+	for _, hub := range p.Hubs {
+		if err := p.ping(hub, topics); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", hub, err))
+		}
+	}
+	if p.Hub != nil {
+		for _, topic := range topics {
+			if err := p.Hub.Notify(topic); err != nil {
+				failed = append(failed, fmt.Sprintf("built-in hub: %v", err))
+			}
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("could not notify hubs: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// ping sends a publish request for the given topics to the hub with the given url.
+func (p *Publisher) ping(hub string, topics []string) error {
+	form := url.Values{"hub.mode": {"publish"}}
+	for _, topic := range topics {
+		form.Add("hub.url", topic)
+	}
+	resp, err := httpClient(p.Client).PostForm(hub, form)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// httpClient returns the given http.Client or one with the default timeout if nil.
+func httpClient(client *http.Client) *http.Client {
+	if client != nil {
+		return client
+	}
+	return &http.Client{Timeout: defaultTimeout}
+}
@@ -0,0 +1,183 @@
+package websub
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// notification is a distribution received by a subscriber.
+type notification struct {
+	body      []byte
+	signature string
+	link      string
+}
+
+func TestHub(t *testing.T) {
+	topicServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write([]byte("<rss></rss>"))
+	}))
+	defer topicServer.Close()
+	notifications := make(chan notification, 1)
+	subscriber := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(r.URL.Query().Get("hub.challenge")))
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		notifications <- notification{
+			body:      body,
+			signature: r.Header.Get("X-Hub-Signature"),
+			link:      r.Header.Get("Link"),
+		}
+	}))
+	defer subscriber.Close()
+	hub := NewHub("https://example.com/websub", topicServer.URL)
+	// The subscriber runs on localhost.
+	hub.AllowPrivateCallbacks = true
+	topic := topicServer.URL + "/2/podcast.xml"
+	// Subscribe.
+	rec := httptest.NewRecorder()
+	hub.ServeHTTP(rec, formRequest(url.Values{
+		"hub.mode":     {"subscribe"},
+		"hub.callback": {subscriber.URL},
+		"hub.topic":    {topic},
+		"hub.secret":   {"secret"},
+	}))
+	if !assert.Equal(t, http.StatusAccepted, rec.Code, "subscribing should be accepted") {
+		return
+	}
+	if !assert.Eventually(t, func() bool { return len(hub.activeSubscriptions(topic)) == 1 }, time.Second,
+		10*time.Millisecond, "subscription should be verified") {
+		return
+	}
+	// Notify.
+	if !assert.Nil(t, hub.Notify(topic), "notifying should not fail") {
+		return
+	}
+	var received notification
+	select {
+	case received = <-notifications:
+	case <-time.After(time.Second):
+		assert.Fail(t, "content should be distributed asynchronously")
+		return
+	}
+	assert.Equal(t, "<rss></rss>", string(received.body), "content should be distributed")
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(received.body)
+	assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), received.signature, "signature should match")
+	assert.Contains(t, received.link, `<https://example.com/websub>; rel="hub"`, "hub link should be set")
+	// Expired subscriptions are removed.
+	hub.now = func() time.Time { return time.Now().Add(maxLease) }
+	assert.Empty(t, hub.activeSubscriptions(topic), "expired subscription should be removed")
+}
+
+func TestHubRejectsInvalidRequests(t *testing.T) {
+	hub := NewHub("https://example.com/websub", "https://example.com/static")
+	hub.AllowTopic = func(topic string) bool { return topic == "https://feeds.example.com/podcast" }
+	tests := []url.Values{
+		{"hub.mode": {"subscribe"}, "hub.callback": {"ftp://example.org"}, "hub.topic": {"https://example.com/static/feed"}},
+		{"hub.mode": {"subscribe"}, "hub.callback": {"https://example.org"}, "hub.topic": {"https://example.org/feed"}},
+		{"hub.mode": {"subscribe"}, "hub.callback": {"http://127.0.0.1:8000"}, "hub.topic": {"https://example.com/static/feed"}},
+		{"hub.mode": {"subscribe"}, "hub.callback": {"http://localhost"}, "hub.topic": {"https://example.com/static/feed"}},
+		{"hub.mode": {"subscribe"}, "hub.callback": {"http://169.254.169.254/latest"}, "hub.topic": {"https://example.com/static/feed"}},
+		{"hub.mode": {"subscribe"}, "hub.callback": {"http://[::1]/"}, "hub.topic": {"https://example.com/static/feed"}},
+		{"hub.mode": {"subscribe"}, "hub.callback": {"http://10.0.0.1/"}, "hub.topic": {"https://example.com/static/feed"}},
+		{"hub.mode": {"publish"}, "hub.url": {"https://example.com/static/feed"}},
+		{"hub.mode": {"unknown"}},
+	}
+	for _, form := range tests {
+		rec := httptest.NewRecorder()
+		hub.ServeHTTP(rec, formRequest(form))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, "request should be rejected for %v", form)
+	}
+	assert.Nil(t, hub.validate("https://example.org", "https://feeds.example.com/podcast"), "allowed topic should be valid")
+}
+
+func TestHubRefusesInternalSubscribers(t *testing.T) {
+	subscriber := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer subscriber.Close()
+	hub := NewHub("https://example.com/websub", "")
+	_, err := hub.subscriberClient.Get(subscriber.URL)
+	assert.NotNil(t, err, "connecting to loopback addresses should fail")
+}
+
+func TestHubAllowsPrivateCallbacks(t *testing.T) {
+	subscriber := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer subscriber.Close()
+	hub := NewHub("https://example.com/websub", "https://example.com/static")
+	hub.AllowPrivateCallbacks = true
+	for _, callback := range []string{"http://localhost", "http://127.0.0.1:8000", "http://10.0.0.1/"} {
+		assert.Nil(t, hub.validate(callback, "https://example.com/static/feed"), "%s should be allowed", callback)
+	}
+	resp, err := hub.subscriberClient.Get(subscriber.URL)
+	if assert.Nil(t, err, "connecting to loopback addresses should not fail") {
+		_ = resp.Body.Close()
+	}
+}
+
+func TestHubLimitsSubscriptions(t *testing.T) {
+	hub := NewHub("https://example.com/websub", "https://example.com/static")
+	topic := "https://example.com/static/2/podcast.xml"
+	expires := time.Now().Add(time.Hour)
+	hub.subscriptions[topic] = make(map[string]subscription)
+	for i := 0; i < maxSubscriptionsPerTopic; i++ {
+		callback := fmt.Sprintf("https://example.org/%d", i)
+		hub.subscriptions[topic][callback] = subscription{callback: callback, expires: expires}
+	}
+	assert.False(t, hub.canSubscribe(topic, "https://example.org/new"), "subscriptions per topic should be limited")
+	assert.True(t, hub.canSubscribe(topic, "https://example.org/0"), "renewals should be allowed")
+	rec := httptest.NewRecorder()
+	hub.ServeHTTP(rec, formRequest(url.Values{
+		"hub.mode":     {"subscribe"},
+		"hub.callback": {"https://example.org/new"},
+		"hub.topic":    {topic},
+	}))
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code, "subscribing should be rejected")
+	for i := 0; i < maxSubscriptions/maxSubscriptionsPerTopic; i++ {
+		hub.subscriptions[fmt.Sprintf("%s?%d", topic, i)] = hub.subscriptions[topic]
+	}
+	assert.False(t, hub.canSubscribe(topic+"?new", "https://example.org/new"), "subscriptions should be limited")
+	hub.now = func() time.Time { return expires }
+	assert.True(t, hub.canSubscribe(topic, "https://example.org/new"), "expired subscriptions should not count")
+}
+
+func TestPublisher(t *testing.T) {
+	forms := make(chan url.Values, 1)
+	hubServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		forms <- r.PostForm
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer hubServer.Close()
+	publisher := &Publisher{Hubs: []string{hubServer.URL}, Hub: NewHub("https://example.com/websub", "")}
+	assert.Equal(t, []string{hubServer.URL, "https://example.com/websub"}, publisher.HubURLs(), "hub urls should match")
+	topics := []string{"https://example.com/static/2/podcast.xml", "https://example.com/static/2/atom.xml"}
+	if !assert.Nil(t, publisher.Publish(topics...), "publishing should not fail") {
+		return
+	}
+	form := <-forms
+	assert.Equal(t, "publish", form.Get("hub.mode"), "mode should match")
+	assert.Equal(t, topics, form["hub.url"], "topics should match")
+	// A nil Publisher does nothing.
+	var nilPublisher *Publisher
+	assert.Nil(t, nilPublisher.HubURLs(), "nil publisher should not have hubs")
+	assert.Nil(t, nilPublisher.Publish(topics...), "nil publisher should not fail")
+}
+
+// formRequest creates a POST request with the given form to the hub.
+func formRequest(form url.Values) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/websub", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r
+}